@@ -0,0 +1,82 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"net"
+
+	"github.com/z5labs/megamind/services/ingest/grpc"
+	"github.com/z5labs/megamind/services/ingest/http"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var allCmd = &cobra.Command{
+	Use:   "all",
+	Short: "Serve requests over gRPC and a RESTful API on the same address.",
+	Run: func(cmd *cobra.Command, args []string) {
+		addr := viper.GetString("addr")
+		ls, err := net.Listen("tcp", addr)
+		if err != nil {
+			zap.L().Fatal(
+				"unexpected error when trying to listen on address",
+				zap.String("addr", addr),
+				zap.Error(err),
+			)
+			return
+		}
+		zap.L().Info("listening for grpc and http requests", zap.String("addr", addr))
+
+		ingester, closeIngester, err := newSubgraphIngester(cmd.Context())
+		if err != nil {
+			zap.L().Fatal("unexpected error when creating subgraph ingester", zap.Error(err))
+			return
+		}
+		defer closeIngester()
+
+		jsonldOpt, err := newJSONLDOption()
+		if err != nil {
+			zap.L().Fatal("unexpected error when loading json-ld rules", zap.Error(err))
+			return
+		}
+
+		callerHeader := viper.GetString("caller-header")
+		s := http.NewSubgraphIngester(
+			zap.L(),
+			ingester,
+			http.WithCallerHeader(callerHeader),
+			http.WithGRPCServer(grpc.NewServer(ingester, grpc.WithCallerHeader(callerHeader))),
+			jsonldOpt,
+		)
+		err = s.Serve(cmd.Context(), ls)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.L().Fatal(
+				"unexpected error when serving grpc and http traffic",
+				zap.String("addr", addr),
+				zap.Error(err),
+			)
+			return
+		}
+	},
+}
+
+func init() {
+	serveCmd.AddCommand(allCmd)
+}
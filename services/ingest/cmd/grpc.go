@@ -21,7 +21,6 @@ import (
 	"net"
 
 	"github.com/z5labs/megamind/services/ingest/grpc"
-	"github.com/z5labs/megamind/services/ingest/ingest"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -44,8 +43,14 @@ var grpcCmd = &cobra.Command{
 		}
 		zap.L().Info("listening for grpc requests", zap.String("addr", addr))
 
-		s := ingest.NewSubgraphIngester(zap.L())
-		err = grpc.Serve(cmd.Context(), ls, s)
+		ingester, closeIngester, err := newSubgraphIngester(cmd.Context())
+		if err != nil {
+			zap.L().Fatal("unexpected error when creating subgraph ingester", zap.Error(err))
+			return
+		}
+		defer closeIngester()
+
+		err = grpc.Serve(cmd.Context(), ls, ingester, grpc.WithCallerHeader(viper.GetString("caller-header")))
 		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
 			zap.L().Fatal(
 				"unexpected error when serving grpc traffic",
@@ -3,9 +3,10 @@ package cmd
 import (
 	"errors"
 	"net"
+	"strings"
 
 	"github.com/z5labs/megamind/services/ingest/http"
-	"github.com/z5labs/megamind/services/ingest/ingest"
+	"github.com/z5labs/megamind/subgraph/rdf"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -28,7 +29,25 @@ var httpCmd = &cobra.Command{
 		}
 		zap.L().Info("listening for http requests", zap.String("addr", addr))
 
-		s := http.NewSubgraphIngester(zap.L(), ingest.NewSubgraphIngester(zap.L()))
+		ingester, closeIngester, err := newSubgraphIngester(cmd.Context())
+		if err != nil {
+			zap.L().Fatal("unexpected error when creating subgraph ingester", zap.Error(err))
+			return
+		}
+		defer closeIngester()
+
+		jsonldOpt, err := newJSONLDOption()
+		if err != nil {
+			zap.L().Fatal("unexpected error when loading json-ld rules", zap.Error(err))
+			return
+		}
+
+		s := http.NewSubgraphIngester(
+			zap.L(),
+			ingester,
+			http.WithCallerHeader(viper.GetString("caller-header")),
+			jsonldOpt,
+		)
 		err = s.Serve(cmd.Context(), ls)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			zap.L().Fatal(
@@ -43,4 +62,25 @@ var httpCmd = &cobra.Command{
 
 func init() {
 	serveCmd.AddCommand(httpCmd)
+
+	// Flags
+	httpCmd.Flags().String("jsonld-rules", "", "Rules file mapping JSON-LD node and property IRIs to subjects and predicates.")
+
+	viper.BindPFlag("jsonld-rules", httpCmd.Flags().Lookup("jsonld-rules"))
+
+	// The all command serves the RESTful API too
+	allCmd.Flags().AddFlag(httpCmd.Flags().Lookup("jsonld-rules"))
+}
+
+func newJSONLDOption() (http.Option, error) {
+	filename := strings.TrimSpace(viper.GetString("jsonld-rules"))
+	if filename == "" {
+		return http.WithJSONLD(), nil
+	}
+
+	rules, err := rdf.LoadRules(filename)
+	if err != nil {
+		return nil, err
+	}
+	return http.WithJSONLD(rdf.WithRules(rules)), nil
 }
@@ -17,8 +17,23 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/z5labs/megamind/services/ingest/ingest"
+	"github.com/z5labs/megamind/services/ingest/jobs"
+	"github.com/z5labs/megamind/services/ingest/publisher"
+	"github.com/z5labs/megamind/services/ingest/wal"
+	"github.com/z5labs/megamind/subgraph/merge"
+	"github.com/z5labs/megamind/subgraph/schema"
+	"github.com/z5labs/megamind/subgraph/store"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 var serveCmd = &cobra.Command{
@@ -29,9 +44,273 @@ var serveCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(serveCmd)
 
-	// Flags
-	serveCmd.Flags().String("addr", "0.0.0.0:8080", "Address to listen for connections.")
+	// Persistent flags
+	serveCmd.PersistentFlags().String("addr", "0.0.0.0:8080", "Address to listen for connections.")
+
+	viper.BindPFlag("addr", serveCmd.PersistentFlags().Lookup("addr"))
+
+	serveCmd.PersistentFlags().String("publisher", "file", "Where to publish ingested subgraphs to. The memory publisher never releases subgraphs so is only meant for testing. (file, cloudevents, store, memory)")
+	serveCmd.PersistentFlags().String("publisher-file", "subgraphs.bin", "File to append subgraphs to when using the file publisher.")
+	serveCmd.PersistentFlags().String("store-file", "megamind.db", "File of the embedded triple store when using the store publisher.")
+	serveCmd.PersistentFlags().String("broker-url", "", "Broker URL to POST CloudEvents to when using the cloudevents publisher.")
+	serveCmd.PersistentFlags().String("cloudevents-mode", "binary", "CloudEvents HTTP content mode. (binary, structured)")
+	serveCmd.PersistentFlags().String("cloudevents-encoding", "proto", "CloudEvents data encoding. (proto, json)")
+	serveCmd.PersistentFlags().Int("cloudevents-retries", 3, "Number of times to resend a CloudEvent after a 5xx response.")
+
+	viper.BindPFlag("publisher", serveCmd.PersistentFlags().Lookup("publisher"))
+	viper.BindPFlag("publisher-file", serveCmd.PersistentFlags().Lookup("publisher-file"))
+	viper.BindPFlag("store-file", serveCmd.PersistentFlags().Lookup("store-file"))
+	viper.BindPFlag("broker-url", serveCmd.PersistentFlags().Lookup("broker-url"))
+	viper.BindPFlag("cloudevents-mode", serveCmd.PersistentFlags().Lookup("cloudevents-mode"))
+	viper.BindPFlag("cloudevents-encoding", serveCmd.PersistentFlags().Lookup("cloudevents-encoding"))
+	viper.BindPFlag("cloudevents-retries", serveCmd.PersistentFlags().Lookup("cloudevents-retries"))
+
+	serveCmd.PersistentFlags().String("wal-dir", "", "Directory for the write-ahead log of accepted subgraphs. Disabled if empty.")
+	serveCmd.PersistentFlags().String("wal-sync", "always", "When to fsync the write-ahead log. (always, interval, never)")
+	serveCmd.PersistentFlags().Duration("wal-sync-interval", time.Second, "How often to fsync the write-ahead log when using the interval sync policy.")
+	serveCmd.PersistentFlags().Int64("wal-segment-size", 64<<20, "Size in bytes after which a new write-ahead log segment is started.")
+	serveCmd.PersistentFlags().Duration("wal-redeliver-interval", 30*time.Second, "How often to retry publishing subgraphs in the write-ahead log which failed to publish. Disabled if zero.")
+
+	viper.BindPFlag("wal-dir", serveCmd.PersistentFlags().Lookup("wal-dir"))
+	viper.BindPFlag("wal-sync", serveCmd.PersistentFlags().Lookup("wal-sync"))
+	viper.BindPFlag("wal-sync-interval", serveCmd.PersistentFlags().Lookup("wal-sync-interval"))
+	viper.BindPFlag("wal-segment-size", serveCmd.PersistentFlags().Lookup("wal-segment-size"))
+	viper.BindPFlag("wal-redeliver-interval", serveCmd.PersistentFlags().Lookup("wal-redeliver-interval"))
+
+	serveCmd.PersistentFlags().String("job-dir", "jobs", "Directory to record ingest jobs in so they survive restarts. Jobs are only kept in memory, and lost on restart, if empty.")
+
+	viper.BindPFlag("job-dir", serveCmd.PersistentFlags().Lookup("job-dir"))
+
+	serveCmd.PersistentFlags().String("schema-file", "", "Schema file to check ingested subgraphs against.")
+	serveCmd.PersistentFlags().String("schema-mode", "strict", "How to handle subgraphs which do not conform to the schema. (strict, warn, off)")
+
+	viper.BindPFlag("schema-file", serveCmd.PersistentFlags().Lookup("schema-file"))
+	viper.BindPFlag("schema-mode", serveCmd.PersistentFlags().Lookup("schema-mode"))
+
+	serveCmd.PersistentFlags().String("caller-header", "", "Trusted header identifying the caller, e.g. set by an authenticating proxy. If empty, only client certificates are used.")
+
+	viper.BindPFlag("caller-header", serveCmd.PersistentFlags().Lookup("caller-header"))
+
+	serveCmd.PersistentFlags().String("node-id", "", "Unique ID of this replica used to timestamp triples. Defaults to the hostname.")
+	serveCmd.PersistentFlags().Duration("max-clock-drift", merge.DefaultMaxDrift, "How far ahead of this replica's clock triple timestamps set by producers may be. Subgraphs with later timestamps are rejected.")
+
+	viper.BindPFlag("node-id", serveCmd.PersistentFlags().Lookup("node-id"))
+	viper.BindPFlag("max-clock-drift", serveCmd.PersistentFlags().Lookup("max-clock-drift"))
+}
+
+// newSubgraphIngester configures a SubgraphIngester from flags. Any unfinished ingest
+// jobs are resumed in the background. If a write-ahead log is configured, any
+// unpublished subgraphs in it are replayed, and retried until they are published.
+// The returned func stops both before closing the log and publisher they use.
+func newSubgraphIngester(ctx context.Context) (*ingest.SubgraphIngester, func(), error) {
+	schemaOpt, err := newSchemaOption()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	js, err := newJobStore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := newPublisher()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w, err := newWriteAheadLog()
+	if err != nil {
+		closePublisher(p)
+		return nil, nil, err
+	}
+	opts := []ingest.Option{schemaOpt, ingest.WithJobStore(js)}
+	nodeID := strings.TrimSpace(viper.GetString("node-id"))
+	if nodeID == "" {
+		nodeID = ingest.DefaultNodeID()
+	}
+	clock := merge.NewClock(nodeID, merge.WithMaxDrift(viper.GetDuration("max-clock-drift")))
+	opts = append(opts, ingest.WithClock(clock))
+	if w == nil {
+		s := ingest.NewSubgraphIngester(zap.L(), p, opts...)
+		resumeJobs(s)
+		closeAll := func() {
+			s.StopJobs()
+			closePublisher(p)
+		}
+		return s, closeAll, nil
+	}
+
+	s := ingest.NewSubgraphIngester(zap.L(), p, append(opts, ingest.WithWriteAheadLog(w))...)
+	replayCtx, stopReplay := context.WithCancel(ctx)
+	replayDone := make(chan struct{})
+	go func() {
+		defer close(replayDone)
+
+		err := s.Replay(replayCtx)
+		if err != nil {
+			zap.L().Error("unexpected error when replaying write-ahead log", zap.Error(err))
+		}
+		s.Redeliver(replayCtx, viper.GetDuration("wal-redeliver-interval"))
+	}()
+	resumeJobs(s)
+
+	closeAll := func() {
+		s.StopJobs()
+		stopReplay()
+		<-replayDone
+
+		err := w.Close()
+		if err != nil {
+			zap.L().Error("unexpected error when closing write-ahead log", zap.Error(err))
+		}
+		closePublisher(p)
+	}
+	return s, closeAll, nil
+}
+
+func newSchemaOption() (ingest.Option, error) {
+	var mode ingest.SchemaMode
+	switch m := getLowerString("schema-mode"); m {
+	case "strict":
+		mode = ingest.SchemaStrict
+	case "warn":
+		mode = ingest.SchemaWarn
+	case "off":
+		mode = ingest.SchemaOff
+	default:
+		return nil, fmt.Errorf("unsupported schema mode: %s", m)
+	}
+
+	filename := strings.TrimSpace(viper.GetString("schema-file"))
+	if filename == "" || mode == ingest.SchemaOff {
+		return ingest.WithSchema(nil, ingest.SchemaOff), nil
+	}
+
+	sc, err := schema.Load(filename)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("loaded schema", zap.String("filename", filename), zap.Int("num_of_types", len(sc.Types)))
+	return ingest.WithSchema(sc, mode), nil
+}
+
+func newJobStore() (ingest.JobStore, error) {
+	dir := strings.TrimSpace(viper.GetString("job-dir"))
+	if dir == "" {
+		return jobs.NewMemory(), nil
+	}
+	return jobs.Open(dir)
+}
+
+func resumeJobs(s *ingest.SubgraphIngester) {
+	err := s.ResumeJobs()
+	if err != nil {
+		zap.L().Error("unexpected error when resuming ingest jobs", zap.Error(err))
+	}
+}
+
+func newWriteAheadLog() (*wal.Log, error) {
+	dir := strings.TrimSpace(viper.GetString("wal-dir"))
+	if dir == "" {
+		return nil, nil
+	}
+
+	var policy wal.SyncPolicy
+	switch p := getLowerString("wal-sync"); p {
+	case "always":
+		policy = wal.SyncAlways
+	case "interval":
+		policy = wal.SyncInterval
+	case "never":
+		policy = wal.SyncNever
+	default:
+		return nil, fmt.Errorf("unsupported wal sync policy: %s", p)
+	}
+
+	return wal.Open(
+		dir,
+		wal.WithSyncPolicy(policy),
+		wal.WithSyncInterval(viper.GetDuration("wal-sync-interval")),
+		wal.WithMaxSegmentSize(viper.GetInt64("wal-segment-size")),
+	)
+}
+
+func newPublisher() (ingest.Publisher, error) {
+	name := getLowerString("publisher")
+	switch name {
+	case "memory":
+		return publisher.NewMemory(), nil
+	case "file":
+		return publisher.NewFile(viper.GetString("publisher-file"))
+	case "cloudevents":
+		return newCloudEventsPublisher()
+	case "store":
+		return newStorePublisher()
+	default:
+		return nil, fmt.Errorf("unsupported publisher: %s", name)
+	}
+}
+
+// newStorePublisher opens the embedded triple store, which resolves
+// single valued predicates the same way as the schema file, if any.
+func newStorePublisher() (*store.Store, error) {
+	opts := []store.Option{store.WithLockTimeout(10 * time.Second)}
+	if filename := strings.TrimSpace(viper.GetString("schema-file")); filename != "" {
+		sc, err := schema.Load(filename)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, store.WithSchema(sc))
+	}
+	return store.Open(viper.GetString("store-file"), opts...)
+}
+
+func newCloudEventsPublisher() (*publisher.CloudEvents, error) {
+	url := strings.TrimSpace(viper.GetString("broker-url"))
+	if url == "" {
+		return nil, fmt.Errorf("a broker url must be provided for the cloudevents publisher")
+	}
+
+	var mode publisher.ContentMode
+	switch m := getLowerString("cloudevents-mode"); m {
+	case "binary":
+		mode = publisher.BinaryMode
+	case "structured":
+		mode = publisher.StructuredMode
+	default:
+		return nil, fmt.Errorf("unsupported cloudevents mode: %s", m)
+	}
+
+	var encoding publisher.DataEncoding
+	switch e := getLowerString("cloudevents-encoding"); e {
+	case "proto":
+		encoding = publisher.ProtobufData
+	case "json":
+		encoding = publisher.JSONData
+	default:
+		return nil, fmt.Errorf("unsupported cloudevents encoding: %s", e)
+	}
+
+	p := publisher.NewCloudEvents(
+		url,
+		publisher.WithContentMode(mode),
+		publisher.WithDataEncoding(encoding),
+		publisher.WithRetries(viper.GetInt("cloudevents-retries"), 100*time.Millisecond),
+	)
+	return p, nil
+}
+
+func getLowerString(key string) string {
+	return strings.ToLower(strings.TrimSpace(viper.GetString(key)))
+}
 
-	viper.BindPFlag("addr", serveCmd.Flags().Lookup("addr"))
-	
+func closePublisher(p ingest.Publisher) {
+	c, ok := p.(io.Closer)
+	if !ok {
+		return
+	}
+	err := c.Close()
+	if err != nil {
+		zap.L().Error("unexpected error when closing publisher", zap.Error(err))
+	}
 }
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+
+	"github.com/z5labs/megamind/services/ingest/ingest"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// withCaller adds the caller of an RPC to its context. The caller is taken
+// from the trusted metadata key, if one is configured and present, and
+// otherwise from the common name of the verified client certificate.
+func withCaller(ctx context.Context, header string) context.Context {
+	if header != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		vals := md.Get(header)
+		if len(vals) > 0 && vals[0] != "" {
+			return ingest.ContextWithCaller(ctx, vals[0])
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ctx
+	}
+	chains := tlsInfo.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return ctx
+	}
+	return ingest.ContextWithCaller(ctx, chains[0][0].Subject.CommonName)
+}
+
+func unaryCallerInterceptor(header string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withCaller(ctx, header), req)
+	}
+}
+
+func streamCallerInterceptor(header string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &callerStream{
+			ServerStream: ss,
+			ctx:          withCaller(ss.Context(), header),
+		})
+	}
+}
+
+type callerStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *callerStream) Context() context.Context {
+	return s.ctx
+}
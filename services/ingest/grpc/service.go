@@ -29,10 +29,40 @@ import (
 
 var ErrServerStopped = grpc.ErrServerStopped
 
-// Serve instantiates the gRPC server and registers the SubgraphIngest service with it.
-func Serve(ctx context.Context, ls net.Listener, s *ingest.SubgraphIngester) error {
-	grpcServer := grpc.NewServer(grpc.Creds(insecure.NewCredentials()))
+// Option
+type Option func(*options)
+
+type options struct {
+	callerHeader string
+}
+
+// WithCallerHeader trusts the given metadata key to identify the caller,
+// e.g. when the service sits behind an authenticating proxy.
+func WithCallerHeader(key string) Option {
+	return func(o *options) {
+		o.callerHeader = key
+	}
+}
+
+// NewServer instantiates a gRPC server and registers the SubgraphIngest service with it.
+func NewServer(s *ingest.SubgraphIngester, opts ...Option) *grpc.Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.Creds(insecure.NewCredentials()),
+		grpc.UnaryInterceptor(unaryCallerInterceptor(o.callerHeader)),
+		grpc.StreamInterceptor(streamCallerInterceptor(o.callerHeader)),
+	)
 	pb.RegisterSubgraphIngestServer(grpcServer, s)
+	return grpcServer
+}
+
+// Serve serves the SubgraphIngest service on the listener until the context is cancelled.
+func Serve(ctx context.Context, ls net.Listener, s *ingest.SubgraphIngester, opts ...Option) error {
+	grpcServer := NewServer(s, opts...)
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -18,6 +18,7 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net"
 	"testing"
@@ -25,15 +26,26 @@ import (
 
 	"github.com/z5labs/megamind/services/ingest/ingest"
 	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/services/ingest/publisher"
 	"github.com/z5labs/megamind/subgraph"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-func newSubgraphIngester(ctx context.Context, logger *zap.Logger) (net.Addr, <-chan error) {
+type publisherFunc func(context.Context, *subgraph.Subgraph) error
+
+func (f publisherFunc) Publish(ctx context.Context, g *subgraph.Subgraph) error {
+	return f(ctx, g)
+}
+
+func newSubgraphIngester(ctx context.Context, logger *zap.Logger, p ingest.Publisher, opts ...Option) (net.Addr, <-chan error) {
 	errCh := make(chan error, 1)
 	ls, err := net.Listen("tcp", ":0")
 	if err != nil {
@@ -41,22 +53,43 @@ func newSubgraphIngester(ctx context.Context, logger *zap.Logger) (net.Addr, <-c
 		return nil, errCh
 	}
 
-	s := ingest.NewSubgraphIngester(logger)
+	s := ingest.NewSubgraphIngester(logger, p)
 	go func() {
 		defer close(errCh)
-		err := Serve(ctx, ls, s)
+		err := Serve(ctx, ls, s, opts...)
 		errCh <- err
 	}()
 
 	return ls.Addr(), errCh
 }
 
+func newTestSubgraph() *subgraph.Subgraph {
+	return &subgraph.Subgraph{
+		Triples: []*subgraph.Triple{
+			{
+				Subject: &subgraph.Subject{
+					Type: "Person",
+					Tuid: "1",
+				},
+				Predicate: &subgraph.Predicate{
+					Name: "name",
+				},
+				Object: &subgraph.Object{
+					Value: &subgraph.Object_String_{
+						String_: "Bob",
+					},
+				},
+			},
+		},
+	}
+}
+
 func TestSubgraphIngester(t *testing.T) {
 	t.Run("should shutdown when context is cancelled", func(subT *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		addr, errCh := newSubgraphIngester(ctx, zap.L())
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
 		if !assert.NotNil(subT, addr) {
 			return
 		}
@@ -72,7 +105,7 @@ func TestSubgraphIngester(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		addr, errCh := newSubgraphIngester(ctx, zap.L())
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
 		if !assert.NotNil(subT, addr) {
 			return
 		}
@@ -87,29 +120,245 @@ func TestSubgraphIngester(t *testing.T) {
 		if !assert.Nil(subT, err) {
 			return
 		}
-		err = stream.Send(&subgraph.Subgraph{
-			Triples: []*subgraph.Triple{
-				{
-					Subject: &subgraph.Subject{
-						Type: "Person",
-						Tuid: "1",
-					},
-					Predicate: &subgraph.Predicate{
-						Name: "name",
-					},
-					Object: &subgraph.Object{
-						Value: &subgraph.Object_String_{
-							String_: "Bob",
-						},
-					},
-				},
-			},
+		err = stream.Send(newTestSubgraph())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		resp, err := stream.CloseAndRecv()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.NotEmpty(subT, resp.IngestId) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), resp.NumOfTriples) {
+			return
+		}
+		cancel()
+
+		err = <-errCh
+		if !assert.Equal(subT, ErrServerStopped, err) {
+			return
+		}
+	})
+	t.Run("should ack every subgraph sent on the stream", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisher.NewMemory()
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), p)
+		if !assert.NotNil(subT, addr) {
+			return
+		}
+
+		cc, err := grpc.Dial(addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		client := pb.NewSubgraphIngestClient(cc)
+
+		stream, err := client.IngestStream(ctx)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		for i := uint64(1); i <= 3; i++ {
+			err = stream.Send(&pb.IngestStreamRequest{
+				Sequence: i,
+				Subgraph: newTestSubgraph(),
+			})
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+		err = stream.CloseSend()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		acked := make(map[uint64]pb.IngestAck_Status)
+		for {
+			ack, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if !assert.Nil(subT, err) {
+				return
+			}
+			acked[ack.Sequence] = ack.Status
+			if !assert.NotEmpty(subT, ack.Receipt.GetIngestId()) {
+				return
+			}
+		}
+		if !assert.Equal(subT, map[uint64]pb.IngestAck_Status{1: pb.IngestAck_ACK, 2: pb.IngestAck_ACK, 3: pb.IngestAck_ACK}, acked) {
+			return
+		}
+		if !assert.Len(subT, p.Subgraphs(), 3) {
+			return
+		}
+		cancel()
+
+		err = <-errCh
+		if !assert.Equal(subT, ErrServerStopped, err) {
+			return
+		}
+	})
+
+	t.Run("should nack subgraphs which could not be ingested", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisherFunc(func(context.Context, *subgraph.Subgraph) error {
+			return errors.New("failed to publish")
+		})
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), p)
+		if !assert.NotNil(subT, addr) {
+			return
+		}
+
+		cc, err := grpc.Dial(addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		client := pb.NewSubgraphIngestClient(cc)
+
+		stream, err := client.IngestStream(ctx)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = stream.Send(&pb.IngestStreamRequest{
+			Sequence: 7,
+			Subgraph: newTestSubgraph(),
 		})
 		if !assert.Nil(subT, err) {
 			return
 		}
+		err = stream.Send(&pb.IngestStreamRequest{
+			Sequence: 8,
+		})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = stream.CloseSend()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		nacked := make(map[uint64]string)
+		for {
+			ack, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if !assert.Nil(subT, err) {
+				return
+			}
+			if !assert.Equal(subT, pb.IngestAck_NACK, ack.Status) {
+				return
+			}
+			nacked[ack.Sequence] = ack.Error
+		}
+		if !assert.Equal(subT, map[uint64]string{7: "failed to publish", 8: "missing subgraph"}, nacked) {
+			return
+		}
+		cancel()
+
+		err = <-errCh
+		if !assert.Equal(subT, ErrServerStopped, err) {
+			return
+		}
+	})
+	t.Run("should return invalid argument with every field violation", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
+		if !assert.NotNil(subT, addr) {
+			return
+		}
+
+		cc, err := grpc.Dial(addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		client := pb.NewSubgraphIngestClient(cc)
+
+		g := newTestSubgraph()
+		g.Triples[0].Subject.Tuid = ""
+		g.Triples[0].Predicate = nil
+		_, err = client.IngestSubgraph(ctx, g)
+
+		st := status.Convert(err)
+		if !assert.Equal(subT, codes.InvalidArgument, st.Code()) {
+			return
+		}
+		if !assert.Len(subT, st.Details(), 1) {
+			return
+		}
+		br, ok := st.Details()[0].(*errdetails.BadRequest)
+		if !assert.True(subT, ok) {
+			return
+		}
+
+		var fields []string
+		for _, fv := range br.FieldViolations {
+			fields = append(fields, fv.Field)
+		}
+		if !assert.Equal(subT, []string{"triples[0].subject.tuid", "triples[0].predicate"}, fields) {
+			return
+		}
+		cancel()
+
+		err = <-errCh
+		if !assert.Equal(subT, ErrServerStopped, err) {
+			return
+		}
+	})
+
+	t.Run("should stamp the caller from the trusted header", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		provCh := make(chan *subgraph.Provenance, 1)
+		p := publisherFunc(func(_ context.Context, g *subgraph.Subgraph) error {
+			provCh <- g.Provenance
+			return nil
+		})
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), p, WithCallerHeader("x-caller"))
+		if !assert.NotNil(subT, addr) {
+			return
+		}
+
+		cc, err := grpc.Dial(addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		client := pb.NewSubgraphIngestClient(cc)
+
+		mctx := metadata.AppendToOutgoingContext(ctx, "x-caller", "alice")
+		stream, err := client.Ingest(mctx)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = stream.Send(newTestSubgraph())
+		if !assert.Nil(subT, err) {
+			return
+		}
 		_, err = stream.CloseAndRecv()
-		if !assert.Equal(subT, io.EOF, err) {
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		var prov *subgraph.Provenance
+		select {
+		case <-ctx.Done():
+			subT.Error(ctx.Err())
+			return
+		case prov = <-provCh:
+		}
+		if !assert.Equal(subT, "alice", prov.GetCaller()) {
+			return
+		}
+		if !assert.NotNil(subT, prov.GetReceivedAt()) {
 			return
 		}
 		cancel()
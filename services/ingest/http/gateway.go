@@ -0,0 +1,146 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	ingestgrpc "github.com/z5labs/megamind/services/ingest/grpc"
+	"github.com/z5labs/megamind/services/ingest/ingest"
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// gatewayCallerKey is the metadata key the gateway passes the caller of a
+// request on to the in-process gRPC server with.
+const gatewayCallerKey = "x-megamind-gateway-caller"
+
+const gatewayBufferSize = 1 << 20
+
+// gateway serves every RPC of the SubgraphIngest service over REST, as mapped
+// by the google.api.http annotations in service.proto, by proxying requests to
+// a gRPC server running in-process. Errors are written as problem documents,
+// except for those in the middle of a streamed response which are sent as
+// an {"error": ...} line.
+type gateway struct {
+	mux *runtime.ServeMux
+	gs  *grpc.Server
+	ls  *bufconn.Listener
+	cc  *grpc.ClientConn
+
+	errCh chan error
+}
+
+// newGateway starts the in-process gRPC server. Its lifetime is decoupled
+// from any context, since in-flight requests should finish when the http
+// server is shutdown, so it must be stopped.
+func newGateway(s *ingest.SubgraphIngester) (*gateway, error) {
+	ctx := context.Background()
+	ls := bufconn.Listen(gatewayBufferSize)
+	cc, err := grpc.DialContext(
+		ctx,
+		"bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ls.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		ls.Close()
+		return nil, err
+	}
+
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{}),
+		runtime.WithMarshalerOption("application/x-protobuf", &runtime.ProtoMarshaller{}),
+		runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher),
+		runtime.WithMetadata(gatewayCaller),
+		runtime.WithErrorHandler(gatewayError),
+	)
+	err = pb.RegisterSubgraphIngestHandler(ctx, mux, cc)
+	if err != nil {
+		cc.Close()
+		ls.Close()
+		return nil, err
+	}
+
+	g := &gateway{
+		mux:   mux,
+		gs:    ingestgrpc.NewServer(s, ingestgrpc.WithCallerHeader(gatewayCallerKey)),
+		ls:    ls,
+		cc:    cc,
+		errCh: make(chan error, 1),
+	}
+	go func() {
+		defer close(g.errCh)
+		g.errCh <- g.gs.Serve(ls)
+	}()
+	return g, nil
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// stop waits for in-flight requests to finish before stopping the gRPC server.
+func (g *gateway) stop() error {
+	g.gs.GracefulStop()
+	err := <-g.errCh
+	if err != nil {
+		return err
+	}
+	return g.cc.Close()
+}
+
+// gatewayHeaderMatcher keeps clients from claiming to be another caller
+// by setting the metadata key of the caller themselves.
+func gatewayHeaderMatcher(key string) (string, bool) {
+	key, ok := runtime.DefaultHeaderMatcher(key)
+	if strings.EqualFold(key, gatewayCallerKey) {
+		return "", false
+	}
+	return key, ok
+}
+
+// gatewayCaller passes the caller identified by the caller middleware on to
+// the gRPC server.
+func gatewayCaller(ctx context.Context, r *http.Request) metadata.MD {
+	caller := ingest.CallerFromContext(r.Context())
+	if caller == "" {
+		return nil
+	}
+	return metadata.Pairs(gatewayCallerKey, caller)
+}
+
+func gatewayError(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	var statusErr *runtime.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		encodeProblem(w, newProblem(statusErr.HTTPStatus, status.Convert(statusErr.Err).Message(), nil))
+		return
+	}
+	encodeProblem(w, problemFromError(err))
+}
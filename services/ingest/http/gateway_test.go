@@ -0,0 +1,260 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/services/ingest/ingest"
+	"github.com/z5labs/megamind/services/ingest/jobs"
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/services/ingest/publisher"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func post(ctx context.Context, endpoint, contentType, body string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+	return http.DefaultClient.Do(req)
+}
+
+func TestSubgraphIngester_Gateway(t *testing.T) {
+	t.Run("should map every rpc to a rest endpoint", func(subT *testing.T) {
+		methods := pb.File_services_ingest_proto_service_proto.Services().ByName("SubgraphIngest").Methods()
+		for i := 0; i < methods.Len(); i++ {
+			opts := methods.Get(i).Options().(*descriptorpb.MethodOptions)
+			rule := proto.GetExtension(opts, annotations.E_Http).(*annotations.HttpRule)
+			if !assert.NotNil(subT, rule, "%s has no google.api.http annotation", methods.Get(i).Name()) {
+				return
+			}
+		}
+	})
+
+	t.Run("should ingest a subgraph", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := `{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`
+		resp, err := post(ctx, "http://"+addr.String()+"/v1/subgraph:ingest", "application/json", body, nil)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+
+		b, err := io.ReadAll(resp.Body)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		var ingestResp pb.IngestResponse
+		err = protojson.Unmarshal(b, &ingestResp)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.NotEmpty(subT, ingestResp.IngestId) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), ingestResp.NumOfTriples) {
+			return
+		}
+	})
+
+	t.Run("should stream an ack for every subgraph", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := strings.Join([]string{
+			`{"sequence":"1","subgraph":{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}}`,
+			`{"sequence":"2","subgraph":{"triples":[{"subject":{"type":"Person","tuid":"2"},"predicate":{"name":"name"},"object":{"string":"Alice"}}]}}`,
+		}, "\n")
+		resp, err := post(ctx, "http://"+addr.String()+"/v1/subgraphs:stream", "application/json", body, nil)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+
+		sequences := make(map[uint64]pb.IngestAck_Status)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var chunk struct {
+				Result json.RawMessage `json:"result"`
+			}
+			err = json.Unmarshal(scanner.Bytes(), &chunk)
+			if !assert.Nil(subT, err) {
+				return
+			}
+
+			var ack pb.IngestAck
+			err = protojson.Unmarshal(chunk.Result, &ack)
+			if !assert.Nil(subT, err) {
+				return
+			}
+			sequences[ack.Sequence] = ack.Status
+		}
+		if !assert.Nil(subT, scanner.Err()) {
+			return
+		}
+		if !assert.Equal(subT, map[uint64]pb.IngestAck_Status{1: pb.IngestAck_ACK, 2: pb.IngestAck_ACK}, sequences) {
+			return
+		}
+	})
+
+	t.Run("should return a problem document for invalid subgraphs", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := `{"triples":[{"subject":{"type":"","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`
+		resp, err := post(ctx, "http://"+addr.String()+"/v1/subgraph:ingest", "application/json", body, nil)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusBadRequest, resp.StatusCode) {
+			return
+		}
+		if !assert.Equal(subT, "application/problem+json", resp.Header.Get("Content-Type")) {
+			return
+		}
+
+		var p problem
+		err = json.NewDecoder(resp.Body).Decode(&p)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusBadRequest, p.Status) {
+			return
+		}
+		if !assert.NotEmpty(subT, p.Violations) {
+			return
+		}
+	})
+
+	t.Run("should return a problem document for unknown jobs", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		ingester := ingest.NewSubgraphIngester(zap.L(), publisher.NewMemory(), ingest.WithJobStore(jobs.NewMemory()))
+		addr, errCh := serveSubgraphIngester(ctx, zap.L(), ingester)
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr.String()+"/v1/jobs/unknown", nil)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusNotFound, resp.StatusCode) {
+			return
+		}
+		if !assert.Equal(subT, "application/problem+json", resp.Header.Get("Content-Type")) {
+			return
+		}
+	})
+
+	t.Run("should pass the caller on to the ingester", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		ingester := ingest.NewSubgraphIngester(zap.L(), publisher.NewMemory(), ingest.WithJobStore(jobs.NewMemory()))
+		addr, errCh := serveSubgraphIngester(ctx, zap.L(), ingester, WithCallerHeader("X-Caller"))
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		submit := func(header http.Header) (*pb.Job, error) {
+			body := `{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`
+			resp, err := post(ctx, "http://"+addr.String()+"/v1/jobs", "application/json", body, header)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, err
+			}
+			job := new(pb.Job)
+			err = protojson.Unmarshal(b, job)
+			return job, err
+		}
+
+		job, err := submit(http.Header{"X-Caller": {"alice"}})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, "alice", job.Caller) {
+			return
+		}
+
+		job, err = submit(http.Header{"Grpc-Metadata-" + gatewayCallerKey: {"mallory"}})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, job.Caller) {
+			return
+		}
+	})
+}
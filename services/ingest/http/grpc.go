@@ -0,0 +1,74 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// WithGRPCServer serves gRPC requests on the same listener as the RESTful API.
+// Requests with an application/grpc content type are handed to the gRPC server,
+// which lets clients speak HTTP/2 without TLS.
+func WithGRPCServer(gs *grpc.Server) Option {
+	return func(s *SubgraphIngester) {
+		s.grpc = &grpcHandler{gs: gs}
+	}
+}
+
+// grpcHandler hands gRPC requests to a gRPC server and keeps track of
+// them, since they outlive the shutdown of the http server.
+type grpcHandler struct {
+	gs *grpc.Server
+	wg sync.WaitGroup
+}
+
+// route returns a handler which sends gRPC requests to the gRPC server
+// and everything else to h.
+func (g *grpcHandler) route(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		g.wg.Add(1)
+		defer g.wg.Done()
+
+		g.gs.ServeHTTP(w, r)
+	})
+}
+
+// stop waits for in-flight gRPC requests to finish, or the context to be
+// cancelled, before stopping the gRPC server.
+func (g *grpcHandler) stop(ctx context.Context) {
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		g.wg.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-doneCh:
+	}
+	g.gs.Stop()
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/services/ingest/ingest"
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/services/ingest/publisher"
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestSubgraphIngester_GRPC(t *testing.T) {
+	newTestSubgraph := func() *subgraph.Subgraph {
+		return &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{
+				{
+					Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+					Predicate: &subgraph.Predicate{Name: "name"},
+					Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Bob"}},
+				},
+			},
+		}
+	}
+
+	serve := func(ctx context.Context) (string, <-chan error) {
+		ingester := ingest.NewSubgraphIngester(zap.L(), publisher.NewMemory())
+		gs := grpc.NewServer()
+		pb.RegisterSubgraphIngestServer(gs, ingester)
+
+		addr, errCh := serveSubgraphIngester(ctx, zap.L(), ingester, WithGRPCServer(gs))
+		if addr == nil {
+			return "", errCh
+		}
+		return addr.String(), errCh
+	}
+
+	t.Run("should serve grpc and http requests on the same listener", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := serve(ctx)
+		if !assert.NotEmpty(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		cc, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer cc.Close()
+
+		resp, err := pb.NewSubgraphIngestClient(cc).IngestSubgraph(ctx, newTestSubgraph())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.NotEmpty(subT, resp.IngestId) {
+			return
+		}
+
+		body := `{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+addr+"/subgraph/ingest", strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		httpResp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer httpResp.Body.Close()
+		if !assert.Equal(subT, http.StatusOK, httpResp.StatusCode) {
+			return
+		}
+	})
+
+	t.Run("should shutdown grpc and http when context is cancelled", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := serve(ctx)
+		if !assert.NotEmpty(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		cc, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer cc.Close()
+
+		client := pb.NewSubgraphIngestClient(cc)
+		_, err = client.IngestSubgraph(ctx, newTestSubgraph())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		cancel()
+
+		err = <-errCh
+		if !assert.Equal(subT, ErrServerClosed, err) {
+			return
+		}
+
+		rpcCtx, rpcCancel := context.WithTimeout(context.Background(), time.Second)
+		defer rpcCancel()
+
+		_, err = client.IngestSubgraph(rpcCtx, newTestSubgraph())
+		if !assert.NotNil(subT, err) {
+			return
+		}
+	})
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// submitJob records the subgraphs in the request body as an ingest job
+// and responds with the pending job right away. The body is either an
+// NDJSON document with one subgraph per line, or a single subgraph in
+// any of the content types accepted by /subgraph/ingest. The job is only
+// submitted if every line of an NDJSON body is a subgraph.
+func (s *SubgraphIngester) submitJob(c *gin.Context) {
+	var next func() (*subgraph.Subgraph, error)
+	switch c.ContentType() {
+	case "application/x-ndjson":
+		defer c.Request.Body.Close()
+		next = newNDJSONReader(c.Request.Body).next
+	default:
+		bodyBinding, err := s.binding(c.ContentType())
+		if err != nil {
+			s.log.Error("unsupported request content type", zap.String("content_type", c.ContentType()))
+			writeProblem(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		g := new(subgraph.Subgraph)
+		err = c.ShouldBindWith(g, bodyBinding)
+		if err != nil {
+			s.log.Error("unexpected error when unmarshalling request body", zap.Error(err))
+			writeProblem(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		sent := false
+		next = func() (*subgraph.Subgraph, error) {
+			if sent {
+				return nil, io.EOF
+			}
+			sent = true
+			return g, nil
+		}
+	}
+
+	stream := &submitJobStream{ctx: c.Request.Context(), next: next}
+	err := s.ingester.SubmitJob(stream)
+	var lerr *lineError
+	if errors.As(err, &lerr) {
+		s.log.Error("malformed ndjson request body", zap.Error(err))
+		writeProblem(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		s.log.Error("failed to submit ingest job", zap.Error(err))
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Location", "/v1/jobs/"+url.PathEscape(stream.job.Id))
+	s.writeMessage(c, http.StatusAccepted, stream.job)
+}
+
+func (s *SubgraphIngester) getJob(c *gin.Context) {
+	job, err := s.ingester.GetJob(c.Request.Context(), &pb.GetJobRequest{Id: c.Param("id")})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	s.writeMessage(c, http.StatusOK, job)
+}
+
+// submitJobStream adapts a request body to the server side of a SubmitJob stream.
+type submitJobStream struct {
+	grpc.ServerStream
+
+	ctx  context.Context
+	next func() (*subgraph.Subgraph, error)
+	job  *pb.Job
+}
+
+func (s *submitJobStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *submitJobStream) Recv() (*subgraph.Subgraph, error) {
+	return s.next()
+}
+
+func (s *submitJobStream) SendAndClose(job *pb.Job) error {
+	s.job = job
+	return nil
+}
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/services/ingest/ingest"
+	"github.com/z5labs/megamind/services/ingest/jobs"
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/services/ingest/publisher"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func getJob(ctx context.Context, endpoint string) (*pb.Job, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, err
+	}
+	job := new(pb.Job)
+	err = protojson.Unmarshal(b, job)
+	return job, resp.StatusCode, err
+}
+
+func TestSubgraphIngester_Jobs(t *testing.T) {
+	t.Run("should submit an ndjson body as a job and report its progress", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisher.NewMemory()
+		ingester := ingest.NewSubgraphIngester(zap.L(), p, ingest.WithJobStore(jobs.NewMemory()))
+		addr, errCh := serveSubgraphIngester(ctx, zap.L(), ingester)
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := strings.Join([]string{
+			`{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`,
+			`{"triples":[{"subject":{"type":"","tuid":"2"},"predicate":{"name":"name"},"object":{"string":"Alice"}}]}`,
+		}, "\n")
+		endpoint := "http://" + addr.String() + "/subgraph/jobs"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusAccepted, resp.StatusCode) {
+			return
+		}
+
+		b, err := io.ReadAll(resp.Body)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		var submitted pb.Job
+		err = protojson.Unmarshal(b, &submitted)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, int64(2), submitted.NumOfSubgraphs) {
+			return
+		}
+		if !assert.Equal(subT, "/v1/jobs/"+submitted.Id, resp.Header.Get("Location")) {
+			return
+		}
+
+		var job *pb.Job
+		for job == nil || job.State != pb.Job_DONE {
+			var code int
+			job, code, err = getJob(ctx, "http://"+addr.String()+resp.Header.Get("Location"))
+			if !assert.Nil(subT, err) {
+				return
+			}
+			if !assert.Equal(subT, http.StatusOK, code) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !assert.Equal(subT, int64(1), job.NumOfAccepted) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), job.NumOfRejected) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), job.NumOfPublished) {
+			return
+		}
+		if !assert.Len(subT, p.Subgraphs(), 1) {
+			return
+		}
+	})
+
+	t.Run("should not submit a job if a line is malformed", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		store := jobs.NewMemory()
+		ingester := ingest.NewSubgraphIngester(zap.L(), publisher.NewMemory(), ingest.WithJobStore(store))
+		addr, errCh := serveSubgraphIngester(ctx, zap.L(), ingester)
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := `{"triples":[]}` + "\n" + `{"triples":[`
+		endpoint := "http://" + addr.String() + "/subgraph/jobs"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusBadRequest, resp.StatusCode) {
+			return
+		}
+
+		submitted, err := store.List()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, submitted) {
+			return
+		}
+	})
+
+	t.Run("should return not found for an unknown job", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		ingester := ingest.NewSubgraphIngester(zap.L(), publisher.NewMemory(), ingest.WithJobStore(jobs.NewMemory()))
+		addr, errCh := serveSubgraphIngester(ctx, zap.L(), ingester)
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		_, code, err := getJob(ctx, "http://"+addr.String()+"/subgraph/jobs/unknown")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusNotFound, code) {
+			return
+		}
+	})
+}
@@ -0,0 +1,486 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// openAPIDocument is the OpenAPI 3 document describing the RESTful API. The
+// schemas of request and response bodies are generated from the protobuf
+// messages they are the JSON mapping of, and the /v1/* paths of the REST
+// gateway from the google.api.http annotations in service.proto, so those
+// cannot drift from the SubgraphIngest contract. The deprecated /subgraph/*
+// paths are served by hand-written gin handlers, so they are described by
+// hand too and must be kept in sync with those handlers.
+var openAPIDocument = sync.OnceValues(func() ([]byte, error) {
+	return json.Marshal(newOpenAPIDocument())
+})
+
+func (s *SubgraphIngester) openAPI(c *gin.Context) {
+	b, err := openAPIDocument()
+	if err != nil {
+		s.log.Error("unexpected error when marshalling openapi document", zap.Error(err))
+		writeProblem(c, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", b)
+}
+
+type object = map[string]any
+
+func newOpenAPIDocument() object {
+	sb := &schemaBuilder{schemas: object{}}
+
+	subgraphRef := sb.message((&subgraph.Subgraph{}).ProtoReflect().Descriptor())
+	ingestResponseRef := sb.message((&pb.IngestResponse{}).ProtoReflect().Descriptor())
+	jobRef := sb.message((&pb.Job{}).ProtoReflect().Descriptor())
+
+	subgraphBody := object{
+		"required": true,
+		"content": object{
+			"application/json":       object{"schema": subgraphRef},
+			"application/x-protobuf": object{"schema": binarySchema},
+			"application/ld+json": object{
+				"schema": object{
+					"type":        "object",
+					"description": "JSON-LD document which is expanded into a subgraph.",
+				},
+			},
+			"application/x-ndjson": object{
+				"schema": object{
+					"type":        "string",
+					"description": "Newline delimited JSON with one subgraph.Subgraph per line.",
+				},
+			},
+		},
+	}
+
+	paths := object{
+		"/subgraph/ingest": object{
+			"post": object{
+				"operationId": "ingestSubgraph",
+				"summary":     "Ingest a subgraph, or every line of an NDJSON body as its own subgraph.",
+				"description": "Deprecated in favour of POST /v1/subgraph:ingest, or POST /v1/subgraphs:stream for many subgraphs.",
+				"deprecated":  true,
+				"requestBody": subgraphBody,
+				"responses": object{
+					"200": object{
+						"description": "The subgraph was ingested. For an NDJSON body, there is an acknowledgement for every line.",
+						"content": object{
+							"application/json":       object{"schema": ingestResponseRef},
+							"application/x-protobuf": object{"schema": binarySchema},
+							"application/x-ndjson": object{
+								"schema": object{
+									"type":        "string",
+									"description": "Newline delimited JSON with one proto.IngestAck per line, in the order of the lines, or an {\"error\": google.rpc.Status} line ending the stream.",
+								},
+							},
+						},
+					},
+					"default": problemResponseRef,
+				},
+			},
+		},
+		"/subgraph/jobs": object{
+			"post": object{
+				"operationId": "submitJob",
+				"summary":     "Submit a subgraph, or every line of an NDJSON body, as an ingest job.",
+				"description": "Deprecated in favour of POST /v1/jobs.",
+				"deprecated":  true,
+				"requestBody": subgraphBody,
+				"responses": object{
+					"202": object{
+						"description": "The job was recorded and is pending.",
+						"headers": object{
+							"Location": object{
+								"description": "Path to poll the progress of the job at, i.e. /v1/jobs/{id}.",
+								"schema":      object{"type": "string"},
+							},
+						},
+						"content": messageContent(jobRef),
+					},
+					"default": problemResponseRef,
+				},
+			},
+		},
+		"/subgraph/jobs/{id}": object{
+			"get": object{
+				"operationId": "getJob",
+				"summary":     "Get the progress of an ingest job.",
+				"description": "Deprecated in favour of GET /v1/jobs/{id}.",
+				"deprecated":  true,
+				"parameters": []any{
+					object{"name": "id", "in": "path", "required": true, "schema": object{"type": "string"}},
+				},
+				"responses": object{
+					"200":     object{"description": "The job.", "content": messageContent(jobRef)},
+					"default": problemResponseRef,
+				},
+			},
+		},
+		"/openapi.json": object{
+			"get": object{
+				"operationId": "getOpenAPI",
+				"summary":     "Get this document.",
+				"responses": object{
+					"200": object{
+						"description": "The OpenAPI document.",
+						"content":     object{"application/json": object{"schema": object{"type": "object"}}},
+					},
+				},
+			},
+		},
+	}
+	sd := pb.File_services_ingest_proto_service_proto.Services().ByName("SubgraphIngest")
+	for path, ops := range sb.gatewayPaths(sd) {
+		paths[path] = ops
+	}
+
+	sb.schemas["Problem"] = object{
+		"type":        "object",
+		"description": "RFC 7807 problem details.",
+		"required":    []any{"type", "title", "status"},
+		"properties": object{
+			"type":   object{"type": "string"},
+			"title":  object{"type": "string"},
+			"status": object{"type": "integer", "format": "int32"},
+			"detail": object{"type": "string"},
+			"violations": object{
+				"type":  "array",
+				"items": object{"$ref": "#/components/schemas/Violation"},
+			},
+		},
+	}
+	sb.schemas["Violation"] = object{
+		"type": "object",
+		"properties": object{
+			"field":       object{"type": "string"},
+			"description": object{"type": "string"},
+		},
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   "Megamind Subgraph Ingest API",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": sb.schemas,
+			"responses": object{
+				"Problem": object{
+					"description": "The request failed.",
+					"content": object{
+						"application/problem+json": object{
+							"schema": object{"$ref": "#/components/schemas/Problem"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+var (
+	binarySchema       = object{"type": "string", "format": "binary"}
+	problemResponseRef = object{"$ref": "#/components/responses/Problem"}
+)
+
+func messageContent(ref object) object {
+	return object{
+		"application/json":       object{"schema": ref},
+		"application/x-protobuf": object{"schema": binarySchema},
+	}
+}
+
+// pathParam matches the variables of a google.api.http path template.
+var pathParam = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+// gatewayPaths describes the REST endpoints the gateway maps the RPCs of a
+// service to. Streamed requests and responses are newline delimited JSON.
+func (sb *schemaBuilder) gatewayPaths(sd protoreflect.ServiceDescriptor) map[string]object {
+	paths := make(map[string]object)
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		rule, ok := proto.GetExtension(md.Options().(*descriptorpb.MethodOptions), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+
+		method, template := httpPattern(rule)
+		if method == "" {
+			continue
+		}
+		path := pathParam.ReplaceAllString(template, "{$1}")
+
+		op := object{
+			"operationId": string(sd.Name()) + "_" + string(md.Name()),
+			"responses": object{
+				"200":     sb.gatewayResponse(md),
+				"default": problemResponseRef,
+			},
+		}
+
+		var params []any
+		inPath := make(map[string]bool)
+		for _, m := range pathParam.FindAllStringSubmatch(template, -1) {
+			inPath[m[1]] = true
+			params = append(params, object{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   sb.field(md.Input().Fields().ByName(protoreflect.Name(m[1]))),
+			})
+		}
+		if rule.Body == "" {
+			fields := md.Input().Fields()
+			for j := 0; j < fields.Len(); j++ {
+				fd := fields.Get(j)
+				if inPath[string(fd.Name())] || fd.Kind() == protoreflect.MessageKind {
+					continue
+				}
+				params = append(params, object{
+					"name":   fd.JSONName(),
+					"in":     "query",
+					"schema": sb.field(fd),
+				})
+			}
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if rule.Body != "" {
+			op["requestBody"] = sb.gatewayRequest(md)
+		}
+
+		ops, ok := paths[path]
+		if !ok {
+			ops = object{}
+			paths[path] = ops
+		}
+		ops[method] = op
+	}
+	return paths
+}
+
+func httpPattern(rule *annotations.HttpRule) (method, template string) {
+	switch p := rule.Pattern.(type) {
+	case *annotations.HttpRule_Get:
+		return "get", p.Get
+	case *annotations.HttpRule_Put:
+		return "put", p.Put
+	case *annotations.HttpRule_Post:
+		return "post", p.Post
+	case *annotations.HttpRule_Delete:
+		return "delete", p.Delete
+	case *annotations.HttpRule_Patch:
+		return "patch", p.Patch
+	default:
+		return "", ""
+	}
+}
+
+func (sb *schemaBuilder) gatewayRequest(md protoreflect.MethodDescriptor) object {
+	if !md.IsStreamingClient() {
+		return object{
+			"required": true,
+			"content":  messageContent(sb.message(md.Input())),
+		}
+	}
+
+	// the schema of the lines is only referenced by name, so make sure it is defined
+	sb.message(md.Input())
+	return object{
+		"required": true,
+		"content": object{
+			"application/json": object{
+				"schema": object{
+					"type":        "string",
+					"description": fmt.Sprintf("Newline delimited JSON with one %s per line.", md.Input().FullName()),
+				},
+			},
+		},
+	}
+}
+
+func (sb *schemaBuilder) gatewayResponse(md protoreflect.MethodDescriptor) object {
+	if !md.IsStreamingServer() {
+		return object{
+			"description": "The response.",
+			"content":     messageContent(sb.message(md.Output())),
+		}
+	}
+
+	chunk := object{
+		"type": "object",
+		"properties": object{
+			"result": sb.message(md.Output()),
+			"error":  sb.message((&statuspb.Status{}).ProtoReflect().Descriptor()),
+		},
+	}
+	return object{
+		"description": "Newline delimited JSON with one result per line, or an error ending the stream.",
+		"content": object{
+			"application/json": object{"schema": chunk},
+		},
+	}
+}
+
+// schemaBuilder generates the JSON schemas of protobuf messages, as they
+// are mapped to JSON by protojson, into the components of an OpenAPI document.
+type schemaBuilder struct {
+	schemas object
+}
+
+// wellKnownSchemas are the well known types which have a special JSON mapping.
+var wellKnownSchemas = map[protoreflect.FullName]object{
+	"google.protobuf.Timestamp": {"type": "string", "format": "date-time"},
+	"google.protobuf.Duration":  {"type": "string", "description": "Seconds with an s suffix, e.g. 1.5s."},
+	"google.protobuf.Any": {
+		"type":                 "object",
+		"properties":           object{"@type": object{"type": "string"}},
+		"additionalProperties": true,
+	},
+}
+
+// message returns a reference to the schema of a message, adding it and the
+// schemas of every message and enum it depends on to the components.
+func (sb *schemaBuilder) message(md protoreflect.MessageDescriptor) object {
+	if s, ok := wellKnownSchemas[md.FullName()]; ok {
+		return s
+	}
+
+	name := string(md.FullName())
+	ref := object{"$ref": "#/components/schemas/" + name}
+	if _, ok := sb.schemas[name]; ok {
+		return ref
+	}
+
+	properties := object{}
+	schema := object{
+		"type":       "object",
+		"properties": properties,
+	}
+	// added before the fields so recursive messages terminate
+	sb.schemas[name] = schema
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		properties[fd.JSONName()] = sb.field(fd)
+	}
+
+	var descs []string
+	oneofs := md.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		od := oneofs.Get(i)
+		if od.IsSynthetic() {
+			continue
+		}
+		var names []string
+		for j := 0; j < od.Fields().Len(); j++ {
+			names = append(names, od.Fields().Get(j).JSONName())
+		}
+		descs = append(descs, fmt.Sprintf("At most one of %s may be set.", strings.Join(names, ", ")))
+	}
+	if len(descs) > 0 {
+		schema["description"] = strings.Join(descs, " ")
+	}
+	return ref
+}
+
+func (sb *schemaBuilder) enum(ed protoreflect.EnumDescriptor) object {
+	name := string(ed.FullName())
+	ref := object{"$ref": "#/components/schemas/" + name}
+	if _, ok := sb.schemas[name]; ok {
+		return ref
+	}
+
+	var values []any
+	for i := 0; i < ed.Values().Len(); i++ {
+		values = append(values, string(ed.Values().Get(i).Name()))
+	}
+	sb.schemas[name] = object{
+		"type": "string",
+		"enum": values,
+	}
+	return ref
+}
+
+func (sb *schemaBuilder) field(fd protoreflect.FieldDescriptor) object {
+	if fd.IsMap() {
+		return object{
+			"type":                 "object",
+			"additionalProperties": sb.value(fd.MapValue()),
+		}
+	}
+	if fd.IsList() {
+		return object{
+			"type":  "array",
+			"items": sb.value(fd),
+		}
+	}
+	return sb.value(fd)
+}
+
+// value returns the schema of a single value of a field. 64-bit integers are
+// strings, since protojson writes them as such to not lose precision.
+func (sb *schemaBuilder) value(fd protoreflect.FieldDescriptor) object {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return object{"type": "boolean"}
+	case protoreflect.EnumKind:
+		return sb.enum(fd.Enum())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return object{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return object{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return object{"type": "string", "format": "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return object{"type": "string", "format": "uint64"}
+	case protoreflect.FloatKind:
+		return object{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return object{"type": "number", "format": "double"}
+	case protoreflect.StringKind:
+		return object{"type": "string"}
+	case protoreflect.BytesKind:
+		return object{"type": "string", "format": "byte"}
+	default:
+		return sb.message(fd.Message())
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/services/ingest/publisher"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// refs collects every $ref in a decoded JSON document.
+func refs(v any) []string {
+	var rs []string
+	switch v := v.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if s, ok := val.(string); ok && key == "$ref" {
+				rs = append(rs, s)
+				continue
+			}
+			rs = append(rs, refs(val)...)
+		}
+	case []any:
+		for _, val := range v {
+			rs = append(rs, refs(val)...)
+		}
+	}
+	return rs
+}
+
+func TestSubgraphIngester_OpenAPI(t *testing.T) {
+	t.Run("should serve an openapi document", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr.String()+"/openapi.json", nil)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+		if !assert.Equal(subT, "application/json", resp.Header.Get("Content-Type")) {
+			return
+		}
+
+		var doc struct {
+			OpenAPI    string                    `json:"openapi"`
+			Paths      map[string]map[string]any `json:"paths"`
+			Components struct {
+				Schemas   map[string]any `json:"schemas"`
+				Responses map[string]any `json:"responses"`
+			} `json:"components"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&doc)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, "3.0.3", doc.OpenAPI) {
+			return
+		}
+		if !assert.Contains(subT, doc.Paths["/subgraph/ingest"], "post") {
+			return
+		}
+		if !assert.Equal(subT, true, doc.Paths["/subgraph/ingest"]["post"].(map[string]any)["deprecated"]) {
+			return
+		}
+		if !assert.NotContains(subT, doc.Paths["/v1/jobs/{id}"]["get"], "deprecated") {
+			return
+		}
+		if !assert.Contains(subT, doc.Paths["/v1/jobs/{id}"], "get") {
+			return
+		}
+		if !assert.Contains(subT, doc.Components.Schemas, "subgraph.Subgraph") {
+			return
+		}
+		if !assert.Contains(subT, doc.Components.Schemas, "Problem") {
+			return
+		}
+	})
+
+	t.Run("should describe every rpc of the gateway", func(subT *testing.T) {
+		sb := &schemaBuilder{schemas: object{}}
+		paths := sb.gatewayPaths(pb.File_services_ingest_proto_service_proto.Services().ByName("SubgraphIngest"))
+
+		var ops int
+		for _, methods := range paths {
+			ops += len(methods)
+		}
+		if !assert.Equal(subT, pb.File_services_ingest_proto_service_proto.Services().ByName("SubgraphIngest").Methods().Len(), ops) {
+			return
+		}
+	})
+
+	t.Run("should only reference defined components", func(subT *testing.T) {
+		b, err := openAPIDocument()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		var doc map[string]any
+		err = json.Unmarshal(b, &doc)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		components := doc["components"].(map[string]any)
+
+		for _, ref := range refs(doc) {
+			parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+			if !assert.Len(subT, parts, 2, ref) {
+				return
+			}
+			kind, _ := components[parts[0]].(map[string]any)
+			if !assert.Contains(subT, kind, parts[1], ref) {
+				return
+			}
+		}
+	})
+
+	t.Run("should map 64-bit integers to strings", func(subT *testing.T) {
+		sb := &schemaBuilder{schemas: object{}}
+		sb.message((&pb.Job{}).ProtoReflect().Descriptor())
+
+		job := sb.schemas["proto.Job"].(object)
+		property := job["properties"].(object)["numOfSubgraphs"]
+		if !assert.Equal(subT, object{"type": "string", "format": "int64"}, property) {
+			return
+		}
+	})
+}
@@ -2,17 +2,27 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/z5labs/megamind/services/ingest/ingest"
 	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/rdf"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
@@ -22,23 +32,79 @@ var ErrServerClosed = http.ErrServerClosed
 type SubgraphIngester struct {
 	log *zap.Logger
 
-	ingester *ingest.SubgraphIngester
+	ingester     *ingest.SubgraphIngester
+	callerHeader string
+	jsonld       []rdf.Option
+	grpc         *grpcHandler
 }
 
-func NewSubgraphIngester(log *zap.Logger, s *ingest.SubgraphIngester) *SubgraphIngester {
-	return &SubgraphIngester{
+// Option
+type Option func(*SubgraphIngester)
+
+// WithCallerHeader trusts the given request header to identify the caller,
+// e.g. when the service sits behind an authenticating proxy.
+func WithCallerHeader(name string) Option {
+	return func(s *SubgraphIngester) {
+		s.callerHeader = name
+	}
+}
+
+// WithJSONLD configures how JSON-LD documents are mapped to subgraphs,
+// e.g. with rdf.WithRules or rdf.WithContextLoader.
+func WithJSONLD(opts ...rdf.Option) Option {
+	return func(s *SubgraphIngester) {
+		s.jsonld = append(s.jsonld, opts...)
+	}
+}
+
+func NewSubgraphIngester(log *zap.Logger, s *ingest.SubgraphIngester, opts ...Option) *SubgraphIngester {
+	si := &SubgraphIngester{
 		log:      log,
 		ingester: s,
 	}
+	for _, opt := range opts {
+		opt(si)
+	}
+	return si
 }
 
 func (s *SubgraphIngester) Serve(ctx context.Context, ls net.Listener) error {
+	gw, err := newGateway(s.ingester)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := gw.stop()
+		if err != nil {
+			s.log.Error("unexpected error when stopping rest gateway", zap.Error(err))
+		}
+	}()
+
 	r := gin.New()
-	r.Use(logger(s.log))
-	r.POST("/subgraph/ingest", s.ingest)
+	r.Use(logger(s.log), caller(s.callerHeader))
+
+	// The /subgraph/* routes predate the REST gateway and are kept for
+	// existing clients, so they are deprecated in favour of its /v1/* routes.
+	r.POST("/subgraph/ingest", deprecated(subgraphRoutesDeprecatedAt, successorPath("/v1/subgraph:ingest")), s.ingest)
+	r.POST("/subgraph/jobs", deprecated(subgraphRoutesDeprecatedAt, successorPath("/v1/jobs")), s.submitJob)
+	r.GET("/subgraph/jobs/:id", deprecated(subgraphRoutesDeprecatedAt, func(c *gin.Context) string {
+		return "/v1/jobs/" + url.PathEscape(c.Param("id"))
+	}), s.getJob)
+	r.Any("/v1/*path", gin.WrapH(gw))
+	r.GET("/openapi.json", s.openAPI)
 
 	srv := &http.Server{
-		Handler: r,
+		Handler: fullDuplex(r),
+	}
+	if s.grpc != nil {
+		h2s := &http2.Server{}
+		srv.Handler = h2c.NewHandler(s.grpc.route(srv.Handler), h2s)
+
+		// Lets the shutdown of srv tell HTTP/2 clients to go away.
+		err = http2.ConfigureServer(srv, h2s)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Run http server in goroutine
@@ -62,7 +128,10 @@ func (s *SubgraphIngester) Serve(ctx context.Context, ls net.Listener) error {
 	shutCtx, shutCancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer shutCancel()
 
-	err := srv.Shutdown(shutCtx)
+	err = srv.Shutdown(shutCtx)
+	if s.grpc != nil {
+		s.grpc.stop(shutCtx)
+	}
 	if errors.Is(err, context.Canceled) {
 		s.log.Warn("shutdown timed out before all connections could be closed")
 		return nil
@@ -77,16 +146,27 @@ func (s *SubgraphIngester) Serve(ctx context.Context, ls net.Listener) error {
 	return ErrServerClosed
 }
 
+var errUnsupportedContentType = errors.New("content-type must be one of application/json, application/ld+json, application/x-protobuf or application/x-ndjson")
+
+// binding returns the binding for the media type of a request body.
+func (s *SubgraphIngester) binding(contentType string) (binding.Binding, error) {
+	switch contentType {
+	case "application/json":
+		return protoJSON, nil
+	case "application/ld+json":
+		return jsonLDBinding{opts: s.jsonld}, nil
+	case "application/x-protobuf":
+		return protoBinary, nil
+	default:
+		return nil, errUnsupportedContentType
+	}
+}
+
 type protoJsonBinding struct{}
 
 func (b protoJsonBinding) Name() string { return "protoJsonBinding" }
 
 func (b protoJsonBinding) Bind(req *http.Request, v any) error {
-	contentType := req.Header.Get("Content-Type")
-	if contentType != "application/json" {
-		return errors.New("content-type must be application/json")
-	}
-
 	m, ok := v.(proto.Message)
 	if !ok {
 		panic("can only unmarshal request body into a protocol buffer message type")
@@ -101,19 +181,193 @@ func (b protoJsonBinding) Bind(req *http.Request, v any) error {
 
 var protoJSON = protoJsonBinding{}
 
+type protoBinaryBinding struct{}
+
+func (b protoBinaryBinding) Name() string { return "protoBinaryBinding" }
+
+func (b protoBinaryBinding) Bind(req *http.Request, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		panic("can only unmarshal request body into a protocol buffer message type")
+	}
+
+	bs, err := readAllAndClose(req.Body)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(bs, m)
+}
+
+var protoBinary = protoBinaryBinding{}
+
+// jsonLDBinding expands a JSON-LD document into a subgraph.
+type jsonLDBinding struct {
+	opts []rdf.Option
+}
+
+func (b jsonLDBinding) Name() string { return "jsonLDBinding" }
+
+func (b jsonLDBinding) Bind(req *http.Request, v any) error {
+	g, ok := v.(*subgraph.Subgraph)
+	if !ok {
+		panic("can only expand request body into a subgraph")
+	}
+	defer req.Body.Close()
+
+	doc, err := rdf.ReadJSONLD(req.Body, b.opts...)
+	if err != nil {
+		return err
+	}
+	proto.Merge(g, doc)
+	return nil
+}
+
 func (s *SubgraphIngester) ingest(c *gin.Context) {
+	if c.ContentType() == "application/x-ndjson" {
+		s.ingestStream(c)
+		return
+	}
+
+	bodyBinding, err := s.binding(c.ContentType())
+	if err != nil {
+		s.log.Error("unsupported request content type", zap.String("content_type", c.ContentType()))
+		writeProblem(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
 	var subgraph subgraph.Subgraph
-	err := c.MustBindWith(&subgraph, protoJSON)
+	err = c.ShouldBindWith(&subgraph, bodyBinding)
 	if err != nil {
 		s.log.Error("unexpected error when unmarshalling request body", zap.Error(err))
+		writeProblem(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	resp, err := s.ingester.IngestSubgraph(c.Request.Context(), &subgraph)
+	if err != nil {
+		s.log.Error("failed to ingest subgraph", zap.Error(err))
+		writeError(c, err)
 		return
 	}
+	s.writeMessage(c, http.StatusOK, resp)
+}
+
+// writeMessage writes the message as protobuf, if the client prefers
+// it, and otherwise as JSON.
+func (s *SubgraphIngester) writeMessage(c *gin.Context, statusCode int, m proto.Message) {
+	contentType := c.NegotiateFormat("application/json", "application/x-protobuf")
 
-	_, err = s.ingester.IngestSubgraph(c, &subgraph)
+	var (
+		b   []byte
+		err error
+	)
+	switch contentType {
+	case "application/x-protobuf":
+		b, err = proto.Marshal(m)
+	default:
+		contentType = "application/json"
+		b, err = protojson.Marshal(m)
+	}
 	if err != nil {
-		c.Status(http.StatusInternalServerError)
+		s.log.Error("unexpected error when marshalling response", zap.Error(err))
+		writeProblem(c, http.StatusInternalServerError, err.Error(), nil)
 		return
 	}
+	c.Data(statusCode, contentType, b)
+}
+
+// problem is an RFC 7807 problem details document.
+type problem struct {
+	Type       string      `json:"type"`
+	Title      string      `json:"title"`
+	Status     int         `json:"status"`
+	Detail     string      `json:"detail,omitempty"`
+	Violations []violation `json:"violations,omitempty"`
+}
+
+type violation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+func newProblem(statusCode int, detail string, violations []violation) problem {
+	return problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(statusCode),
+		Status:     statusCode,
+		Detail:     detail,
+		Violations: violations,
+	}
+}
+
+func writeProblem(c *gin.Context, statusCode int, detail string, violations []violation) {
+	encodeProblem(c.Writer, newProblem(statusCode, detail, violations))
+}
+
+func encodeProblem(w http.ResponseWriter, p problem) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	w.Write(b)
+}
+
+// writeError writes a problem document for an error returned by the ingest service.
+func writeError(c *gin.Context, err error) {
+	encodeProblem(c.Writer, problemFromError(err))
+}
+
+// problemFromError maps the gRPC status of an error to a problem document.
+func problemFromError(err error) problem {
+	st := status.Convert(err)
+
+	var violations []violation
+	for _, detail := range st.Details() {
+		br, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, fv := range br.FieldViolations {
+			violations = append(violations, violation{
+				Field:       fv.Field,
+				Description: fv.Description,
+			})
+		}
+	}
+
+	return newProblem(httpStatusFromCode(st.Code()), st.Message(), violations)
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 func logger(log *zap.Logger) gin.HandlerFunc {
@@ -126,6 +380,50 @@ func logger(log *zap.Logger) gin.HandlerFunc {
 	}
 }
 
+// subgraphRoutesDeprecatedAt is when the /subgraph/* routes were deprecated.
+var subgraphRoutesDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// deprecated marks the responses of a route as deprecated since the given
+// time, see RFC 9745, and links to the route which succeeds it.
+func deprecated(since time.Time, successor func(*gin.Context) string) gin.HandlerFunc {
+	// RFC 9745 requires a structured field date, i.e. @<unix seconds>
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor(c)))
+
+		c.Next()
+	}
+}
+
+func successorPath(path string) func(*gin.Context) string {
+	return func(*gin.Context) string { return path }
+}
+
+// caller adds the caller of the request to its context. The caller is taken
+// from the trusted header, if one is configured and present, and otherwise
+// from the common name of the verified client certificate.
+func caller(header string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var name string
+		if header != "" {
+			name = c.GetHeader(header)
+		}
+		if tlsState := c.Request.TLS; name == "" && tlsState != nil {
+			chains := tlsState.VerifiedChains
+			if len(chains) > 0 && len(chains[0]) > 0 {
+				name = chains[0][0].Subject.CommonName
+			}
+		}
+		if name != "" {
+			ctx := ingest.ContextWithCaller(c.Request.Context(), name)
+			c.Request = c.Request.WithContext(ctx)
+		}
+
+		c.Next()
+	}
+}
+
 func readAllAndClose(rc io.ReadCloser) ([]byte, error) {
 	defer rc.Close()
 
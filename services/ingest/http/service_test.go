@@ -1,20 +1,38 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/z5labs/megamind/services/ingest/ingest"
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/services/ingest/publisher"
+	"github.com/z5labs/megamind/subgraph"
 )
 
-func newSubgraphIngester(ctx context.Context, logger *zap.Logger) (net.Addr, <-chan error) {
+func newSubgraphIngester(ctx context.Context, logger *zap.Logger, p ingest.Publisher, opts ...Option) (net.Addr, <-chan error) {
+	return serveSubgraphIngester(ctx, logger, ingest.NewSubgraphIngester(logger, p), opts...)
+}
+
+func serveSubgraphIngester(ctx context.Context, logger *zap.Logger, ingester *ingest.SubgraphIngester, opts ...Option) (net.Addr, <-chan error) {
 	errCh := make(chan error, 1)
 	ls, err := net.Listen("tcp", ":0")
 	if err != nil {
@@ -22,7 +40,7 @@ func newSubgraphIngester(ctx context.Context, logger *zap.Logger) (net.Addr, <-c
 		return nil, errCh
 	}
 
-	s := NewSubgraphIngester(logger, ingest.NewSubgraphIngester(logger))
+	s := NewSubgraphIngester(logger, ingester, opts...)
 	go func() {
 		defer close(errCh)
 		err := s.Serve(ctx, ls)
@@ -37,7 +55,7 @@ func TestSubgraphIngester(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		addr, errCh := newSubgraphIngester(ctx, zap.L())
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
 		if !assert.NotNil(subT, addr) {
 			subT.Error(<-errCh)
 			return
@@ -54,7 +72,7 @@ func TestSubgraphIngester(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		addr, errCh := newSubgraphIngester(ctx, zap.L())
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
 		if !assert.NotNil(subT, addr) {
 			subT.Error(<-errCh)
 			return
@@ -79,7 +97,7 @@ func TestSubgraphIngester(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		addr, errCh := newSubgraphIngester(ctx, zap.L())
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
 		if !assert.NotNil(subT, addr) {
 			subT.Error(<-errCh)
 			return
@@ -100,4 +118,560 @@ func TestSubgraphIngester(t *testing.T) {
 			return
 		}
 	})
+	t.Run("should return an ingest receipt", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := `{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+		if !assert.Equal(subT, "@1792281600", resp.Header.Get("Deprecation")) {
+			return
+		}
+		if !assert.Equal(subT, `</v1/subgraph:ingest>; rel="successor-version"`, resp.Header.Get("Link")) {
+			return
+		}
+
+		b, err := io.ReadAll(resp.Body)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		var receipt pb.IngestResponse
+		err = protojson.Unmarshal(b, &receipt)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.NotEmpty(subT, receipt.IngestId) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), receipt.NumOfTriples) {
+			return
+		}
+	})
+	t.Run("should return a problem document if subgraph is invalid", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := `{"triples":[{"subject":{"type":"Person"},"predicate":{"name":"name"},"object":{}}]}`
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusBadRequest, resp.StatusCode) {
+			return
+		}
+		if !assert.Equal(subT, "application/problem+json", resp.Header.Get("Content-Type")) {
+			return
+		}
+
+		var p problem
+		err = json.NewDecoder(resp.Body).Decode(&p)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusBadRequest, p.Status) {
+			return
+		}
+		expected := []violation{
+			{Field: "triples[0].subject.tuid", Description: "must not be empty"},
+			{Field: "triples[0].object.value", Description: "must be set"},
+		}
+		if !assert.Equal(subT, expected, p.Violations) {
+			return
+		}
+	})
+
+	t.Run("should stamp the caller from the trusted header", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisher.NewMemory()
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), p, WithCallerHeader("X-Caller"))
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := `{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Caller", "alice")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		if !assert.Equal(subT, "alice", gs[0].GetProvenance().GetCaller()) {
+			return
+		}
+	})
+
+	t.Run("should accept retractions and subject deletions", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisher.NewMemory()
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), p)
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := `{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"operation":"RETRACT"},{"subject":{"type":"Person","tuid":"2"},"operation":"DELETE_SUBJECT"}]}`
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		if !assert.Equal(subT, subgraph.Operation_RETRACT, gs[0].Triples[0].Operation) {
+			return
+		}
+		if !assert.Equal(subT, subgraph.Operation_DELETE_SUBJECT, gs[0].Triples[1].Operation) {
+			return
+		}
+	})
+
+	t.Run("should expand a JSON-LD document into a subgraph", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisher.NewMemory()
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), p)
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := `{"@context":"https://schema.org","@type":"Person","@id":"https://example.com/people/1","name":"Bob","birthDate":{"@value":"1980-01-02","@type":"Date"}}`
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/ld+json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		if !assert.Len(subT, gs[0].Triples, 2) {
+			return
+		}
+		for _, triple := range gs[0].Triples {
+			if !assert.Equal(subT, "Person", triple.Subject.Type) {
+				return
+			}
+			if !assert.Equal(subT, "https://example.com/people/1", triple.Subject.Tuid) {
+				return
+			}
+		}
+		if !assert.Equal(subT, "birthDate", gs[0].Triples[0].Predicate.Name) {
+			return
+		}
+		if !assert.Equal(subT, "Bob", gs[0].Triples[1].Object.GetString_()) {
+			return
+		}
+	})
+
+	t.Run("should return bad request if a JSON-LD document cannot be expanded", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := `{"@context":"https://example.com/unknown.jsonld","name":"Bob"}`
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/ld+json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusBadRequest, resp.StatusCode) {
+			return
+		}
+		if !assert.Equal(subT, "application/problem+json", resp.Header.Get("Content-Type")) {
+			return
+		}
+	})
+
+	t.Run("should accept a protobuf encoded subgraph", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisher.NewMemory()
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), p)
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body, err := proto.Marshal(&subgraph.Subgraph{
+			Triples: []*subgraph.Triple{
+				{
+					Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+					Predicate: &subgraph.Predicate{Name: "name"},
+					Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Bob"}},
+				},
+			},
+		})
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("Accept", "application/x-protobuf")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+		if !assert.Equal(subT, "application/x-protobuf", resp.Header.Get("Content-Type")) {
+			return
+		}
+
+		b, err := io.ReadAll(resp.Body)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		var receipt pb.IngestResponse
+		err = proto.Unmarshal(b, &receipt)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), receipt.NumOfTriples) {
+			return
+		}
+		if !assert.Len(subT, p.Subgraphs(), 1) {
+			return
+		}
+	})
+
+	t.Run("should ingest every line of an ndjson body and return a result per line", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisher.NewMemory()
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), p)
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		body := strings.Join([]string{
+			`{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`,
+			`{"triples":[`,
+			``,
+			`{"triples":[{"subject":{"type":"","tuid":"2"},"predicate":{"name":"name"},"object":{"string":"Alice"}}]}`,
+			`{"triples":[{"subject":{"type":"Person","tuid":"3"},"predicate":{"name":"name"},"object":{"string":"Eve"}}]}`,
+		}, "\n")
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+		if !assert.Equal(subT, "application/x-ndjson", resp.Header.Get("Content-Type")) {
+			return
+		}
+
+		var acks []*pb.IngestAck
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			var ack pb.IngestAck
+			err := protojson.Unmarshal(sc.Bytes(), &ack)
+			if !assert.Nil(subT, err) {
+				return
+			}
+			acks = append(acks, &ack)
+		}
+		if !assert.Nil(subT, sc.Err()) {
+			return
+		}
+		if !assert.Len(subT, acks, 4) {
+			return
+		}
+
+		expected := []struct {
+			Line   uint64
+			Status pb.IngestAck_Status
+		}{
+			{Line: 1, Status: pb.IngestAck_ACK},
+			{Line: 2, Status: pb.IngestAck_NACK},
+			{Line: 4, Status: pb.IngestAck_NACK},
+			{Line: 5, Status: pb.IngestAck_ACK},
+		}
+		for i, e := range expected {
+			if !assert.Equal(subT, e.Line, acks[i].Sequence) {
+				return
+			}
+			if !assert.Equal(subT, e.Status, acks[i].Status, acks[i].Error) {
+				return
+			}
+		}
+		if !assert.Len(subT, p.Subgraphs(), 2) {
+			return
+		}
+	})
+
+	t.Run("should return bad request for an empty ndjson body", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), publisher.NewMemory())
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader("\n\n"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusBadRequest, resp.StatusCode) {
+			return
+		}
+	})
+
+	t.Run("should write the result of a line before the next line is sent", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisher.NewMemory()
+		addr, errCh := newSubgraphIngester(ctx, zap.L(), p)
+		if !assert.NotNil(subT, addr) {
+			subT.Error(<-errCh)
+			return
+		}
+
+		pr, pw := io.Pipe()
+		defer pw.Close()
+
+		endpoint := "http://" + addr.String() + "/subgraph/ingest"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, pr)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+
+		respCh := make(chan *http.Response, 1)
+		go func() {
+			defer close(respCh)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				subT.Error(err)
+				return
+			}
+			respCh <- resp
+		}()
+
+		_, err = io.WriteString(pw, `{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`+"\n")
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		resp, ok := <-respCh
+		if !assert.True(subT, ok) {
+			return
+		}
+		defer resp.Body.Close()
+		if !assert.Equal(subT, http.StatusOK, resp.StatusCode) {
+			return
+		}
+
+		sc := bufio.NewScanner(resp.Body)
+		if !assert.True(subT, sc.Scan(), sc.Err()) {
+			return
+		}
+
+		var ack pb.IngestAck
+		err = protojson.Unmarshal(sc.Bytes(), &ack)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, uint64(1), ack.Sequence) {
+			return
+		}
+		if !assert.Equal(subT, pb.IngestAck_ACK, ack.Status, ack.Error) {
+			return
+		}
+
+		_, err = io.WriteString(pw, `{"triples":[{"subject":{"type":"Person","tuid":"2"},"predicate":{"name":"name"},"object":{"string":"Alice"}}]}`+"\n")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		pw.Close()
+
+		if !assert.True(subT, sc.Scan(), sc.Err()) {
+			return
+		}
+		err = protojson.Unmarshal(sc.Bytes(), &ack)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, uint64(2), ack.Sequence) {
+			return
+		}
+		if !assert.False(subT, sc.Scan()) {
+			return
+		}
+		if !assert.Len(subT, p.Subgraphs(), 2) {
+			return
+		}
+	})
+
+	t.Run("should end the ndjson response with an error line if reading the body fails", func(subT *testing.T) {
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), ingest.NewSubgraphIngester(zap.L(), p))
+
+		readErr := errors.New("connection reset")
+		body := io.MultiReader(
+			strings.NewReader(`{"triples":[{"subject":{"type":"Person","tuid":"1"},"predicate":{"name":"name"},"object":{"string":"Bob"}}]}`+"\n"),
+			iotest.ErrReader(readErr),
+		)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/subgraph/ingest", body)
+		c.Request.Header.Set("Content-Type", "application/x-ndjson")
+		s.ingestStream(c)
+
+		if !assert.Equal(subT, http.StatusOK, w.Code) {
+			return
+		}
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if !assert.Len(subT, lines, 2) {
+			return
+		}
+
+		var ack pb.IngestAck
+		err := protojson.Unmarshal([]byte(lines[0]), &ack)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, pb.IngestAck_ACK, ack.Status, ack.Error) {
+			return
+		}
+
+		var chunk struct {
+			Error struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		err = json.Unmarshal([]byte(lines[1]), &chunk)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, int(codes.InvalidArgument), chunk.Error.Code) {
+			return
+		}
+		if !assert.Equal(subT, readErr.Error(), chunk.Error.Message) {
+			return
+		}
+	})
 }
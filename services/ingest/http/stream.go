@@ -0,0 +1,262 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var errEmptyStream = errors.New("request body must contain at least one subgraph")
+
+// ingestStream ingests every line of an NDJSON request body as its own
+// subgraph. Lines are read as they are needed and go through the same
+// path as the IngestStream RPC, with the line number as their sequence,
+// so one malformed or invalid line does not fail the lines around it.
+// The response is an NDJSON document with the IngestAck for every
+// non-empty line, in the order of the lines, which is written as soon
+// as the ack for a line and every line before it is ready. An error
+// which ends the stream after the first ack has been written is reported
+// by an {"error": ...} line, like the streaming /v1 endpoints.
+func (s *SubgraphIngester) ingestStream(c *gin.Context) {
+	stream := &ndjsonStream{
+		ctx:  c.Request.Context(),
+		r:    newNDJSONReader(c.Request.Body),
+		w:    c.Writer,
+		acks: make(map[uint64]*pb.IngestAck),
+	}
+	defer c.Request.Body.Close()
+
+	err := s.ingester.IngestStream(stream)
+	if err != nil {
+		s.log.Error("failed to read ndjson request body", zap.Error(err))
+		if !stream.started() {
+			writeProblem(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		err = stream.writeError(status.New(codes.InvalidArgument, err.Error()))
+		if err != nil {
+			s.log.Error("unexpected error when writing response", zap.Error(err))
+		}
+		return
+	}
+	if !stream.started() {
+		writeProblem(c, http.StatusBadRequest, errEmptyStream.Error(), nil)
+		return
+	}
+}
+
+// fullDuplex lets the handler of an NDJSON request write its response
+// while it's still reading the request body, which an HTTP/1 server
+// otherwise closes once the response has been started. It has to wrap
+// the gin engine since gin does not expose the underlying writer.
+func fullDuplex(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 1 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson") {
+			// only fails for connections which are always full duplex, like HTTP/2
+			http.NewResponseController(w).EnableFullDuplex()
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ndjsonStream adapts an NDJSON request body to the server side of an
+// IngestStream and writes the acks to the response in the order of the lines.
+type ndjsonStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+	r   *ndjsonReader
+	w   gin.ResponseWriter
+
+	// Send is called concurrently with Recv
+	mu sync.Mutex
+	// lines are the non-empty lines which have been read
+	// but whose ack has not been written yet, in order
+	lines   []uint64
+	acks    map[uint64]*pb.IngestAck
+	written bool
+}
+
+func (s *ndjsonStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *ndjsonStream) Recv() (*pb.IngestStreamRequest, error) {
+	for {
+		g, err := s.r.next()
+		var lerr *lineError
+		if errors.As(err, &lerr) {
+			// A malformed line is rejected without failing the whole stream.
+			s.read(lerr.line)
+			err := s.Send(&pb.IngestAck{
+				Sequence: lerr.line,
+				Status:   pb.IngestAck_NACK,
+				Error:    lerr.err.Error(),
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.read(s.r.line)
+		return &pb.IngestStreamRequest{Sequence: s.r.line, Subgraph: g}, nil
+	}
+}
+
+func (s *ndjsonStream) read(line uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lines = append(s.lines, line)
+}
+
+// Send writes the ack, and any acks of the lines after it which were
+// waiting on it, if the acks of every line before it have been written.
+func (s *ndjsonStream) Send(ack *pb.IngestAck) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.acks[ack.Sequence] = ack
+	n := 0
+	for ; n < len(s.lines); n++ {
+		ack, ok := s.acks[s.lines[n]]
+		if !ok {
+			break
+		}
+		delete(s.acks, s.lines[n])
+
+		err := s.writeLine(ack)
+		if err != nil {
+			return err
+		}
+	}
+	s.lines = s.lines[n:]
+	if n > 0 {
+		s.w.Flush()
+	}
+	return nil
+}
+
+func (s *ndjsonStream) started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.written
+}
+
+// writeError ends the response with an {"error": ...} line.
+func (s *ndjsonStream) writeError(st *status.Status) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := protojson.Marshal(st.Proto())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "{\"error\":%s}\n", b)
+	if err != nil {
+		return err
+	}
+	s.w.Flush()
+	return nil
+}
+
+func (s *ndjsonStream) writeLine(m proto.Message) error {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if !s.written {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.w.WriteHeader(http.StatusOK)
+		s.written = true
+	}
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+// lineError is returned for a line of an NDJSON body which is not a subgraph.
+type lineError struct {
+	line uint64
+	err  error
+}
+
+func (e *lineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.err)
+}
+
+func (e *lineError) Unwrap() error {
+	return e.err
+}
+
+// ndjsonReader reads one subgraph per line and skips empty lines.
+type ndjsonReader struct {
+	r *bufio.Reader
+
+	// line is the number of the line last read
+	line uint64
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	return &ndjsonReader{r: bufio.NewReader(r)}
+}
+
+// next returns the subgraph on the next non-empty line,
+// or io.EOF once every line has been read.
+func (r *ndjsonReader) next() (*subgraph.Subgraph, error) {
+	for {
+		b, err := r.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(b) == 0) {
+			return nil, err
+		}
+		r.line += 1
+
+		b = bytes.TrimSpace(b)
+		if len(b) == 0 {
+			continue
+		}
+
+		g := new(subgraph.Subgraph)
+		err = protojson.Unmarshal(b, g)
+		if err != nil {
+			return nil, &lineError{line: r.line, err: err}
+		}
+		return g, nil
+	}
+}
@@ -2,70 +2,483 @@ package ingest
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	pb "github.com/z5labs/megamind/services/ingest/proto"
 	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/merge"
+	"github.com/z5labs/megamind/subgraph/schema"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/status"
 )
 
+// Publisher publishes ingested subgraphs to a downstream sink.
+type Publisher interface {
+	Publish(ctx context.Context, g *subgraph.Subgraph) error
+}
+
+// WriteAheadLog durably records subgraphs before they are published.
+type WriteAheadLog interface {
+	Append(g *subgraph.Subgraph) (uint64, error)
+	Ack(seq uint64) error
+	Replay(fn func(seq uint64, g *subgraph.Subgraph) error) error
+}
+
+// Option
+type Option func(*SubgraphIngester)
+
+// WithWriteAheadLog persists every accepted subgraph to the given log
+// before acknowledging it. Subgraphs are acknowledged in the log once
+// they have been published.
+func WithWriteAheadLog(w WriteAheadLog) Option {
+	return func(s *SubgraphIngester) {
+		s.wal = w
+	}
+}
+
+// WithClock sets the hybrid logical clock used to timestamp triples.
+// It defaults to a clock named after the hostname.
+func WithClock(c *merge.Clock) Option {
+	return func(s *SubgraphIngester) {
+		s.clock = c
+	}
+}
+
 // SubgraphIngester
 type SubgraphIngester struct {
 	pb.UnimplementedSubgraphIngestServer
 
-	log *zap.Logger
+	log        *zap.Logger
+	pub        Publisher
+	wal        WriteAheadLog
+	schema     *schema.Schema
+	schemaMode SchemaMode
+	clock      *merge.Clock
+
+	jobs     JobStore
+	jobSlots chan struct{}
+	jobsMu   sync.Mutex
+	running  map[string]*pb.Job
+	jobsCtx  context.Context
+	stopJobs context.CancelFunc
+	jobsWG   sync.WaitGroup
+
+	// delivering holds the entries of the write-ahead log which are
+	// being published and delivered the ones which were published
+	// while a Replay was running, which may have read them before
+	// they were acknowledged.
+	deliveryMu sync.Mutex
+	delivering map[uint64]struct{}
+	delivered  map[uint64]struct{}
+	replaying  int
 }
 
 // NewSubgraphIngester
-func NewSubgraphIngester(l *zap.Logger) *SubgraphIngester {
-	return &SubgraphIngester{
-		log: l,
+func NewSubgraphIngester(l *zap.Logger, p Publisher, opts ...Option) *SubgraphIngester {
+	s := &SubgraphIngester{
+		log:      l,
+		pub:      p,
+		jobSlots: make(chan struct{}, maxRunningJobs),
+		running:  make(map[string]*pb.Job),
+
+		delivering: make(map[uint64]struct{}),
+		delivered:  make(map[uint64]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	if s.clock == nil {
+		s.clock = merge.NewClock(DefaultNodeID())
+	}
+	s.jobsCtx, s.stopJobs = context.WithCancel(context.Background())
+	return s
 }
 
 // IngestSubgraph
 func (s *SubgraphIngester) IngestSubgraph(ctx context.Context, g *subgraph.Subgraph) (*pb.IngestResponse, error) {
-	err := s.publish(ctx, g)
-	return new(pb.IngestResponse), err
+	warnings, err := s.validate(g)
+	if err != nil {
+		return nil, err
+	}
+	s.stamp(ctx, g)
+
+	resp, err := newIngestResponse()
+	if err != nil {
+		return nil, err
+	}
+	addSubgraph(resp, 0, g, warnings)
+
+	if s.wal == nil {
+		err := s.publish(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		return accepted(resp), nil
+	}
+
+	seq, err := s.append(g)
+	if err != nil {
+		return nil, err
+	}
+
+	// The subgraph has been durably accepted at this point so a failure
+	// to publish it is only logged. It stays in the write-ahead log until
+	// a later Replay, e.g. by Redeliver, manages to publish it.
+	err = s.deliver(ctx, seq, g)
+	if err != nil {
+		s.log.Error(
+			"unexpected error when publishing subgraph",
+			zap.Error(err),
+			zap.Uint64("seq", seq),
+			withNumOfTriples(g),
+			withNumOfDistinctSubjects(g),
+		)
+	}
+	return accepted(resp), nil
 }
 
-// Ingest
-func (s *SubgraphIngester) Ingest(stream pb.SubgraphIngest_IngestServer) error {
+var errUndelivered = errors.New("failed to publish subgraphs from write-ahead log")
+
+// Replay publishes every subgraph in the write-ahead log which
+// has not been acknowledged yet, e.g. because the service was
+// restarted before it could publish them or publishing them failed.
+// A subgraph which fails to publish again is logged and left in the
+// log for the next Replay; it does not stop the ones after it from
+// being published.
+func (s *SubgraphIngester) Replay(ctx context.Context) error {
+	if s.wal == nil {
+		return nil
+	}
+
+	s.beginReplay()
+	defer s.endReplay()
+
+	n, failed := 0, 0
+	err := s.wal.Replay(func(seq uint64, g *subgraph.Subgraph) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// it's still being, or has already been, published
+		// by whoever appended it
+		if !s.claim(seq) {
+			return nil
+		}
+
+		err := s.deliver(ctx, seq, g)
+		if err != nil {
+			failed += 1
+			s.log.Error(
+				"unexpected error when replaying subgraph",
+				zap.Error(err),
+				zap.Uint64("seq", seq),
+				withNumOfTriples(g),
+				withNumOfDistinctSubjects(g),
+			)
+			return nil
+		}
+		n += 1
+		return nil
+	})
+	if n > 0 || failed > 0 {
+		s.log.Info(
+			"replayed write-ahead log",
+			zap.Int("num_of_subgraphs", n),
+			zap.Int("num_of_failed_subgraphs", failed),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d failed", errUndelivered, failed, n+failed)
+	}
+	return nil
+}
+
+// Redeliver calls Replay every interval until ctx is cancelled, so
+// subgraphs which failed to publish are retried while the service
+// is running and the write-ahead log can be truncated behind them.
+func (s *SubgraphIngester) Redeliver(ctx context.Context, interval time.Duration) {
+	if s.wal == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
 	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// failed subgraphs have already been logged by Replay
+		err := s.Replay(ctx)
+		if err != nil && !errors.Is(err, errUndelivered) && ctx.Err() == nil {
+			s.log.Error("unexpected error when replaying write-ahead log", zap.Error(err))
+		}
+	}
+}
+
+// Ingest accepts every subgraph sent on the stream, or none of them. Every
+// subgraph is validated as it is received, but none are recorded or published
+// until the stream has ended, so an invalid subgraph fails the whole stream
+// without any of the subgraphs sent before it having been ingested.
+func (s *SubgraphIngester) Ingest(stream pb.SubgraphIngest_IngestServer) error {
+	resp, err := newIngestResponse()
+	if err != nil {
+		return err
+	}
+
+	var subgraphs []*subgraph.Subgraph
+	for i := 0; ; i++ {
 		g, err := stream.Recv()
 		if err == io.EOF {
-			return nil
+			break
 		}
 		if err != nil {
 			return err
 		}
 		s.log.Info(
 			"received subgraph",
-			withSubgraphStats(g)...,
+			append(withSubgraphStats(g), zap.String("ingest_id", resp.IngestId))...,
 		)
+		warnings, err := s.validate(g)
+		if err != nil {
+			s.log.Warn(
+				"rejected invalid subgraph",
+				zap.Error(err),
+				zap.String("ingest_id", resp.IngestId),
+				zap.Int("subgraph_index", i),
+			)
+			return err
+		}
+		s.stamp(stream.Context(), g)
+		addSubgraph(resp, i, g, warnings)
+		subgraphs = append(subgraphs, g)
+	}
+
+	seqs := make([]uint64, len(subgraphs))
+	if s.wal != nil {
+		for i, g := range subgraphs {
+			seqs[i], err = s.append(g)
+			if err != nil {
+				// The subgraphs appended so far will be published by Replay.
+				for _, seq := range seqs[:i] {
+					s.release(seq, false)
+				}
+				return err
+			}
+		}
+	}
 
+	for i, g := range subgraphs {
+		seq := seqs[i]
 		go func() {
-			err := s.publish(context.Background(), g)
+			err := s.deliver(context.Background(), seq, g)
 			if err != nil {
 				s.log.Error(
 					"unexpected error when publishing subgraph",
 					zap.Error(err),
+					zap.Uint64("seq", seq),
 					withNumOfTriples(g),
 					withNumOfDistinctSubjects(g),
 				)
 			}
 		}()
 	}
+	return stream.SendAndClose(accepted(resp))
+}
+
+// maxInFlightAcks bounds how many subgraphs from a single
+// IngestStream are ingested concurrently.
+const maxInFlightAcks = 32
+
+var errMissingSubgraph = errors.New("missing subgraph")
+
+// IngestStream
+func (s *SubgraphIngester) IngestStream(stream pb.SubgraphIngest_IngestStreamServer) error {
+	ctx := stream.Context()
+
+	// stream.Send is not safe to call concurrently
+	ackCh := make(chan *pb.IngestAck)
+	sendErrCh := make(chan error, 1)
+	go func() {
+		defer close(sendErrCh)
+
+		var sendErr error
+		for ack := range ackCh {
+			if sendErr != nil {
+				continue
+			}
+			sendErr = stream.Send(ack)
+		}
+		sendErrCh <- sendErr
+	}()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxInFlightAcks)
+	recvErr := func() error {
+		for {
+			req, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				ackCh <- s.ack(ctx, req)
+			}()
+		}
+	}()
+
+	wg.Wait()
+	close(ackCh)
+	sendErr := <-sendErrCh
+	if recvErr != nil {
+		return recvErr
+	}
+	return sendErr
+}
+
+func (s *SubgraphIngester) ack(ctx context.Context, req *pb.IngestStreamRequest) *pb.IngestAck {
+	g := req.GetSubgraph()
+	if g == nil {
+		return nack(req.Sequence, errMissingSubgraph)
+	}
+
+	s.log.Info(
+		"received subgraph",
+		append(withSubgraphStats(g), zap.Uint64("sequence", req.Sequence))...,
+	)
+	resp, err := s.IngestSubgraph(ctx, g)
+	if err != nil {
+		s.log.Error(
+			"failed to ingest subgraph",
+			zap.Error(err),
+			zap.Uint64("sequence", req.Sequence),
+			withNumOfTriples(g),
+			withNumOfDistinctSubjects(g),
+		)
+		return nack(req.Sequence, err)
+	}
+
+	return &pb.IngestAck{
+		Sequence: req.Sequence,
+		Status:   pb.IngestAck_ACK,
+		Receipt:  resp,
+	}
+}
+
+func nack(seq uint64, err error) *pb.IngestAck {
+	return &pb.IngestAck{
+		Sequence: seq,
+		Status:   pb.IngestAck_NACK,
+		Error:    status.Convert(err).Message(),
+	}
 }
 
 func (s *SubgraphIngester) publish(ctx context.Context, g *subgraph.Subgraph) error {
-	defer s.log.Info("published subgraph", withSubgraphStats(g)...)
 	s.log.Info("publishing subgraph", withSubgraphStats(g)...)
+	err := s.pub.Publish(ctx, g)
+	if err != nil {
+		return err
+	}
+	s.log.Info("published subgraph", withSubgraphStats(g)...)
 	return nil
 }
 
+// append records the subgraph in the write-ahead log and claims its entry
+// for the caller to deliver. The entry is claimed under the same lock it is
+// appended under, so a concurrent Replay never delivers it as well.
+func (s *SubgraphIngester) append(g *subgraph.Subgraph) (uint64, error) {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+
+	seq, err := s.wal.Append(g)
+	if err != nil {
+		return 0, err
+	}
+	s.delivering[seq] = struct{}{}
+	return seq, nil
+}
+
+// claim claims the entry for delivery, unless it has already been
+// claimed or delivered.
+func (s *SubgraphIngester) claim(seq uint64) bool {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+
+	if _, ok := s.delivering[seq]; ok {
+		return false
+	}
+	if _, ok := s.delivered[seq]; ok {
+		return false
+	}
+	s.delivering[seq] = struct{}{}
+	return true
+}
+
+// release releases a claimed entry. A delivered entry is remembered
+// until every running Replay has finished so none of them claim it again.
+func (s *SubgraphIngester) release(seq uint64, delivered bool) {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+
+	delete(s.delivering, seq)
+	if delivered && s.replaying > 0 {
+		s.delivered[seq] = struct{}{}
+	}
+}
+
+func (s *SubgraphIngester) beginReplay() {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+
+	s.replaying += 1
+}
+
+func (s *SubgraphIngester) endReplay() {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+
+	s.replaying -= 1
+	if s.replaying == 0 {
+		clear(s.delivered)
+	}
+}
+
+// deliver publishes the subgraph and, if there is a write-ahead log,
+// acknowledges its entry in the log. The entry must have been claimed,
+// by append or claim, and is released once it has been delivered.
+func (s *SubgraphIngester) deliver(ctx context.Context, seq uint64, g *subgraph.Subgraph) error {
+	if s.wal == nil {
+		return s.publish(ctx, g)
+	}
+	err := s.publish(ctx, g)
+	if err != nil {
+		s.release(seq, false)
+		return err
+	}
+	err = s.wal.Ack(seq)
+	s.release(seq, err == nil)
+	return err
+}
+
 func withSubgraphStats(g *subgraph.Subgraph) []zapcore.Field {
 	return []zapcore.Field{
 		withNumOfTriples(g),
@@ -81,24 +494,25 @@ func withNumOfDistinctSubjects(g *subgraph.Subgraph) zapcore.Field {
 	return zap.Int("num_of_distinct_subjects", countDistinctSubjects(g))
 }
 
+type subjectKey struct {
+	typ  string
+	tuid string
+}
+
 func countDistinctSubjects(g *subgraph.Subgraph) int {
-	m := make(map[string]struct{}, 2*len(g.Triples))
+	m := make(map[subjectKey]struct{}, 2*len(g.Triples))
 	for _, triple := range g.Triples {
-		subj := triple.Subject
-		sid := subj.Type + subj.Tuid
-		if _, exists := m[sid]; !exists {
-			m[sid] = struct{}{}
+		subj := triple.GetSubject()
+		if subj != nil {
+			m[subjectKey{typ: subj.Type, tuid: subj.Tuid}] = struct{}{}
 		}
 
-		object := triple.GetObject()
-		val, ok := object.Value.(*subgraph.Object_Subject)
-		if !ok {
+		val, ok := triple.GetObject().GetValue().(*subgraph.Object_Subject)
+		if !ok || val.Subject == nil {
 			continue
 		}
 		subj = val.Subject
-		if _, exists := m[sid]; !exists {
-			m[sid] = struct{}{}
-		}
+		m[subjectKey{typ: subj.Type, tuid: subj.Tuid}] = struct{}{}
 	}
 	return len(m)
 }
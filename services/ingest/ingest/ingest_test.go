@@ -1,13 +1,33 @@
 package ingest
 
 import (
+	"context"
+	"errors"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/services/ingest/publisher"
+	"github.com/z5labs/megamind/services/ingest/wal"
 	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/merge"
+	"github.com/z5labs/megamind/subgraph/schema"
 )
 
+type publisherFunc func(context.Context, *subgraph.Subgraph) error
+
+func (f publisherFunc) Publish(ctx context.Context, g *subgraph.Subgraph) error {
+	return f(ctx, g)
+}
+
 func TestCountDistinctSubjects(t *testing.T) {
 	t.Run("should return zero for nil triples list", func(subT *testing.T) {
 		g := &subgraph.Subgraph{}
@@ -17,4 +37,579 @@ func TestCountDistinctSubjects(t *testing.T) {
 			return
 		}
 	})
+
+	t.Run("should count subjects referenced by objects", func(subT *testing.T) {
+		g := newTestSubgraph()
+		g.Triples = append(g.Triples, &subgraph.Triple{
+			Subject: &subgraph.Subject{
+				Type: "Person",
+				Tuid: "1",
+			},
+			Predicate: &subgraph.Predicate{
+				Name: "knows",
+			},
+			Object: &subgraph.Object{
+				Value: &subgraph.Object_Subject{
+					Subject: &subgraph.Subject{
+						Type: "Person",
+						Tuid: "2",
+					},
+				},
+			},
+		})
+
+		numOfSubjects := countDistinctSubjects(g)
+		if !assert.Equal(subT, 2, numOfSubjects) {
+			return
+		}
+	})
+
+	t.Run("should not confuse subjects whose type and tuid concatenate the same", func(subT *testing.T) {
+		g := &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{
+				{Subject: &subgraph.Subject{Type: "ab", Tuid: "c"}},
+				{Subject: &subgraph.Subject{Type: "a", Tuid: "bc"}},
+			},
+		}
+
+		numOfSubjects := countDistinctSubjects(g)
+		if !assert.Equal(subT, 2, numOfSubjects) {
+			return
+		}
+	})
+}
+
+func newTestSubgraph() *subgraph.Subgraph {
+	return &subgraph.Subgraph{
+		Triples: []*subgraph.Triple{
+			{
+				Subject: &subgraph.Subject{
+					Type: "Person",
+					Tuid: "1",
+				},
+				Predicate: &subgraph.Predicate{
+					Name: "name",
+				},
+				Object: &subgraph.Object{
+					Value: &subgraph.Object_String_{
+						String_: "Bob",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSubgraphIngester_IngestSubgraph(t *testing.T) {
+	t.Run("should publish the subgraph", func(subT *testing.T) {
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p)
+
+		g := newTestSubgraph()
+		resp, err := s.IngestSubgraph(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.NotEmpty(subT, resp.IngestId) {
+			return
+		}
+		if !assert.NotNil(subT, resp.AcceptedAt) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), resp.NumOfTriples) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), resp.NumOfDistinctSubjects) {
+			return
+		}
+		if !assert.Empty(subT, resp.Warnings) {
+			return
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(g, gs[0])) {
+			return
+		}
+	})
+
+	t.Run("should stamp the provenance with when and by whom it was received", func(subT *testing.T) {
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p)
+
+		g := newTestSubgraph()
+		g.Provenance = &subgraph.Provenance{
+			ProducerId: "crawler",
+			Caller:     "spoofed",
+		}
+		ctx := ContextWithCaller(context.Background(), "alice")
+		_, err := s.IngestSubgraph(ctx, g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		prov := gs[0].Provenance
+		if !assert.Equal(subT, "crawler", prov.GetProducerId()) {
+			return
+		}
+		if !assert.Equal(subT, "alice", prov.GetCaller()) {
+			return
+		}
+		if !assert.NotNil(subT, prov.GetReceivedAt()) {
+			return
+		}
+	})
+
+	t.Run("should timestamp every triple in the order they were sent", func(subT *testing.T) {
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p, WithClock(merge.NewClock("ingest-0")))
+
+		producerTs := &subgraph.HybridTimestamp{
+			WallTime: time.Now().Add(30 * time.Second).UnixNano(),
+			NodeId:   "producer",
+		}
+		g := newTestSubgraph()
+		g.Triples[0].Hlc = producerTs
+		retract := proto.Clone(g.Triples[0]).(*subgraph.Triple)
+		retract.Operation = subgraph.Operation_RETRACT
+		retract.Hlc = nil
+		reassert := proto.Clone(retract).(*subgraph.Triple)
+		reassert.Operation = subgraph.Operation_ASSERT
+		g.Triples = append(g.Triples, retract, reassert)
+
+		_, err := s.IngestSubgraph(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		triples := gs[0].Triples
+		if !assert.True(subT, proto.Equal(producerTs, triples[0].Hlc)) {
+			return
+		}
+		if !assert.Equal(subT, "ingest-0", triples[1].GetHlc().GetNodeId()) {
+			return
+		}
+		if !assert.Equal(subT, 1, merge.Compare(triples[1].Hlc, triples[0].Hlc)) {
+			return
+		}
+		if !assert.Equal(subT, 1, merge.Compare(triples[2].Hlc, triples[1].Hlc)) {
+			return
+		}
+	})
+
+	t.Run("should reject triples timestamped too far ahead of the clock", func(subT *testing.T) {
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p, WithClock(merge.NewClock("ingest-0", merge.WithMaxDrift(time.Minute))))
+
+		g := newTestSubgraph()
+		g.Triples[0].Hlc = &subgraph.HybridTimestamp{
+			WallTime: time.Now().Add(time.Hour).UnixNano(),
+			NodeId:   "producer",
+		}
+
+		_, err := s.IngestSubgraph(context.Background(), g)
+		if !assert.Equal(subT, codes.InvalidArgument, status.Code(err)) {
+			return
+		}
+		if !assert.Empty(subT, p.Subgraphs()) {
+			return
+		}
+	})
+
+	t.Run("should warn about duplicate triples", func(subT *testing.T) {
+		s := NewSubgraphIngester(zap.L(), publisher.NewMemory())
+
+		g := newTestSubgraph()
+		g.Triples = append(g.Triples, proto.Clone(g.Triples[0]).(*subgraph.Triple))
+		resp, err := s.IngestSubgraph(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, resp.Warnings, 1) {
+			return
+		}
+		if !assert.Equal(subT, int32(1), resp.Warnings[0].TripleIndex) {
+			return
+		}
+	})
+
+	t.Run("should publish retractions in the order they were sent", func(subT *testing.T) {
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p)
+
+		g := newTestSubgraph()
+		retract := proto.Clone(g.Triples[0]).(*subgraph.Triple)
+		retract.Operation = subgraph.Operation_RETRACT
+		reassert := proto.Clone(g.Triples[0]).(*subgraph.Triple)
+		g.Triples = append(g.Triples, retract, reassert)
+
+		resp, err := s.IngestSubgraph(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, resp.Warnings) {
+			return
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		ops := make([]subgraph.Operation, len(gs[0].Triples))
+		for i, t := range gs[0].Triples {
+			ops[i] = t.Operation
+		}
+		expected := []subgraph.Operation{
+			subgraph.Operation_ASSERT,
+			subgraph.Operation_RETRACT,
+			subgraph.Operation_ASSERT,
+		}
+		if !assert.Equal(subT, expected, ops) {
+			return
+		}
+	})
+
+	t.Run("should reject subgraphs which do not conform to the schema in strict mode", func(subT *testing.T) {
+		sc, err := schema.Parse([]byte(`{"types":{"Person":{"predicates":{"age":{"kind":"int64"}}}}}`))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p, WithSchema(sc, SchemaStrict))
+
+		_, err = s.IngestSubgraph(context.Background(), newTestSubgraph())
+		if !assert.Equal(subT, codes.InvalidArgument, status.Code(err)) {
+			return
+		}
+		if !assert.Empty(subT, p.Subgraphs()) {
+			return
+		}
+	})
+
+	t.Run("should accept subgraphs which do not conform to the schema in warn mode", func(subT *testing.T) {
+		sc, err := schema.Parse([]byte(`{"types":{"Person":{"predicates":{"age":{"kind":"int64"}}}}}`))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p, WithSchema(sc, SchemaWarn))
+
+		resp, err := s.IngestSubgraph(context.Background(), newTestSubgraph())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, resp.Warnings, 1) {
+			return
+		}
+		if !assert.Equal(subT, `triples[0].predicate.name: predicate "name" is not declared on type "Person"`, resp.Warnings[0].Message) {
+			return
+		}
+		if !assert.Len(subT, p.Subgraphs(), 1) {
+			return
+		}
+	})
+
+	t.Run("should return the error from the publisher", func(subT *testing.T) {
+		pubErr := errors.New("failed to publish")
+		s := NewSubgraphIngester(zap.L(), publisherFunc(func(context.Context, *subgraph.Subgraph) error {
+			return pubErr
+		}))
+
+		_, err := s.IngestSubgraph(context.Background(), &subgraph.Subgraph{})
+		if !assert.Equal(subT, pubErr, err) {
+			return
+		}
+	})
+}
+
+// ingestStream is the server side of an Ingest stream
+// which sends the given subgraphs.
+type ingestStream struct {
+	grpc.ServerStream
+
+	ctx       context.Context
+	subgraphs []*subgraph.Subgraph
+	resp      *pb.IngestResponse
+}
+
+func (s *ingestStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *ingestStream) Recv() (*subgraph.Subgraph, error) {
+	if len(s.subgraphs) == 0 {
+		return nil, io.EOF
+	}
+	g := s.subgraphs[0]
+	s.subgraphs = s.subgraphs[1:]
+	return g, nil
+}
+
+func (s *ingestStream) SendAndClose(resp *pb.IngestResponse) error {
+	s.resp = resp
+	return nil
+}
+
+func TestSubgraphIngester_Ingest(t *testing.T) {
+	t.Run("should publish every subgraph once the stream has ended", func(subT *testing.T) {
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p)
+
+		stream := &ingestStream{
+			ctx:       context.Background(),
+			subgraphs: []*subgraph.Subgraph{newTestSubgraph(), newTestSubgraph()},
+		}
+		err := s.Ingest(stream)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, int64(2), stream.resp.GetNumOfTriples()) {
+			return
+		}
+
+		published := assert.Eventually(subT, func() bool {
+			return len(p.Subgraphs()) == 2
+		}, time.Second, 10*time.Millisecond)
+		if !published {
+			return
+		}
+	})
+
+	t.Run("should not ingest any subgraph of the stream if one is invalid", func(subT *testing.T) {
+		w, err := wal.Open(subT.TempDir())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer w.Close()
+
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p, WithWriteAheadLog(w))
+
+		stream := &ingestStream{
+			ctx: context.Background(),
+			subgraphs: []*subgraph.Subgraph{
+				newTestSubgraph(),
+				{Triples: []*subgraph.Triple{{}}},
+				newTestSubgraph(),
+			},
+		}
+		err = s.Ingest(stream)
+		if !assert.Equal(subT, codes.InvalidArgument, status.Code(err)) {
+			return
+		}
+		if !assert.Nil(subT, stream.resp) {
+			return
+		}
+
+		err = s.Replay(context.Background())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, p.Subgraphs()) {
+			return
+		}
+	})
+}
+
+// blockingLog blocks every Append, after the entry has been
+// appended, until release is closed.
+type blockingLog struct {
+	*wal.Log
+
+	appended chan struct{}
+	release  chan struct{}
+}
+
+func (l *blockingLog) Append(g *subgraph.Subgraph) (uint64, error) {
+	seq, err := l.Log.Append(g)
+	close(l.appended)
+	<-l.release
+	return seq, err
+}
+
+func TestSubgraphIngester_Replay(t *testing.T) {
+	t.Run("should not publish an entry which is still being appended", func(subT *testing.T) {
+		w, err := wal.Open(subT.TempDir())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer w.Close()
+
+		l := &blockingLog{Log: w, appended: make(chan struct{}), release: make(chan struct{})}
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p, WithWriteAheadLog(l))
+
+		ingested := make(chan error, 1)
+		go func() {
+			_, err := s.IngestSubgraph(context.Background(), newTestSubgraph())
+			ingested <- err
+		}()
+		<-l.appended
+
+		replayed := make(chan error, 1)
+		go func() {
+			replayed <- s.Replay(context.Background())
+		}()
+
+		// give Replay a chance to reach the entry before Append returns
+		time.Sleep(50 * time.Millisecond)
+		close(l.release)
+
+		if !assert.Nil(subT, <-ingested) {
+			return
+		}
+		if !assert.Nil(subT, <-replayed) {
+			return
+		}
+		if !assert.Len(subT, p.Subgraphs(), 1) {
+			return
+		}
+	})
+
+	t.Run("should publish subgraphs which failed to publish when ingested", func(subT *testing.T) {
+		w, err := wal.Open(subT.TempDir())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer w.Close()
+
+		failing := NewSubgraphIngester(zap.L(), publisherFunc(func(context.Context, *subgraph.Subgraph) error {
+			return errors.New("failed to publish")
+		}), WithWriteAheadLog(w))
+
+		g := newTestSubgraph()
+		_, err = failing.IngestSubgraph(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p, WithWriteAheadLog(w))
+		err = s.Replay(context.Background())
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(g, gs[0])) {
+			return
+		}
+
+		// the subgraph was acknowledged so it shouldn't be replayed again
+		err = s.Replay(context.Background())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, p.Subgraphs(), 1) {
+			return
+		}
+	})
+
+	t.Run("should keep publishing subgraphs after one fails to publish again", func(subT *testing.T) {
+		w, err := wal.Open(subT.TempDir())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer w.Close()
+
+		failing := NewSubgraphIngester(zap.L(), publisherFunc(func(context.Context, *subgraph.Subgraph) error {
+			return errors.New("failed to publish")
+		}), WithWriteAheadLog(w))
+
+		for i := 0; i < 2; i++ {
+			_, err = failing.IngestSubgraph(context.Background(), newTestSubgraph())
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+
+		p := publisher.NewMemory()
+		calls := 0
+		s := NewSubgraphIngester(zap.L(), publisherFunc(func(ctx context.Context, g *subgraph.Subgraph) error {
+			calls += 1
+			if calls == 1 {
+				return errors.New("failed to publish")
+			}
+			return p.Publish(ctx, g)
+		}), WithWriteAheadLog(w))
+
+		err = s.Replay(context.Background())
+		if !assert.ErrorIs(subT, err, errUndelivered) {
+			return
+		}
+		if !assert.Len(subT, p.Subgraphs(), 1) {
+			return
+		}
+
+		err = s.Replay(context.Background())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, p.Subgraphs(), 2) {
+			return
+		}
+	})
+}
+
+func TestSubgraphIngester_Redeliver(t *testing.T) {
+	t.Run("should publish subgraphs which failed to publish while running", func(subT *testing.T) {
+		w, err := wal.Open(subT.TempDir())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer w.Close()
+
+		p := publisher.NewMemory()
+		failures := make(chan struct{}, 1)
+		failures <- struct{}{}
+		s := NewSubgraphIngester(zap.L(), publisherFunc(func(ctx context.Context, g *subgraph.Subgraph) error {
+			select {
+			case <-failures:
+				return errors.New("failed to publish")
+			default:
+			}
+			return p.Publish(ctx, g)
+		}), WithWriteAheadLog(w))
+
+		_, err = s.IngestSubgraph(context.Background(), newTestSubgraph())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, p.Subgraphs()) {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.Redeliver(ctx, 10*time.Millisecond)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		published := assert.Eventually(subT, func() bool {
+			return len(p.Subgraphs()) == 1
+		}, time.Second, 10*time.Millisecond)
+		if !published {
+			return
+		}
+	})
 }
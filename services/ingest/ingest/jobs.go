@@ -0,0 +1,320 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingest
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// maxRunningJobs bounds how many jobs are ingested concurrently.
+	maxRunningJobs = 4
+
+	// jobCheckpointInterval is how many subgraphs are ingested between
+	// recording the progress of a job in its store. A job resumed after a
+	// restart ingests the subgraphs since its last checkpoint again.
+	jobCheckpointInterval = 100
+
+	// maxJobMessages bounds how many warnings and errors are kept per job.
+	maxJobMessages = 100
+)
+
+var errJobsDisabled = status.Error(codes.Unimplemented, "ingest jobs are not enabled")
+
+// JobStore durably records ingest jobs and the subgraphs submitted to them.
+type JobStore interface {
+	// Create records a new job along with every subgraph returned by next,
+	// until it returns io.EOF. The job is only recorded if every subgraph is.
+	Create(job *pb.Job, next func() (*subgraph.Subgraph, error)) error
+
+	// Update records the progress of a job. The subgraphs of a job
+	// which is done, or has failed, may be removed.
+	Update(job *pb.Job) error
+
+	// Get returns the job with the ID. The returned error
+	// wraps fs.ErrNotExist if there is no such job.
+	Get(id string) (*pb.Job, error)
+
+	// List returns every job.
+	List() ([]*pb.Job, error)
+
+	// Subgraphs calls fn, in order, for every subgraph submitted to the job.
+	Subgraphs(id string, fn func(i int, g *subgraph.Subgraph) error) error
+}
+
+// WithJobStore enables ingest jobs, which are recorded in the given store.
+func WithJobStore(js JobStore) Option {
+	return func(s *SubgraphIngester) {
+		s.jobs = js
+	}
+}
+
+// SubmitJob
+func (s *SubgraphIngester) SubmitJob(stream pb.SubgraphIngest_SubmitJobServer) error {
+	if s.jobs == nil {
+		return errJobsDisabled
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	now := timestamppb.Now()
+	job := &pb.Job{
+		Id:        id.String(),
+		State:     pb.Job_PENDING,
+		Caller:    CallerFromContext(stream.Context()),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = s.jobs.Create(job, func() (*subgraph.Subgraph, error) {
+		g, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		job.NumOfSubgraphs += 1
+		return g, nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Info(
+		"submitted ingest job",
+		zap.String("job_id", job.Id),
+		zap.Int64("num_of_subgraphs", job.NumOfSubgraphs),
+	)
+
+	resp := proto.Clone(job).(*pb.Job)
+	s.startJob(job)
+	return stream.SendAndClose(resp)
+}
+
+// GetJob
+func (s *SubgraphIngester) GetJob(ctx context.Context, req *pb.GetJobRequest) (*pb.Job, error) {
+	if s.jobs == nil {
+		return nil, errJobsDisabled
+	}
+
+	s.jobsMu.Lock()
+	job, ok := s.running[req.Id]
+	s.jobsMu.Unlock()
+	if ok {
+		return job, nil
+	}
+
+	job, err := s.jobs.Get(req.Id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, status.Errorf(codes.NotFound, "no job with id: %s", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ResumeJobs runs every job which was not finished, e.g. because
+// the service was restarted while it was running.
+func (s *SubgraphIngester) ResumeJobs() error {
+	if s.jobs == nil {
+		return nil
+	}
+
+	jobs, err := s.jobs.List()
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, job := range jobs {
+		if job.State != pb.Job_PENDING && job.State != pb.Job_RUNNING {
+			continue
+		}
+		s.startJob(job)
+		n += 1
+	}
+	s.log.Info("resumed ingest jobs", zap.Int("num_of_jobs", n))
+	return nil
+}
+
+// StopJobs interrupts every running job and waits for them to record
+// their progress, so that they can be resumed by ResumeJobs after a
+// restart. Jobs submitted after StopJobs are recorded but not run.
+func (s *SubgraphIngester) StopJobs() {
+	s.jobsMu.Lock()
+	s.stopJobs()
+	s.jobsMu.Unlock()
+
+	s.jobsWG.Wait()
+}
+
+// startJob runs the job in the background until it
+// finishes or the jobs are stopped by StopJobs.
+func (s *SubgraphIngester) startJob(job *pb.Job) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	if s.jobsCtx.Err() != nil {
+		s.log.Warn("not running ingest job since jobs are stopped", zap.String("job_id", job.Id))
+		return
+	}
+	s.jobsWG.Add(1)
+	go func() {
+		defer s.jobsWG.Done()
+		s.runJob(s.jobsCtx, job)
+	}()
+}
+
+// runJob ingests every subgraph of the job which has not been ingested yet.
+func (s *SubgraphIngester) runJob(ctx context.Context, job *pb.Job) {
+	log := s.log.With(zap.String("job_id", job.Id))
+
+	select {
+	case <-ctx.Done():
+		// The job is left pending so that it is resumed after a restart.
+		log.Warn("interrupted ingest job before it started", zap.Error(ctx.Err()))
+		return
+	case s.jobSlots <- struct{}{}:
+	}
+	defer func() { <-s.jobSlots }()
+
+	ctx = ContextWithCaller(ctx, job.Caller)
+
+	done := job.NumOfAccepted + job.NumOfRejected
+	job.State = pb.Job_RUNNING
+	err := s.checkpoint(job, true)
+	if err == nil {
+		err = s.jobs.Subgraphs(job.Id, func(i int, g *subgraph.Subgraph) error {
+			if int64(i) < done {
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			s.ingestJobSubgraph(ctx, log, job, i, g)
+			return s.checkpoint(job, (i+1)%jobCheckpointInterval == 0)
+		})
+	}
+
+	if ctx.Err() != nil {
+		// The job is left running so that it is resumed after a restart.
+		log.Warn("interrupted ingest job", zap.Error(ctx.Err()))
+		err = s.checkpoint(job, true)
+		if err != nil {
+			log.Error("unexpected error when recording ingest job", zap.Error(err))
+		}
+		return
+	}
+
+	job.State = pb.Job_DONE
+	if err != nil {
+		log.Error("ingest job failed", zap.Error(err))
+		job.State = pb.Job_FAILED
+		addJobError(job, -1, err.Error())
+	}
+	err = s.checkpoint(job, true)
+	if err != nil {
+		log.Error("unexpected error when recording ingest job", zap.Error(err))
+	}
+
+	s.jobsMu.Lock()
+	delete(s.running, job.Id)
+	s.jobsMu.Unlock()
+
+	log.Info(
+		"finished ingest job",
+		zap.Stringer("state", job.State),
+		zap.Int64("num_of_accepted", job.NumOfAccepted),
+		zap.Int64("num_of_rejected", job.NumOfRejected),
+		zap.Int64("num_of_published", job.NumOfPublished),
+	)
+}
+
+// ingestJobSubgraph ingests the i-th subgraph of the job the same way as
+// IngestSubgraph and records the outcome in the job.
+func (s *SubgraphIngester) ingestJobSubgraph(ctx context.Context, log *zap.Logger, job *pb.Job, i int, g *subgraph.Subgraph) {
+	warnings, err := s.validate(g)
+	if err != nil {
+		job.NumOfRejected += 1
+		addJobError(job, i, status.Convert(err).Message())
+		return
+	}
+	s.stamp(ctx, g)
+	job.NumOfAccepted += 1
+	for _, w := range append(warnings, tripleWarnings(g)...) {
+		if len(job.Warnings) >= maxJobMessages {
+			break
+		}
+		w.SubgraphIndex = int32(i)
+		job.Warnings = append(job.Warnings, w)
+	}
+
+	var seq uint64
+	if s.wal != nil {
+		seq, err = s.append(g)
+		if err != nil {
+			addJobError(job, i, err.Error())
+			return
+		}
+	}
+
+	// With a write-ahead log, a subgraph which fails to publish here
+	// is retried by Replay but is not counted as published.
+	err = s.deliver(ctx, seq, g)
+	if err != nil {
+		log.Error("unexpected error when publishing subgraph", zap.Error(err), zap.Int("subgraph_index", i))
+		addJobError(job, i, err.Error())
+		return
+	}
+	job.NumOfPublished += 1
+}
+
+// checkpoint makes the progress of a running job visible to GetJob
+// and, if persist is set, records it in the job store.
+func (s *SubgraphIngester) checkpoint(job *pb.Job, persist bool) error {
+	job.UpdatedAt = timestamppb.Now()
+
+	s.jobsMu.Lock()
+	s.running[job.Id] = proto.Clone(job).(*pb.Job)
+	s.jobsMu.Unlock()
+
+	if !persist {
+		return nil
+	}
+	return s.jobs.Update(job)
+}
+
+func addJobError(job *pb.Job, i int, msg string) {
+	if len(job.Errors) >= maxJobMessages {
+		return
+	}
+	job.Errors = append(job.Errors, &pb.JobError{
+		SubgraphIndex: int32(i),
+		Message:       msg,
+	})
+}
@@ -0,0 +1,307 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingest
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/services/ingest/jobs"
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/services/ingest/publisher"
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// submitJobStream is the server side of a SubmitJob stream
+// which sends the given subgraphs.
+type submitJobStream struct {
+	grpc.ServerStream
+
+	ctx       context.Context
+	subgraphs []*subgraph.Subgraph
+	job       *pb.Job
+}
+
+func (s *submitJobStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *submitJobStream) Recv() (*subgraph.Subgraph, error) {
+	if len(s.subgraphs) == 0 {
+		return nil, io.EOF
+	}
+	g := s.subgraphs[0]
+	s.subgraphs = s.subgraphs[1:]
+	return g, nil
+}
+
+func (s *submitJobStream) SendAndClose(job *pb.Job) error {
+	s.job = job
+	return nil
+}
+
+// waitForJob polls the job until it has finished.
+func waitForJob(ctx context.Context, s *SubgraphIngester, id string) (*pb.Job, error) {
+	for {
+		job, err := s.GetJob(ctx, &pb.GetJobRequest{Id: id})
+		if err != nil {
+			return nil, err
+		}
+		if job.State == pb.Job_DONE || job.State == pb.Job_FAILED {
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestSubgraphIngester_SubmitJob(t *testing.T) {
+	t.Run("should ingest the subgraphs in the background", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p, WithJobStore(jobs.NewMemory()))
+
+		invalid := newTestSubgraph()
+		invalid.Triples[0].Subject.Type = ""
+		stream := &submitJobStream{
+			ctx:       ContextWithCaller(ctx, "producer"),
+			subgraphs: []*subgraph.Subgraph{newTestSubgraph(), invalid, newTestSubgraph()},
+		}
+		err := s.SubmitJob(stream)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.NotEmpty(subT, stream.job.Id) {
+			return
+		}
+		if !assert.Equal(subT, pb.Job_PENDING, stream.job.State) {
+			return
+		}
+		if !assert.Equal(subT, int64(3), stream.job.NumOfSubgraphs) {
+			return
+		}
+
+		job, err := waitForJob(ctx, s, stream.job.Id)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, pb.Job_DONE, job.State) {
+			return
+		}
+		if !assert.Equal(subT, "producer", job.Caller) {
+			return
+		}
+		if !assert.Equal(subT, int64(2), job.NumOfAccepted) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), job.NumOfRejected) {
+			return
+		}
+		if !assert.Equal(subT, int64(2), job.NumOfPublished) {
+			return
+		}
+		if !assert.Len(subT, job.Errors, 1) {
+			return
+		}
+		if !assert.Equal(subT, int32(1), job.Errors[0].SubgraphIndex) {
+			return
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 2) {
+			return
+		}
+		if !assert.Equal(subT, "producer", gs[0].Provenance.Caller) {
+			return
+		}
+	})
+
+	t.Run("should count subgraphs which failed to publish as accepted only", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		p := publisherFunc(func(context.Context, *subgraph.Subgraph) error {
+			return io.ErrClosedPipe
+		})
+		s := NewSubgraphIngester(zap.L(), p, WithJobStore(jobs.NewMemory()))
+
+		stream := &submitJobStream{
+			ctx:       ctx,
+			subgraphs: []*subgraph.Subgraph{newTestSubgraph()},
+		}
+		err := s.SubmitJob(stream)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		job, err := waitForJob(ctx, s, stream.job.Id)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, int64(1), job.NumOfAccepted) {
+			return
+		}
+		if !assert.Equal(subT, int64(0), job.NumOfPublished) {
+			return
+		}
+		if !assert.Len(subT, job.Errors, 1) {
+			return
+		}
+	})
+
+	t.Run("should return unimplemented if jobs are not enabled", func(subT *testing.T) {
+		s := NewSubgraphIngester(zap.L(), publisher.NewMemory())
+
+		err := s.SubmitJob(&submitJobStream{ctx: context.Background()})
+		if !assert.Equal(subT, codes.Unimplemented, status.Code(err)) {
+			return
+		}
+	})
+}
+
+func TestSubgraphIngester_GetJob(t *testing.T) {
+	t.Run("should return not found for an unknown job", func(subT *testing.T) {
+		s := NewSubgraphIngester(zap.L(), publisher.NewMemory(), WithJobStore(jobs.NewMemory()))
+
+		_, err := s.GetJob(context.Background(), &pb.GetJobRequest{Id: "unknown"})
+		if !assert.Equal(subT, codes.NotFound, status.Code(err)) {
+			return
+		}
+	})
+}
+
+func TestSubgraphIngester_ResumeJobs(t *testing.T) {
+	t.Run("should only ingest the subgraphs after the last checkpoint", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		store := jobs.NewMemory()
+		job := &pb.Job{
+			Id:             "interrupted",
+			State:          pb.Job_RUNNING,
+			CreatedAt:      timestamppb.Now(),
+			NumOfSubgraphs: 2,
+			NumOfAccepted:  1,
+			NumOfPublished: 1,
+		}
+		gs := []*subgraph.Subgraph{newTestSubgraph(), newTestSubgraph()}
+		gs[1].Triples[0].Subject.Tuid = "2"
+		err := store.Create(job, func() (*subgraph.Subgraph, error) {
+			if len(gs) == 0 {
+				return nil, io.EOF
+			}
+			g := gs[0]
+			gs = gs[1:]
+			return g, nil
+		})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		done := &pb.Job{Id: "done", State: pb.Job_DONE, CreatedAt: timestamppb.Now()}
+		err = store.Create(done, func() (*subgraph.Subgraph, error) { return nil, io.EOF })
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		p := publisher.NewMemory()
+		s := NewSubgraphIngester(zap.L(), p, WithJobStore(store))
+		err = s.ResumeJobs()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		resumed, err := waitForJob(ctx, s, "interrupted")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, int64(2), resumed.NumOfAccepted) {
+			return
+		}
+		if !assert.Equal(subT, int64(2), resumed.NumOfPublished) {
+			return
+		}
+
+		published := p.Subgraphs()
+		if !assert.Len(subT, published, 1) {
+			return
+		}
+		if !assert.Equal(subT, "2", published[0].Triples[0].Subject.Tuid) {
+			return
+		}
+	})
+}
+
+func TestSubgraphIngester_StopJobs(t *testing.T) {
+	t.Run("should leave interrupted jobs to be resumed", func(subT *testing.T) {
+		store := jobs.NewMemory()
+		job := &pb.Job{
+			Id:             "running",
+			State:          pb.Job_PENDING,
+			CreatedAt:      timestamppb.Now(),
+			NumOfSubgraphs: 1,
+		}
+		gs := []*subgraph.Subgraph{newTestSubgraph()}
+		err := store.Create(job, func() (*subgraph.Subgraph, error) {
+			if len(gs) == 0 {
+				return nil, io.EOF
+			}
+			g := gs[0]
+			gs = gs[1:]
+			return g, nil
+		})
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		publishing := make(chan struct{})
+		s := NewSubgraphIngester(zap.L(), publisherFunc(func(ctx context.Context, g *subgraph.Subgraph) error {
+			close(publishing)
+			<-ctx.Done()
+			return ctx.Err()
+		}), WithJobStore(store))
+		err = s.ResumeJobs()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		<-publishing
+		s.StopJobs()
+
+		stopped, err := store.Get("running")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, pb.Job_RUNNING, stopped.State) {
+			return
+		}
+	})
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingest
+
+import (
+	"context"
+	"os"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type callerKey struct{}
+
+// ContextWithCaller returns a copy of ctx which carries the authenticated caller.
+func ContextWithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the authenticated caller, or an empty string if there is none.
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// stamp records when, and by whom, the subgraph was received.
+// Any values sent by the producer for these fields are overwritten.
+//
+// Every triple without a hybrid logical clock timestamp is assigned one,
+// in the order they appear in the subgraph. Timestamps set by the producer
+// are kept and advance the clock, unless they are too far ahead of it,
+// which validate has already rejected them for.
+func (s *SubgraphIngester) stamp(ctx context.Context, g *subgraph.Subgraph) {
+	if g.Provenance == nil {
+		g.Provenance = new(subgraph.Provenance)
+	}
+	g.Provenance.ReceivedAt = timestamppb.Now()
+	g.Provenance.Caller = CallerFromContext(ctx)
+
+	for _, t := range g.Triples {
+		if t.Hlc != nil {
+			err := s.clock.Update(t.Hlc)
+			if err != nil {
+				s.log.Warn("did not advance clock past triple timestamp", zap.Error(err))
+			}
+			continue
+		}
+		t.Hlc = s.clock.Now()
+	}
+}
+
+// DefaultNodeID returns the node ID of the clock used when none
+// is set with WithClock, which is the hostname if there is one.
+func DefaultNodeID() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "ingest"
+	}
+	return name
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingest
+
+import (
+	"fmt"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newIngestResponse returns a response with a newly assigned ingest ID.
+func newIngestResponse() (*pb.IngestResponse, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.IngestResponse{IngestId: id.String()}, nil
+}
+
+// addSubgraph adds the stats and warnings for the i-th subgraph to the response.
+func addSubgraph(resp *pb.IngestResponse, i int, g *subgraph.Subgraph, warnings []*pb.Warning) {
+	resp.NumOfTriples += int64(len(g.Triples))
+	resp.NumOfDistinctSubjects += int64(countDistinctSubjects(g))
+	for _, w := range append(warnings, tripleWarnings(g)...) {
+		w.SubgraphIndex = int32(i)
+		resp.Warnings = append(resp.Warnings, w)
+	}
+}
+
+func accepted(resp *pb.IngestResponse) *pb.IngestResponse {
+	resp.AcceptedAt = timestamppb.Now()
+	return resp
+}
+
+// tripleWarnings returns a warning for every triple which is accepted
+// as is, but is most likely a mistake by the producer.
+func tripleWarnings(g *subgraph.Subgraph) []*pb.Warning {
+	var warnings []*pb.Warning
+	seen := make(map[string]int, len(g.Triples))
+	for i, triple := range g.Triples {
+		if triple.GetOperation() != subgraph.Operation_ASSERT {
+			// Asserting a triple again after it has been removed
+			// is not a duplicate.
+			for key, j := range seen {
+				if removes(triple, g.Triples[j]) {
+					delete(seen, key)
+				}
+			}
+		}
+
+		// Triples are timestamped as they are received so a duplicate
+		// will only ever differ by its timestamp.
+		key := proto.Clone(triple).(*subgraph.Triple)
+		key.Hlc = nil
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(key)
+		if err != nil {
+			continue
+		}
+		if j, ok := seen[string(b)]; ok {
+			warnings = append(warnings, &pb.Warning{
+				TripleIndex: int32(i),
+				Message:     fmt.Sprintf("duplicate of triple %d", j),
+			})
+			continue
+		}
+		seen[string(b)] = i
+
+		subj := triple.GetSubject()
+		obj, ok := triple.GetObject().GetValue().(*subgraph.Object_Subject)
+		if ok && proto.Equal(subj, obj.Subject) {
+			warnings = append(warnings, &pb.Warning{
+				TripleIndex: int32(i),
+				Message:     "subject references itself",
+			})
+		}
+	}
+	return warnings
+}
+
+// removes reports whether the retraction, or deletion, r removes the asserted triple t.
+func removes(r, t *subgraph.Triple) bool {
+	if !proto.Equal(r.GetSubject(), t.GetSubject()) {
+		return false
+	}
+	if r.GetOperation() == subgraph.Operation_DELETE_SUBJECT {
+		return true
+	}
+	if r.GetPredicate().GetName() != t.GetPredicate().GetName() {
+		return false
+	}
+	return r.GetObject() == nil || proto.Equal(r.GetObject(), t.GetObject())
+}
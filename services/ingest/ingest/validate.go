@@ -0,0 +1,131 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingest
+
+import (
+	"errors"
+	"fmt"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/schema"
+	"github.com/z5labs/megamind/subgraph/validate"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SchemaMode controls how subgraphs which do not conform to the schema are handled.
+type SchemaMode int
+
+const (
+	// SchemaOff does not check subgraphs against the schema.
+	SchemaOff SchemaMode = iota
+
+	// SchemaWarn accepts subgraphs which do not conform to the schema
+	// and returns every violation as a warning.
+	SchemaWarn
+
+	// SchemaStrict rejects subgraphs which do not conform to the schema.
+	SchemaStrict
+)
+
+// WithSchema checks every ingested subgraph against the schema.
+func WithSchema(sc *schema.Schema, mode SchemaMode) Option {
+	return func(s *SubgraphIngester) {
+		s.schema = sc
+		s.schemaMode = mode
+	}
+}
+
+// validate returns an InvalidArgument status if the subgraph is invalid, has
+// timestamps too far ahead of the clock or, in strict mode, does not conform
+// to the schema. In warn mode, schema violations are returned as warnings instead.
+func (s *SubgraphIngester) validate(g *subgraph.Subgraph) ([]*pb.Warning, error) {
+	err := validate.Subgraph(g)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	if vs := s.checkClock(g); len(vs) > 0 {
+		return nil, invalidArgument(&validate.Error{Violations: vs})
+	}
+	if s.schema == nil || s.schemaMode == SchemaOff {
+		return nil, nil
+	}
+
+	vs := s.schema.Check(g)
+	if len(vs) == 0 {
+		return nil, nil
+	}
+	if s.schemaMode == SchemaStrict {
+		return nil, invalidArgument(&validate.Error{Violations: vs})
+	}
+
+	warnings := make([]*pb.Warning, len(vs))
+	for i, v := range vs {
+		warnings[i] = &pb.Warning{
+			TripleIndex: int32(v.TripleIndex),
+			Message:     v.Error(),
+		}
+	}
+	return warnings, nil
+}
+
+// checkClock returns a violation for every triple whose timestamp
+// would be rejected by the clock when the subgraph is stamped.
+func (s *SubgraphIngester) checkClock(g *subgraph.Subgraph) []validate.Violation {
+	var vs []validate.Violation
+	for i, t := range g.Triples {
+		if t.Hlc == nil {
+			continue
+		}
+		err := s.clock.Check(t.Hlc)
+		if err != nil {
+			vs = append(vs, validate.Violation{
+				TripleIndex: i,
+				Field:       fmt.Sprintf("triples[%d].hlc.wall_time", i),
+				Description: err.Error(),
+			})
+		}
+	}
+	return vs
+}
+
+// invalidArgument converts a *validate.Error into an InvalidArgument
+// status with a BadRequest detail listing every violation.
+func invalidArgument(err error) error {
+	var verr *validate.Error
+	if !errors.As(err, &verr) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	br := new(errdetails.BadRequest)
+	for _, v := range verr.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	st := status.New(codes.InvalidArgument, verr.Error())
+	stWithDetails, err := st.WithDetails(br)
+	if err != nil {
+		return st.Err()
+	}
+	return stWithDetails.Err()
+}
@@ -0,0 +1,288 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jobs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	jobExt       = ".job"
+	subgraphsExt = ".subgraphs"
+	tmpExt       = ".tmp"
+)
+
+// File is a JobStore which records every job, and the subgraphs submitted
+// to it, as files in a directory so that jobs survive a restart.
+// The subgraphs of a job are removed once it has finished.
+type File struct {
+	dir string
+}
+
+// Open opens the store in dir, creating it if it does not exist yet.
+// Files left behind by a crash during Create are removed.
+func Open(dir string) (*File, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, de := range des {
+		name := de.Name()
+		if de.IsDir() {
+			continue
+		}
+		id, isSubgraphs := strings.CutSuffix(name, subgraphsExt)
+		_, err := os.Stat(filepath.Join(dir, id+jobExt))
+		orphaned := isSubgraphs && os.IsNotExist(err)
+		if !orphaned && !strings.HasSuffix(name, tmpExt) {
+			continue
+		}
+		err = os.Remove(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return &File{dir: dir}, nil
+}
+
+// Create
+func (f *File) Create(job *pb.Job, next func() (*subgraph.Subgraph, error)) error {
+	if !validID(job.Id) {
+		return notFound(job.Id)
+	}
+
+	name := f.path(job.Id, subgraphsExt)
+	err := writeFile(name, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		var n [binary.MaxVarintLen64]byte
+		for {
+			g, err := next()
+			if err == io.EOF {
+				return bw.Flush()
+			}
+			if err != nil {
+				return err
+			}
+
+			b, err := proto.Marshal(g)
+			if err != nil {
+				return err
+			}
+			_, err = bw.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))])
+			if err != nil {
+				return err
+			}
+			_, err = bw.Write(b)
+			if err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = f.writeJob(job)
+	if err != nil {
+		os.Remove(name)
+		return err
+	}
+	return nil
+}
+
+// Update
+func (f *File) Update(job *pb.Job) error {
+	if !validID(job.Id) {
+		return notFound(job.Id)
+	}
+	_, err := os.Stat(f.path(job.Id, jobExt))
+	if os.IsNotExist(err) {
+		return notFound(job.Id)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = f.writeJob(job)
+	if err != nil || !finished(job) {
+		return err
+	}
+	err = os.Remove(f.path(job.Id, subgraphsExt))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Get
+func (f *File) Get(id string) (*pb.Job, error) {
+	if !validID(id) {
+		return nil, notFound(id)
+	}
+
+	b, err := os.ReadFile(f.path(id, jobExt))
+	if os.IsNotExist(err) {
+		return nil, notFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job := new(pb.Job)
+	err = proto.Unmarshal(b, job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// List returns every job in the order they were created.
+func (f *File) List() ([]*pb.Job, error) {
+	des, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*pb.Job
+	for _, de := range des {
+		id, ok := strings.CutSuffix(de.Name(), jobExt)
+		if de.IsDir() || !ok {
+			continue
+		}
+		job, err := f.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// Subgraphs
+func (f *File) Subgraphs(id string, fn func(i int, g *subgraph.Subgraph) error) error {
+	if !validID(id) {
+		return notFound(id)
+	}
+
+	file, err := os.Open(f.path(id, subgraphsExt))
+	if os.IsNotExist(err) {
+		// the subgraphs of a finished job have been removed
+		_, err = f.Get(id)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	for i := 0; ; i++ {
+		n, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		b := make([]byte, n)
+		_, err = io.ReadFull(br, b)
+		if err != nil {
+			return err
+		}
+
+		var g subgraph.Subgraph
+		err = proto.Unmarshal(b, &g)
+		if err != nil {
+			return err
+		}
+		err = fn(i, &g)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (f *File) path(id, ext string) string {
+	return filepath.Join(f.dir, id+ext)
+}
+
+func (f *File) writeJob(job *pb.Job) error {
+	b, err := proto.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return writeFile(f.path(job.Id, jobExt), func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// writeFile atomically replaces the named file with whatever write writes.
+func writeFile(name string, write func(io.Writer) error) error {
+	tmp := name + tmpExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(name))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// validID reports whether the ID can safely be used as a file name.
+func validID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, "/\\\x00")
+}
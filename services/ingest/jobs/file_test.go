@@ -0,0 +1,126 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jobs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFile(t *testing.T) {
+	testStore(t, func(subT *testing.T) store {
+		f, err := Open(subT.TempDir())
+		if err != nil {
+			subT.Fatal(err)
+		}
+		return f
+	})
+
+	t.Run("should keep jobs when reopened", func(subT *testing.T) {
+		dir := subT.TempDir()
+		f, err := Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		job := newTestJob("a", time.Now())
+		err = createJob(f, job, newTestSubgraph("1"), newTestSubgraph("2"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		job.State = pb.Job_RUNNING
+		job.NumOfAccepted = 1
+		err = f.Update(job)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		f, err = Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		jobs, err := f.List()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, jobs, 1) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(job, jobs[0]), "expected %v but got %v", job, jobs[0]) {
+			return
+		}
+		gs, err := collectSubgraphs(f, "a")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, gs, 2) {
+			return
+		}
+	})
+
+	t.Run("should remove files left behind by a crash", func(subT *testing.T) {
+		dir := subT.TempDir()
+		for _, name := range []string{"a.job.tmp", "b.subgraphs"} {
+			err := os.WriteFile(filepath.Join(dir, name), []byte("partial"), 0o644)
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+
+		f, err := Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		des, err := os.ReadDir(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, des) {
+			return
+		}
+		jobs, err := f.List()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, jobs) {
+			return
+		}
+	})
+
+	t.Run("should not allow job IDs to escape the directory", func(subT *testing.T) {
+		f, err := Open(subT.TempDir())
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		for _, id := range []string{"", "..", "../a", "a/b"} {
+			_, err := f.Get(id)
+			if !assert.True(subT, errors.Is(err, fs.ErrNotExist), "unexpected error for %q: %v", id, err) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package jobs provides stores for the ingest jobs of the ingest service.
+package jobs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Memory is a JobStore which keeps every job in memory,
+// so jobs do not survive a restart.
+type Memory struct {
+	mu        sync.Mutex
+	jobs      map[string]*pb.Job
+	subgraphs map[string][]*subgraph.Subgraph
+}
+
+// NewMemory
+func NewMemory() *Memory {
+	return &Memory{
+		jobs:      make(map[string]*pb.Job),
+		subgraphs: make(map[string][]*subgraph.Subgraph),
+	}
+}
+
+// Create
+func (m *Memory) Create(job *pb.Job, next func() (*subgraph.Subgraph, error)) error {
+	var gs []*subgraph.Subgraph
+	for {
+		g, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		gs = append(gs, proto.Clone(g).(*subgraph.Subgraph))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.jobs[job.Id] = proto.Clone(job).(*pb.Job)
+	m.subgraphs[job.Id] = gs
+	return nil
+}
+
+// Update
+func (m *Memory) Update(job *pb.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.jobs[job.Id]; !ok {
+		return notFound(job.Id)
+	}
+	m.jobs[job.Id] = proto.Clone(job).(*pb.Job)
+	if finished(job) {
+		delete(m.subgraphs, job.Id)
+	}
+	return nil
+}
+
+// Get
+func (m *Memory) Get(id string) (*pb.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	return proto.Clone(job).(*pb.Job), nil
+}
+
+// List returns every job in the order they were created.
+func (m *Memory) List() ([]*pb.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]*pb.Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, proto.Clone(job).(*pb.Job))
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// Subgraphs
+func (m *Memory) Subgraphs(id string, fn func(i int, g *subgraph.Subgraph) error) error {
+	m.mu.Lock()
+	_, ok := m.jobs[id]
+	gs := m.subgraphs[id]
+	m.mu.Unlock()
+	if !ok {
+		return notFound(id)
+	}
+
+	for i, g := range gs {
+		err := fn(i, proto.Clone(g).(*subgraph.Subgraph))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func notFound(id string) error {
+	return fmt.Errorf("jobs: %s: %w", id, fs.ErrNotExist)
+}
+
+// finished reports whether the subgraphs of the job are no longer needed.
+func finished(job *pb.Job) bool {
+	return job.State == pb.Job_DONE || job.State == pb.Job_FAILED
+}
+
+func sortJobs(jobs []*pb.Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		a, b := jobs[i].CreatedAt.AsTime(), jobs[j].CreatedAt.AsTime()
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return jobs[i].Id < jobs[j].Id
+	})
+}
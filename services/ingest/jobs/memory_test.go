@@ -0,0 +1,223 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jobs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// store is the ingest.JobStore interface, which is not
+// imported to keep this package independent of it.
+type store interface {
+	Create(job *pb.Job, next func() (*subgraph.Subgraph, error)) error
+	Update(job *pb.Job) error
+	Get(id string) (*pb.Job, error)
+	List() ([]*pb.Job, error)
+	Subgraphs(id string, fn func(i int, g *subgraph.Subgraph) error) error
+}
+
+func newTestSubgraph(tuid string) *subgraph.Subgraph {
+	return &subgraph.Subgraph{
+		Triples: []*subgraph.Triple{
+			{
+				Subject:   &subgraph.Subject{Type: "Person", Tuid: tuid},
+				Predicate: &subgraph.Predicate{Name: "name"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Bob"}},
+			},
+		},
+	}
+}
+
+func newTestJob(id string, createdAt time.Time) *pb.Job {
+	return &pb.Job{
+		Id:        id,
+		State:     pb.Job_PENDING,
+		CreatedAt: timestamppb.New(createdAt),
+	}
+}
+
+func createJob(s store, job *pb.Job, gs ...*subgraph.Subgraph) error {
+	return s.Create(job, func() (*subgraph.Subgraph, error) {
+		if len(gs) == 0 {
+			return nil, io.EOF
+		}
+		g := gs[0]
+		gs = gs[1:]
+		return g, nil
+	})
+}
+
+func collectSubgraphs(s store, id string) ([]*subgraph.Subgraph, error) {
+	var gs []*subgraph.Subgraph
+	err := s.Subgraphs(id, func(i int, g *subgraph.Subgraph) error {
+		if i != len(gs) {
+			return errors.New("subgraphs out of order")
+		}
+		gs = append(gs, g)
+		return nil
+	})
+	return gs, err
+}
+
+func testStore(t *testing.T, open func(*testing.T) store) {
+	t.Run("should return the subgraphs of a job in order", func(subT *testing.T) {
+		s := open(subT)
+
+		gs := []*subgraph.Subgraph{newTestSubgraph("1"), newTestSubgraph("2"), newTestSubgraph("3")}
+		err := createJob(s, newTestJob("a", time.Now()), gs...)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		read, err := collectSubgraphs(s, "a")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, read, len(gs)) {
+			return
+		}
+		for i, g := range gs {
+			if !assert.True(subT, proto.Equal(g, read[i]), "expected %v but got %v", g, read[i]) {
+				return
+			}
+		}
+	})
+
+	t.Run("should not record a job if its subgraphs could not be read", func(subT *testing.T) {
+		s := open(subT)
+
+		readErr := errors.New("connection reset")
+		err := s.Create(newTestJob("a", time.Now()), func() (*subgraph.Subgraph, error) {
+			return nil, readErr
+		})
+		if !assert.Equal(subT, readErr, err) {
+			return
+		}
+
+		_, err = s.Get("a")
+		if !assert.True(subT, errors.Is(err, fs.ErrNotExist), "unexpected error: %v", err) {
+			return
+		}
+	})
+
+	t.Run("should record the progress of a job", func(subT *testing.T) {
+		s := open(subT)
+
+		job := newTestJob("a", time.Now())
+		err := createJob(s, job, newTestSubgraph("1"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		job.State = pb.Job_RUNNING
+		job.NumOfAccepted = 1
+		err = s.Update(job)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		got, err := s.Get("a")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(job, got), "expected %v but got %v", job, got) {
+			return
+		}
+	})
+
+	t.Run("should remove the subgraphs of a finished job", func(subT *testing.T) {
+		s := open(subT)
+
+		job := newTestJob("a", time.Now())
+		err := createJob(s, job, newTestSubgraph("1"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		job.State = pb.Job_DONE
+		err = s.Update(job)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		gs, err := collectSubgraphs(s, "a")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, gs) {
+			return
+		}
+	})
+
+	t.Run("should list jobs in the order they were created", func(subT *testing.T) {
+		s := open(subT)
+
+		now := time.Now()
+		for i, id := range []string{"c", "a", "b"} {
+			err := createJob(s, newTestJob(id, now.Add(time.Duration(i)*time.Second)))
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+
+		jobs, err := s.List()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		var ids []string
+		for _, job := range jobs {
+			ids = append(ids, job.Id)
+		}
+		if !assert.Equal(subT, []string{"c", "a", "b"}, ids) {
+			return
+		}
+	})
+
+	t.Run("should return a not exist error for an unknown job", func(subT *testing.T) {
+		s := open(subT)
+
+		_, err := s.Get("unknown")
+		if !assert.True(subT, errors.Is(err, fs.ErrNotExist), "unexpected error: %v", err) {
+			return
+		}
+		err = s.Update(newTestJob("unknown", time.Now()))
+		if !assert.True(subT, errors.Is(err, fs.ErrNotExist), "unexpected error: %v", err) {
+			return
+		}
+		err = s.Subgraphs("unknown", func(int, *subgraph.Subgraph) error { return nil })
+		if !assert.True(subT, errors.Is(err, fs.ErrNotExist), "unexpected error: %v", err) {
+			return
+		}
+	})
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, func(*testing.T) store {
+		return NewMemory()
+	})
+}
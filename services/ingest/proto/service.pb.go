@@ -23,8 +23,10 @@ package proto
 
 import (
 	subgraph "github.com/z5labs/megamind/subgraph"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -36,10 +38,129 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type IngestAck_Status int32
+
+const (
+	IngestAck_STATUS_UNSPECIFIED IngestAck_Status = 0
+	// The subgraph was accepted.
+	IngestAck_ACK IngestAck_Status = 1
+	// The subgraph was not accepted and should be resent.
+	IngestAck_NACK IngestAck_Status = 2
+)
+
+// Enum value maps for IngestAck_Status.
+var (
+	IngestAck_Status_name = map[int32]string{
+		0: "STATUS_UNSPECIFIED",
+		1: "ACK",
+		2: "NACK",
+	}
+	IngestAck_Status_value = map[string]int32{
+		"STATUS_UNSPECIFIED": 0,
+		"ACK":                1,
+		"NACK":               2,
+	}
+)
+
+func (x IngestAck_Status) Enum() *IngestAck_Status {
+	p := new(IngestAck_Status)
+	*p = x
+	return p
+}
+
+func (x IngestAck_Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (IngestAck_Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_services_ingest_proto_service_proto_enumTypes[0].Descriptor()
+}
+
+func (IngestAck_Status) Type() protoreflect.EnumType {
+	return &file_services_ingest_proto_service_proto_enumTypes[0]
+}
+
+func (x IngestAck_Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use IngestAck_Status.Descriptor instead.
+func (IngestAck_Status) EnumDescriptor() ([]byte, []int) {
+	return file_services_ingest_proto_service_proto_rawDescGZIP(), []int{3, 0}
+}
+
+type Job_State int32
+
+const (
+	Job_STATE_UNSPECIFIED Job_State = 0
+	// The subgraphs have been recorded but none have been ingested yet.
+	Job_PENDING Job_State = 1
+	// The subgraphs are being ingested.
+	Job_RUNNING Job_State = 2
+	// Every subgraph has been either accepted or rejected.
+	Job_DONE Job_State = 3
+	// The job could not be finished, see errors.
+	Job_FAILED Job_State = 4
+)
+
+// Enum value maps for Job_State.
+var (
+	Job_State_name = map[int32]string{
+		0: "STATE_UNSPECIFIED",
+		1: "PENDING",
+		2: "RUNNING",
+		3: "DONE",
+		4: "FAILED",
+	}
+	Job_State_value = map[string]int32{
+		"STATE_UNSPECIFIED": 0,
+		"PENDING":           1,
+		"RUNNING":           2,
+		"DONE":              3,
+		"FAILED":            4,
+	}
+)
+
+func (x Job_State) Enum() *Job_State {
+	p := new(Job_State)
+	*p = x
+	return p
+}
+
+func (x Job_State) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Job_State) Descriptor() protoreflect.EnumDescriptor {
+	return file_services_ingest_proto_service_proto_enumTypes[1].Descriptor()
+}
+
+func (Job_State) Type() protoreflect.EnumType {
+	return &file_services_ingest_proto_service_proto_enumTypes[1]
+}
+
+func (x Job_State) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Job_State.Descriptor instead.
+func (Job_State) EnumDescriptor() ([]byte, []int) {
+	return file_services_ingest_proto_service_proto_rawDescGZIP(), []int{5, 0}
+}
+
 type IngestResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	// Server assigned identifier for correlating ingested subgraphs
+	// with what eventually lands in the graph.
+	IngestId     string                 `protobuf:"bytes,1,opt,name=ingest_id,json=ingestId,proto3" json:"ingest_id,omitempty"`
+	AcceptedAt   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=accepted_at,json=acceptedAt,proto3" json:"accepted_at,omitempty"`
+	NumOfTriples int64                  `protobuf:"varint,3,opt,name=num_of_triples,json=numOfTriples,proto3" json:"num_of_triples,omitempty"`
+	// For Ingest, this is the sum of the distinct subjects in each subgraph.
+	NumOfDistinctSubjects int64      `protobuf:"varint,4,opt,name=num_of_distinct_subjects,json=numOfDistinctSubjects,proto3" json:"num_of_distinct_subjects,omitempty"`
+	Warnings              []*Warning `protobuf:"bytes,5,rep,name=warnings,proto3" json:"warnings,omitempty"`
 }
 
 func (x *IngestResponse) Reset() {
@@ -74,27 +195,597 @@ func (*IngestResponse) Descriptor() ([]byte, []int) {
 	return file_services_ingest_proto_service_proto_rawDescGZIP(), []int{0}
 }
 
+func (x *IngestResponse) GetIngestId() string {
+	if x != nil {
+		return x.IngestId
+	}
+	return ""
+}
+
+func (x *IngestResponse) GetAcceptedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AcceptedAt
+	}
+	return nil
+}
+
+func (x *IngestResponse) GetNumOfTriples() int64 {
+	if x != nil {
+		return x.NumOfTriples
+	}
+	return 0
+}
+
+func (x *IngestResponse) GetNumOfDistinctSubjects() int64 {
+	if x != nil {
+		return x.NumOfDistinctSubjects
+	}
+	return 0
+}
+
+func (x *IngestResponse) GetWarnings() []*Warning {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+// Warning describes a triple which was accepted but is likely a mistake.
+type Warning struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Index of the subgraph within an Ingest stream. Always 0 for IngestSubgraph.
+	SubgraphIndex int32 `protobuf:"varint,1,opt,name=subgraph_index,json=subgraphIndex,proto3" json:"subgraph_index,omitempty"`
+	// Index of the triple within its subgraph.
+	TripleIndex int32  `protobuf:"varint,2,opt,name=triple_index,json=tripleIndex,proto3" json:"triple_index,omitempty"`
+	Message     string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *Warning) Reset() {
+	*x = Warning{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_ingest_proto_service_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Warning) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Warning) ProtoMessage() {}
+
+func (x *Warning) ProtoReflect() protoreflect.Message {
+	mi := &file_services_ingest_proto_service_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Warning.ProtoReflect.Descriptor instead.
+func (*Warning) Descriptor() ([]byte, []int) {
+	return file_services_ingest_proto_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Warning) GetSubgraphIndex() int32 {
+	if x != nil {
+		return x.SubgraphIndex
+	}
+	return 0
+}
+
+func (x *Warning) GetTripleIndex() int32 {
+	if x != nil {
+		return x.TripleIndex
+	}
+	return 0
+}
+
+func (x *Warning) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type IngestStreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Client assigned sequence number which is echoed back in the IngestAck.
+	Sequence uint64             `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Subgraph *subgraph.Subgraph `protobuf:"bytes,2,opt,name=subgraph,proto3" json:"subgraph,omitempty"`
+}
+
+func (x *IngestStreamRequest) Reset() {
+	*x = IngestStreamRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_ingest_proto_service_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IngestStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestStreamRequest) ProtoMessage() {}
+
+func (x *IngestStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_services_ingest_proto_service_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestStreamRequest.ProtoReflect.Descriptor instead.
+func (*IngestStreamRequest) Descriptor() ([]byte, []int) {
+	return file_services_ingest_proto_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *IngestStreamRequest) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *IngestStreamRequest) GetSubgraph() *subgraph.Subgraph {
+	if x != nil {
+		return x.Subgraph
+	}
+	return nil
+}
+
+type IngestAck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sequence uint64           `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Status   IngestAck_Status `protobuf:"varint,2,opt,name=status,proto3,enum=proto.IngestAck_Status" json:"status,omitempty"`
+	// Reason the subgraph was not accepted. Only set for NACK.
+	Error string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	// Only set for ACK.
+	Receipt *IngestResponse `protobuf:"bytes,4,opt,name=receipt,proto3" json:"receipt,omitempty"`
+}
+
+func (x *IngestAck) Reset() {
+	*x = IngestAck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_ingest_proto_service_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IngestAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestAck) ProtoMessage() {}
+
+func (x *IngestAck) ProtoReflect() protoreflect.Message {
+	mi := &file_services_ingest_proto_service_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestAck.ProtoReflect.Descriptor instead.
+func (*IngestAck) Descriptor() ([]byte, []int) {
+	return file_services_ingest_proto_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IngestAck) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *IngestAck) GetStatus() IngestAck_Status {
+	if x != nil {
+		return x.Status
+	}
+	return IngestAck_STATUS_UNSPECIFIED
+}
+
+func (x *IngestAck) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *IngestAck) GetReceipt() *IngestResponse {
+	if x != nil {
+		return x.Receipt
+	}
+	return nil
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_ingest_proto_service_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_services_ingest_proto_service_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_services_ingest_proto_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Job struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id    string    `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State Job_State `protobuf:"varint,2,opt,name=state,proto3,enum=proto.Job_State" json:"state,omitempty"`
+	// Caller which submitted the job, if known.
+	Caller    string                 `protobuf:"bytes,3,opt,name=caller,proto3" json:"caller,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// Number of subgraphs submitted to the job.
+	NumOfSubgraphs int64 `protobuf:"varint,6,opt,name=num_of_subgraphs,json=numOfSubgraphs,proto3" json:"num_of_subgraphs,omitempty"`
+	// Number of subgraphs which passed validation.
+	NumOfAccepted int64 `protobuf:"varint,7,opt,name=num_of_accepted,json=numOfAccepted,proto3" json:"num_of_accepted,omitempty"`
+	// Number of subgraphs which failed validation.
+	NumOfRejected int64 `protobuf:"varint,8,opt,name=num_of_rejected,json=numOfRejected,proto3" json:"num_of_rejected,omitempty"`
+	// Number of accepted subgraphs which have been published.
+	NumOfPublished int64 `protobuf:"varint,9,opt,name=num_of_published,json=numOfPublished,proto3" json:"num_of_published,omitempty"`
+	// Only the first warnings and errors are kept.
+	Warnings []*Warning  `protobuf:"bytes,10,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	Errors   []*JobError `protobuf:"bytes,11,rep,name=errors,proto3" json:"errors,omitempty"`
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_ingest_proto_service_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_services_ingest_proto_service_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_services_ingest_proto_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetState() Job_State {
+	if x != nil {
+		return x.State
+	}
+	return Job_STATE_UNSPECIFIED
+}
+
+func (x *Job) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *Job) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Job) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Job) GetNumOfSubgraphs() int64 {
+	if x != nil {
+		return x.NumOfSubgraphs
+	}
+	return 0
+}
+
+func (x *Job) GetNumOfAccepted() int64 {
+	if x != nil {
+		return x.NumOfAccepted
+	}
+	return 0
+}
+
+func (x *Job) GetNumOfRejected() int64 {
+	if x != nil {
+		return x.NumOfRejected
+	}
+	return 0
+}
+
+func (x *Job) GetNumOfPublished() int64 {
+	if x != nil {
+		return x.NumOfPublished
+	}
+	return 0
+}
+
+func (x *Job) GetWarnings() []*Warning {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *Job) GetErrors() []*JobError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+type JobError struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Index of the subgraph within the job, or -1 if the error is not for a single subgraph.
+	SubgraphIndex int32  `protobuf:"varint,1,opt,name=subgraph_index,json=subgraphIndex,proto3" json:"subgraph_index,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *JobError) Reset() {
+	*x = JobError{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_services_ingest_proto_service_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *JobError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobError) ProtoMessage() {}
+
+func (x *JobError) ProtoReflect() protoreflect.Message {
+	mi := &file_services_ingest_proto_service_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobError.ProtoReflect.Descriptor instead.
+func (*JobError) Descriptor() ([]byte, []int) {
+	return file_services_ingest_proto_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *JobError) GetSubgraphIndex() int32 {
+	if x != nil {
+		return x.SubgraphIndex
+	}
+	return 0
+}
+
+func (x *JobError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 var File_services_ingest_proto_service_proto protoreflect.FileDescriptor
 
 var file_services_ingest_proto_service_proto_rawDesc = []byte{
 	0x0a, 0x23, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x69, 0x6e, 0x67, 0x65, 0x73,
 	0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x17, 0x73, 0x75,
-	0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2f, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x10, 0x0a, 0x0e, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0x84, 0x01, 0x0a, 0x0e, 0x53, 0x75, 0x62, 0x67,
-	0x72, 0x61, 0x70, 0x68, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x12, 0x3b, 0x0a, 0x0e, 0x49, 0x6e,
-	0x67, 0x65, 0x73, 0x74, 0x53, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x12, 0x12, 0x2e, 0x73,
-	0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x53, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68,
-	0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x06, 0x49, 0x6e, 0x67, 0x65, 0x73,
-	0x74, 0x12, 0x12, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x53, 0x75, 0x62,
-	0x67, 0x72, 0x61, 0x70, 0x68, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x49, 0x6e,
-	0x67, 0x65, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x42, 0x32,
-	0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x7a, 0x35, 0x6c,
-	0x61, 0x62, 0x73, 0x2f, 0x6d, 0x65, 0x67, 0x61, 0x6d, 0x69, 0x6e, 0x64, 0x2f, 0x73, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x2f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1c, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x17, 0x73, 0x75, 0x62,
+	0x67, 0x72, 0x61, 0x70, 0x68, 0x2f, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0xf5, 0x01, 0x0a, 0x0e, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x6e, 0x67, 0x65, 0x73,
+	0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x67, 0x65,
+	0x73, 0x74, 0x49, 0x64, 0x12, 0x3b, 0x0a, 0x0b, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64,
+	0x5f, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0a, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x41,
+	0x74, 0x12, 0x24, 0x0a, 0x0e, 0x6e, 0x75, 0x6d, 0x5f, 0x6f, 0x66, 0x5f, 0x74, 0x72, 0x69, 0x70,
+	0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x6e, 0x75, 0x6d, 0x4f, 0x66,
+	0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x12, 0x37, 0x0a, 0x18, 0x6e, 0x75, 0x6d, 0x5f, 0x6f,
+	0x66, 0x5f, 0x64, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x63, 0x74, 0x5f, 0x73, 0x75, 0x62, 0x6a, 0x65,
+	0x63, 0x74, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x15, 0x6e, 0x75, 0x6d, 0x4f, 0x66,
+	0x44, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x63, 0x74, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x73,
+	0x12, 0x2a, 0x0a, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x05, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x57, 0x61, 0x72, 0x6e, 0x69,
+	0x6e, 0x67, 0x52, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73, 0x22, 0x6d, 0x0a, 0x07,
+	0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x75, 0x62, 0x67, 0x72,
+	0x61, 0x70, 0x68, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0d, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x21,
+	0x0a, 0x0c, 0x74, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x74, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x49, 0x6e, 0x64, 0x65,
+	0x78, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x61, 0x0a, 0x13, 0x49,
+	0x6e, 0x67, 0x65, 0x73, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x2e,
+	0x0a, 0x08, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x12, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x53, 0x75, 0x62, 0x67,
+	0x72, 0x61, 0x70, 0x68, 0x52, 0x08, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x22, 0xd4,
+	0x01, 0x0a, 0x09, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x41, 0x63, 0x6b, 0x12, 0x1a, 0x0a, 0x08,
+	0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08,
+	0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x2f, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x41, 0x63, 0x6b, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12,
+	0x2f, 0x0a, 0x07, 0x72, 0x65, 0x63, 0x65, 0x69, 0x70, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x07, 0x72, 0x65, 0x63, 0x65, 0x69, 0x70, 0x74,
+	0x22, 0x33, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x16, 0x0a, 0x12, 0x53, 0x54,
+	0x41, 0x54, 0x55, 0x53, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44,
+	0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x41, 0x43, 0x4b, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x4e,
+	0x41, 0x43, 0x4b, 0x10, 0x02, 0x22, 0x1f, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x4a, 0x6f, 0x62, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x94, 0x04, 0x0a, 0x03, 0x4a, 0x6f, 0x62, 0x12, 0x0e,
+	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x26,
+	0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x10, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4a, 0x6f, 0x62, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52,
+	0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x61, 0x6c, 0x6c, 0x65, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x61, 0x6c, 0x6c, 0x65, 0x72, 0x12, 0x39,
+	0x0a, 0x0a, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09,
+	0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x39, 0x0a, 0x0a, 0x75, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x75, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x64, 0x41, 0x74, 0x12, 0x28, 0x0a, 0x10, 0x6e, 0x75, 0x6d, 0x5f, 0x6f, 0x66, 0x5f, 0x73,
+	0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e,
+	0x6e, 0x75, 0x6d, 0x4f, 0x66, 0x53, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x73, 0x12, 0x26,
+	0x0a, 0x0f, 0x6e, 0x75, 0x6d, 0x5f, 0x6f, 0x66, 0x5f, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65,
+	0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x6e, 0x75, 0x6d, 0x4f, 0x66, 0x41, 0x63,
+	0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x12, 0x26, 0x0a, 0x0f, 0x6e, 0x75, 0x6d, 0x5f, 0x6f, 0x66,
+	0x5f, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x65, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0d, 0x6e, 0x75, 0x6d, 0x4f, 0x66, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x65, 0x64, 0x12, 0x28,
+	0x0a, 0x10, 0x6e, 0x75, 0x6d, 0x5f, 0x6f, 0x66, 0x5f, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68,
+	0x65, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x6e, 0x75, 0x6d, 0x4f, 0x66, 0x50,
+	0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x65, 0x64, 0x12, 0x2a, 0x0a, 0x08, 0x77, 0x61, 0x72, 0x6e,
+	0x69, 0x6e, 0x67, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x52, 0x08, 0x77, 0x61, 0x72, 0x6e,
+	0x69, 0x6e, 0x67, 0x73, 0x12, 0x27, 0x0a, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x0b,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4a, 0x6f, 0x62,
+	0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x22, 0x4e, 0x0a,
+	0x05, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x15, 0x0a, 0x11, 0x53, 0x54, 0x41, 0x54, 0x45, 0x5f,
+	0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0b, 0x0a,
+	0x07, 0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x52, 0x55,
+	0x4e, 0x4e, 0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x08, 0x0a, 0x04, 0x44, 0x4f, 0x4e, 0x45, 0x10,
+	0x03, 0x12, 0x0a, 0x0a, 0x06, 0x46, 0x41, 0x49, 0x4c, 0x45, 0x44, 0x10, 0x04, 0x22, 0x4b, 0x0a,
+	0x08, 0x4a, 0x6f, 0x62, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x75, 0x62,
+	0x67, 0x72, 0x61, 0x70, 0x68, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0d, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x49, 0x6e, 0x64, 0x65, 0x78,
+	0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x32, 0xaf, 0x03, 0x0a, 0x0e, 0x53,
+	0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x12, 0x5b, 0x0a,
+	0x0e, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x53, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x12,
+	0x12, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x53, 0x75, 0x62, 0x67, 0x72,
+	0x61, 0x70, 0x68, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x49, 0x6e, 0x67, 0x65,
+	0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x1e, 0x82, 0xd3, 0xe4, 0x93,
+	0x02, 0x18, 0x22, 0x13, 0x2f, 0x76, 0x31, 0x2f, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68,
+	0x3a, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x3a, 0x01, 0x2a, 0x12, 0x56, 0x0a, 0x06, 0x49, 0x6e,
+	0x67, 0x65, 0x73, 0x74, 0x12, 0x12, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e,
+	0x53, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x1a, 0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x1f, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x19, 0x22, 0x14, 0x2f, 0x76, 0x31, 0x2f, 0x73, 0x75, 0x62,
+	0x67, 0x72, 0x61, 0x70, 0x68, 0x73, 0x3a, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x3a, 0x01, 0x2a,
+	0x28, 0x01, 0x12, 0x61, 0x0a, 0x0c, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x53, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x12, 0x1a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x49, 0x6e, 0x67, 0x65, 0x73,
+	0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x41, 0x63, 0x6b,
+	0x22, 0x1f, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x19, 0x3a, 0x01, 0x2a, 0x22, 0x14, 0x2f, 0x76, 0x31,
+	0x2f, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x73, 0x3a, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x28, 0x01, 0x30, 0x01, 0x12, 0x42, 0x0a, 0x09, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x4a,
+	0x6f, 0x62, 0x12, 0x12, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x53, 0x75,
+	0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x1a, 0x0a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4a,
+	0x6f, 0x62, 0x22, 0x13, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x0d, 0x22, 0x08, 0x2f, 0x76, 0x31, 0x2f,
+	0x6a, 0x6f, 0x62, 0x73, 0x3a, 0x01, 0x2a, 0x28, 0x01, 0x12, 0x41, 0x0a, 0x06, 0x47, 0x65, 0x74,
+	0x4a, 0x6f, 0x62, 0x12, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x47, 0x65, 0x74, 0x4a,
+	0x6f, 0x62, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0a, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x4a, 0x6f, 0x62, 0x22, 0x15, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x0f, 0x12, 0x0d, 0x2f,
+	0x76, 0x31, 0x2f, 0x6a, 0x6f, 0x62, 0x73, 0x2f, 0x7b, 0x69, 0x64, 0x7d, 0x42, 0x32, 0x5a, 0x30,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x7a, 0x35, 0x6c, 0x61, 0x62,
+	0x73, 0x2f, 0x6d, 0x65, 0x67, 0x61, 0x6d, 0x69, 0x6e, 0x64, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x73, 0x2f, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -109,21 +800,47 @@ func file_services_ingest_proto_service_proto_rawDescGZIP() []byte {
 	return file_services_ingest_proto_service_proto_rawDescData
 }
 
-var file_services_ingest_proto_service_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_services_ingest_proto_service_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_services_ingest_proto_service_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_services_ingest_proto_service_proto_goTypes = []interface{}{
-	(*IngestResponse)(nil),    // 0: proto.IngestResponse
-	(*subgraph.Subgraph)(nil), // 1: subgraph.Subgraph
+	(IngestAck_Status)(0),         // 0: proto.IngestAck.Status
+	(Job_State)(0),                // 1: proto.Job.State
+	(*IngestResponse)(nil),        // 2: proto.IngestResponse
+	(*Warning)(nil),               // 3: proto.Warning
+	(*IngestStreamRequest)(nil),   // 4: proto.IngestStreamRequest
+	(*IngestAck)(nil),             // 5: proto.IngestAck
+	(*GetJobRequest)(nil),         // 6: proto.GetJobRequest
+	(*Job)(nil),                   // 7: proto.Job
+	(*JobError)(nil),              // 8: proto.JobError
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+	(*subgraph.Subgraph)(nil),     // 10: subgraph.Subgraph
 }
 var file_services_ingest_proto_service_proto_depIdxs = []int32{
-	1, // 0: proto.SubgraphIngest.IngestSubgraph:input_type -> subgraph.Subgraph
-	1, // 1: proto.SubgraphIngest.Ingest:input_type -> subgraph.Subgraph
-	0, // 2: proto.SubgraphIngest.IngestSubgraph:output_type -> proto.IngestResponse
-	0, // 3: proto.SubgraphIngest.Ingest:output_type -> proto.IngestResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	9,  // 0: proto.IngestResponse.accepted_at:type_name -> google.protobuf.Timestamp
+	3,  // 1: proto.IngestResponse.warnings:type_name -> proto.Warning
+	10, // 2: proto.IngestStreamRequest.subgraph:type_name -> subgraph.Subgraph
+	0,  // 3: proto.IngestAck.status:type_name -> proto.IngestAck.Status
+	2,  // 4: proto.IngestAck.receipt:type_name -> proto.IngestResponse
+	1,  // 5: proto.Job.state:type_name -> proto.Job.State
+	9,  // 6: proto.Job.created_at:type_name -> google.protobuf.Timestamp
+	9,  // 7: proto.Job.updated_at:type_name -> google.protobuf.Timestamp
+	3,  // 8: proto.Job.warnings:type_name -> proto.Warning
+	8,  // 9: proto.Job.errors:type_name -> proto.JobError
+	10, // 10: proto.SubgraphIngest.IngestSubgraph:input_type -> subgraph.Subgraph
+	10, // 11: proto.SubgraphIngest.Ingest:input_type -> subgraph.Subgraph
+	4,  // 12: proto.SubgraphIngest.IngestStream:input_type -> proto.IngestStreamRequest
+	10, // 13: proto.SubgraphIngest.SubmitJob:input_type -> subgraph.Subgraph
+	6,  // 14: proto.SubgraphIngest.GetJob:input_type -> proto.GetJobRequest
+	2,  // 15: proto.SubgraphIngest.IngestSubgraph:output_type -> proto.IngestResponse
+	2,  // 16: proto.SubgraphIngest.Ingest:output_type -> proto.IngestResponse
+	5,  // 17: proto.SubgraphIngest.IngestStream:output_type -> proto.IngestAck
+	7,  // 18: proto.SubgraphIngest.SubmitJob:output_type -> proto.Job
+	7,  // 19: proto.SubgraphIngest.GetJob:output_type -> proto.Job
+	15, // [15:20] is the sub-list for method output_type
+	10, // [10:15] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_services_ingest_proto_service_proto_init() }
@@ -144,19 +861,92 @@ func file_services_ingest_proto_service_proto_init() {
 				return nil
 			}
 		}
+		file_services_ingest_proto_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Warning); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_ingest_proto_service_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IngestStreamRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_ingest_proto_service_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IngestAck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_ingest_proto_service_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetJobRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_ingest_proto_service_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Job); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_services_ingest_proto_service_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*JobError); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_services_ingest_proto_service_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   1,
+			NumEnums:      2,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_services_ingest_proto_service_proto_goTypes,
 		DependencyIndexes: file_services_ingest_proto_service_proto_depIdxs,
+		EnumInfos:         file_services_ingest_proto_service_proto_enumTypes,
 		MessageInfos:      file_services_ingest_proto_service_proto_msgTypes,
 	}.Build()
 	File_services_ingest_proto_service_proto = out.File
@@ -21,6 +21,14 @@ const _ = grpc.SupportPackageIsVersion7
 type SubgraphIngestClient interface {
 	IngestSubgraph(ctx context.Context, in *subgraph.Subgraph, opts ...grpc.CallOption) (*IngestResponse, error)
 	Ingest(ctx context.Context, opts ...grpc.CallOption) (SubgraphIngest_IngestClient, error)
+	// IngestStream acknowledges every subgraph sent on the stream, in the
+	// order they are accepted rather than the order they were sent.
+	IngestStream(ctx context.Context, opts ...grpc.CallOption) (SubgraphIngest_IngestStreamClient, error)
+	// SubmitJob durably records every subgraph sent on the stream and returns
+	// a pending job right away. The subgraphs are then ingested in the
+	// background and the progress of the job can be polled with GetJob.
+	SubmitJob(ctx context.Context, opts ...grpc.CallOption) (SubgraphIngest_SubmitJobClient, error)
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
 }
 
 type subgraphIngestClient struct {
@@ -74,12 +82,94 @@ func (x *subgraphIngestIngestClient) CloseAndRecv() (*IngestResponse, error) {
 	return m, nil
 }
 
+func (c *subgraphIngestClient) IngestStream(ctx context.Context, opts ...grpc.CallOption) (SubgraphIngest_IngestStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SubgraphIngest_ServiceDesc.Streams[1], "/proto.SubgraphIngest/IngestStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &subgraphIngestIngestStreamClient{stream}
+	return x, nil
+}
+
+type SubgraphIngest_IngestStreamClient interface {
+	Send(*IngestStreamRequest) error
+	Recv() (*IngestAck, error)
+	grpc.ClientStream
+}
+
+type subgraphIngestIngestStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *subgraphIngestIngestStreamClient) Send(m *IngestStreamRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *subgraphIngestIngestStreamClient) Recv() (*IngestAck, error) {
+	m := new(IngestAck)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *subgraphIngestClient) SubmitJob(ctx context.Context, opts ...grpc.CallOption) (SubgraphIngest_SubmitJobClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SubgraphIngest_ServiceDesc.Streams[2], "/proto.SubgraphIngest/SubmitJob", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &subgraphIngestSubmitJobClient{stream}
+	return x, nil
+}
+
+type SubgraphIngest_SubmitJobClient interface {
+	Send(*subgraph.Subgraph) error
+	CloseAndRecv() (*Job, error)
+	grpc.ClientStream
+}
+
+type subgraphIngestSubmitJobClient struct {
+	grpc.ClientStream
+}
+
+func (x *subgraphIngestSubmitJobClient) Send(m *subgraph.Subgraph) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *subgraphIngestSubmitJobClient) CloseAndRecv() (*Job, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Job)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *subgraphIngestClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, "/proto.SubgraphIngest/GetJob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SubgraphIngestServer is the server API for SubgraphIngest service.
 // All implementations must embed UnimplementedSubgraphIngestServer
 // for forward compatibility
 type SubgraphIngestServer interface {
 	IngestSubgraph(context.Context, *subgraph.Subgraph) (*IngestResponse, error)
 	Ingest(SubgraphIngest_IngestServer) error
+	// IngestStream acknowledges every subgraph sent on the stream, in the
+	// order they are accepted rather than the order they were sent.
+	IngestStream(SubgraphIngest_IngestStreamServer) error
+	// SubmitJob durably records every subgraph sent on the stream and returns
+	// a pending job right away. The subgraphs are then ingested in the
+	// background and the progress of the job can be polled with GetJob.
+	SubmitJob(SubgraphIngest_SubmitJobServer) error
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
 	mustEmbedUnimplementedSubgraphIngestServer()
 }
 
@@ -93,6 +183,15 @@ func (UnimplementedSubgraphIngestServer) IngestSubgraph(context.Context, *subgra
 func (UnimplementedSubgraphIngestServer) Ingest(SubgraphIngest_IngestServer) error {
 	return status.Errorf(codes.Unimplemented, "method Ingest not implemented")
 }
+func (UnimplementedSubgraphIngestServer) IngestStream(SubgraphIngest_IngestStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method IngestStream not implemented")
+}
+func (UnimplementedSubgraphIngestServer) SubmitJob(SubgraphIngest_SubmitJobServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubmitJob not implemented")
+}
+func (UnimplementedSubgraphIngestServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
 func (UnimplementedSubgraphIngestServer) mustEmbedUnimplementedSubgraphIngestServer() {}
 
 // UnsafeSubgraphIngestServer may be embedded to opt out of forward compatibility for this service.
@@ -150,6 +249,76 @@ func (x *subgraphIngestIngestServer) Recv() (*subgraph.Subgraph, error) {
 	return m, nil
 }
 
+func _SubgraphIngest_IngestStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SubgraphIngestServer).IngestStream(&subgraphIngestIngestStreamServer{stream})
+}
+
+type SubgraphIngest_IngestStreamServer interface {
+	Send(*IngestAck) error
+	Recv() (*IngestStreamRequest, error)
+	grpc.ServerStream
+}
+
+type subgraphIngestIngestStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *subgraphIngestIngestStreamServer) Send(m *IngestAck) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *subgraphIngestIngestStreamServer) Recv() (*IngestStreamRequest, error) {
+	m := new(IngestStreamRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _SubgraphIngest_SubmitJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SubgraphIngestServer).SubmitJob(&subgraphIngestSubmitJobServer{stream})
+}
+
+type SubgraphIngest_SubmitJobServer interface {
+	SendAndClose(*Job) error
+	Recv() (*subgraph.Subgraph, error)
+	grpc.ServerStream
+}
+
+type subgraphIngestSubmitJobServer struct {
+	grpc.ServerStream
+}
+
+func (x *subgraphIngestSubmitJobServer) SendAndClose(m *Job) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *subgraphIngestSubmitJobServer) Recv() (*subgraph.Subgraph, error) {
+	m := new(subgraph.Subgraph)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _SubgraphIngest_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubgraphIngestServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SubgraphIngest/GetJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubgraphIngestServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // SubgraphIngest_ServiceDesc is the grpc.ServiceDesc for SubgraphIngest service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -161,6 +330,10 @@ var SubgraphIngest_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "IngestSubgraph",
 			Handler:    _SubgraphIngest_IngestSubgraph_Handler,
 		},
+		{
+			MethodName: "GetJob",
+			Handler:    _SubgraphIngest_GetJob_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -168,6 +341,17 @@ var SubgraphIngest_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _SubgraphIngest_Ingest_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "IngestStream",
+			Handler:       _SubgraphIngest_IngestStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SubmitJob",
+			Handler:       _SubgraphIngest_SubmitJob_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "services/ingest/proto/service.proto",
 }
@@ -0,0 +1,292 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+
+	// DefaultEventType is the CloudEvent type attribute set on every published subgraph.
+	DefaultEventType = "com.github.z5labs.megamind.subgraph"
+
+	// DefaultEventSource is the CloudEvent source attribute set on every published subgraph.
+	DefaultEventSource = "megamind/ingest"
+)
+
+// eventIDNamespace is the namespace of the name based UUIDs used as event IDs.
+var eventIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/z5labs/megamind/subgraph"))
+
+// ContentMode is the CloudEvents HTTP protocol binding content mode.
+type ContentMode int
+
+const (
+	// BinaryMode maps event attributes to ce- prefixed HTTP headers
+	// and sends the event data as the request body.
+	BinaryMode ContentMode = iota
+
+	// StructuredMode sends the entire event, attributes and data,
+	// as a single application/cloudevents+json request body.
+	StructuredMode
+)
+
+// DataEncoding is how a subgraph is encoded into the CloudEvent data.
+type DataEncoding int
+
+const (
+	// ProtobufData encodes subgraphs with the protobuf wire format.
+	ProtobufData DataEncoding = iota
+
+	// JSONData encodes subgraphs with protojson.
+	JSONData
+)
+
+// StatusError is returned when the broker responds with a non-2xx status code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("broker responded with unexpected status code: %d", e.StatusCode)
+}
+
+// CloudEvents is a Publisher which wraps each subgraph in a CloudEvent
+// and POSTs it to a broker, such as a Knative Broker ingress.
+type CloudEvents struct {
+	client     *http.Client
+	url        string
+	source     string
+	eventType  string
+	mode       ContentMode
+	encoding   DataEncoding
+	maxRetries int
+	backoff    time.Duration
+}
+
+// CloudEventsOption
+type CloudEventsOption func(*CloudEvents)
+
+// WithHTTPClient sets the client used for sending events to the broker.
+func WithHTTPClient(c *http.Client) CloudEventsOption {
+	return func(ce *CloudEvents) {
+		ce.client = c
+	}
+}
+
+// WithEventSource overrides DefaultEventSource.
+func WithEventSource(source string) CloudEventsOption {
+	return func(ce *CloudEvents) {
+		ce.source = source
+	}
+}
+
+// WithEventType overrides DefaultEventType.
+func WithEventType(typ string) CloudEventsOption {
+	return func(ce *CloudEvents) {
+		ce.eventType = typ
+	}
+}
+
+// WithContentMode sets the HTTP content mode. The default is BinaryMode.
+func WithContentMode(m ContentMode) CloudEventsOption {
+	return func(ce *CloudEvents) {
+		ce.mode = m
+	}
+}
+
+// WithDataEncoding sets the event data encoding. The default is ProtobufData.
+func WithDataEncoding(e DataEncoding) CloudEventsOption {
+	return func(ce *CloudEvents) {
+		ce.encoding = e
+	}
+}
+
+// WithRetries sets how many times an event is resent after the broker
+// responds with a 5xx status code, or cannot be reached at all. The
+// backoff is doubled after every attempt.
+func WithRetries(n int, backoff time.Duration) CloudEventsOption {
+	return func(ce *CloudEvents) {
+		ce.maxRetries = n
+		ce.backoff = backoff
+	}
+}
+
+// NewCloudEvents
+func NewCloudEvents(url string, opts ...CloudEventsOption) *CloudEvents {
+	ce := &CloudEvents{
+		client:     http.DefaultClient,
+		url:        url,
+		source:     DefaultEventSource,
+		eventType:  DefaultEventType,
+		mode:       BinaryMode,
+		encoding:   ProtobufData,
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(ce)
+	}
+	return ce
+}
+
+// Publish sends the subgraph as a CloudEvent whose ID is derived from the
+// subgraph, so a subgraph which is published again, e.g. when it's replayed
+// from the write-ahead log, is sent with the same ID and consumers can
+// deduplicate it. Subgraphs are stamped with a hybrid logical clock and
+// provenance when they're ingested, so separately ingested subgraphs
+// don't share an ID.
+func (ce *CloudEvents) Publish(ctx context.Context, g *subgraph.Subgraph) error {
+	data, contentType, err := ce.encode(g)
+	if err != nil {
+		return err
+	}
+
+	id, err := eventID(g)
+	if err != nil {
+		return err
+	}
+	ev := event{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              id,
+		Source:          ce.source,
+		Type:            ce.eventType,
+		Time:            time.Now().UTC().Format(time.RFC3339Nano),
+		DataContentType: contentType,
+	}
+
+	header := make(http.Header)
+	var body []byte
+	switch ce.mode {
+	case BinaryMode:
+		header.Set("Content-Type", contentType)
+		header.Set("ce-specversion", ev.SpecVersion)
+		header.Set("ce-id", ev.ID)
+		header.Set("ce-source", ev.Source)
+		header.Set("ce-type", ev.Type)
+		header.Set("ce-time", ev.Time)
+		body = data
+	case StructuredMode:
+		if ce.encoding == JSONData {
+			ev.Data = data
+		} else {
+			ev.DataBase64 = data
+		}
+		body, err = json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		header.Set("Content-Type", "application/cloudevents+json")
+	default:
+		return fmt.Errorf("unknown cloudevents content mode: %d", ce.mode)
+	}
+
+	backoff := ce.backoff
+	for attempt := 0; ; attempt++ {
+		err = ce.send(ctx, header, body)
+		if err == nil || attempt >= ce.maxRetries || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// eventID is a name based UUID of the deterministic encoding of the subgraph.
+func eventID(g *subgraph.Subgraph) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(g)
+	if err != nil {
+		return "", err
+	}
+	return uuid.NewSHA1(eventIDNamespace, b).String(), nil
+}
+
+func (ce *CloudEvents) encode(g *subgraph.Subgraph) ([]byte, string, error) {
+	switch ce.encoding {
+	case ProtobufData:
+		b, err := proto.Marshal(g)
+		return b, "application/protobuf", err
+	case JSONData:
+		b, err := protojson.Marshal(g)
+		return b, "application/json", err
+	default:
+		return nil, "", fmt.Errorf("unknown cloudevents data encoding: %d", ce.encoding)
+	}
+}
+
+func (ce *CloudEvents) send(ctx context.Context, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ce.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+
+	resp, err := ce.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var serr StatusError
+	if errors.As(err, &serr) {
+		return serr.StatusCode >= 500
+	}
+	return true
+}
+
+// event is the JSON format of a CloudEvent, as used in structured mode.
+type event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
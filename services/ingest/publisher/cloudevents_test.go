@@ -0,0 +1,257 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package publisher
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type receivedEvent struct {
+	header http.Header
+	body   []byte
+}
+
+func newBroker(statusCodes ...int) (*httptest.Server, <-chan receivedEvent) {
+	var calls int32
+	evCh := make(chan receivedEvent, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&calls, 1)) - 1
+
+		body, _ := io.ReadAll(r.Body)
+		evCh <- receivedEvent{header: r.Header.Clone(), body: body}
+
+		statusCode := http.StatusAccepted
+		if i < len(statusCodes) {
+			statusCode = statusCodes[i]
+		}
+		w.WriteHeader(statusCode)
+	}))
+	return srv, evCh
+}
+
+func TestCloudEvents(t *testing.T) {
+	t.Run("should send protobuf data in binary mode", func(subT *testing.T) {
+		srv, evCh := newBroker()
+		defer srv.Close()
+
+		g := newTestSubgraph("1", "Alice")
+		p := NewCloudEvents(srv.URL)
+		err := p.Publish(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		ev := <-evCh
+		if !assert.Equal(subT, "application/protobuf", ev.header.Get("Content-Type")) {
+			return
+		}
+		if !assert.Equal(subT, "1.0", ev.header.Get("ce-specversion")) {
+			return
+		}
+		if !assert.Equal(subT, DefaultEventType, ev.header.Get("ce-type")) {
+			return
+		}
+		if !assert.Equal(subT, DefaultEventSource, ev.header.Get("ce-source")) {
+			return
+		}
+		if !assert.NotEmpty(subT, ev.header.Get("ce-id")) {
+			return
+		}
+
+		var got subgraph.Subgraph
+		err = proto.Unmarshal(ev.body, &got)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(g, &got)) {
+			return
+		}
+	})
+
+	t.Run("should send json data in structured mode", func(subT *testing.T) {
+		srv, evCh := newBroker()
+		defer srv.Close()
+
+		g := newTestSubgraph("1", "Alice")
+		p := NewCloudEvents(srv.URL, WithContentMode(StructuredMode), WithDataEncoding(JSONData))
+		err := p.Publish(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		ev := <-evCh
+		if !assert.Equal(subT, "application/cloudevents+json", ev.header.Get("Content-Type")) {
+			return
+		}
+
+		var e struct {
+			SpecVersion     string          `json:"specversion"`
+			Type            string          `json:"type"`
+			DataContentType string          `json:"datacontenttype"`
+			Data            json.RawMessage `json:"data"`
+		}
+		err = json.Unmarshal(ev.body, &e)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, "1.0", e.SpecVersion) {
+			return
+		}
+		if !assert.Equal(subT, "application/json", e.DataContentType) {
+			return
+		}
+
+		var got subgraph.Subgraph
+		err = protojson.Unmarshal(e.Data, &got)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(g, &got)) {
+			return
+		}
+	})
+
+	t.Run("should base64 encode protobuf data in structured mode", func(subT *testing.T) {
+		srv, evCh := newBroker()
+		defer srv.Close()
+
+		g := newTestSubgraph("1", "Alice")
+		p := NewCloudEvents(srv.URL, WithContentMode(StructuredMode))
+		err := p.Publish(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		ev := <-evCh
+		var e struct {
+			DataBase64 string `json:"data_base64"`
+		}
+		err = json.Unmarshal(ev.body, &e)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		b, err := base64.StdEncoding.DecodeString(e.DataBase64)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		var got subgraph.Subgraph
+		err = proto.Unmarshal(b, &got)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(g, &got)) {
+			return
+		}
+	})
+
+	t.Run("should send the same subgraph with the same event id", func(subT *testing.T) {
+		srv, evCh := newBroker()
+		defer srv.Close()
+
+		p := NewCloudEvents(srv.URL)
+		for _, g := range []*subgraph.Subgraph{newTestSubgraph("1", "Alice"), newTestSubgraph("1", "Alice"), newTestSubgraph("2", "Bob")} {
+			err := p.Publish(context.Background(), g)
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+
+		first, again, other := <-evCh, <-evCh, <-evCh
+		if !assert.NotEmpty(subT, first.header.Get("ce-id")) {
+			return
+		}
+		if !assert.Equal(subT, first.header.Get("ce-id"), again.header.Get("ce-id")) {
+			return
+		}
+		if !assert.NotEqual(subT, first.header.Get("ce-id"), other.header.Get("ce-id")) {
+			return
+		}
+	})
+
+	t.Run("should retry when the broker responds with a 5xx", func(subT *testing.T) {
+		srv, evCh := newBroker(http.StatusServiceUnavailable, http.StatusInternalServerError)
+		defer srv.Close()
+
+		p := NewCloudEvents(srv.URL, WithRetries(2, time.Millisecond))
+		err := p.Publish(context.Background(), newTestSubgraph("1", "Alice"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, evCh, 3) {
+			return
+		}
+
+		// every attempt should be the same event
+		first := <-evCh
+		for i := 0; i < 2; i++ {
+			ev := <-evCh
+			if !assert.Equal(subT, first.header.Get("ce-id"), ev.header.Get("ce-id")) {
+				return
+			}
+		}
+	})
+
+	t.Run("should give up once retries are exhausted", func(subT *testing.T) {
+		srv, evCh := newBroker(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		defer srv.Close()
+
+		p := NewCloudEvents(srv.URL, WithRetries(1, time.Millisecond))
+		err := p.Publish(context.Background(), newTestSubgraph("1", "Alice"))
+
+		var serr StatusError
+		if !assert.True(subT, errors.As(err, &serr)) {
+			return
+		}
+		if !assert.Equal(subT, http.StatusBadGateway, serr.StatusCode) {
+			return
+		}
+		if !assert.Len(subT, evCh, 2) {
+			return
+		}
+	})
+
+	t.Run("should not retry when the broker responds with a 4xx", func(subT *testing.T) {
+		srv, evCh := newBroker(http.StatusBadRequest)
+		defer srv.Close()
+
+		p := NewCloudEvents(srv.URL, WithRetries(3, time.Millisecond))
+		err := p.Publish(context.Background(), newTestSubgraph("1", "Alice"))
+		if !assert.Equal(subT, StatusError{StatusCode: http.StatusBadRequest}, err) {
+			return
+		}
+		if !assert.Len(subT, evCh, 1) {
+			return
+		}
+	})
+}
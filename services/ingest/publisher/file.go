@@ -0,0 +1,74 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package publisher
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"sync"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// File is a durable Publisher which appends every published subgraph
+// to a file as a varint length-prefixed protobuf message. A subgraph
+// is only considered published once it has been synced to disk.
+type File struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFile opens, or creates, the named file for appending subgraphs to.
+func NewFile(name string) (*File, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{f: f}, nil
+}
+
+// Publish
+func (p *File) Publish(ctx context.Context, g *subgraph.Subgraph) error {
+	b, err := proto.Marshal(g)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(b))
+	n := binary.PutUvarint(buf, uint64(len(b)))
+	buf = append(buf[:n], b...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.f.Write(buf)
+	if err != nil {
+		return err
+	}
+	return p.f.Sync()
+}
+
+// Close
+func (p *File) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.f.Close()
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func readFile(name string) ([]*subgraph.Subgraph, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var gs []*subgraph.Subgraph
+	br := bufio.NewReader(f)
+	for {
+		n, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return gs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		b := make([]byte, n)
+		_, err = io.ReadFull(br, b)
+		if err != nil {
+			return nil, err
+		}
+
+		var g subgraph.Subgraph
+		err = proto.Unmarshal(b, &g)
+		if err != nil {
+			return nil, err
+		}
+		gs = append(gs, &g)
+	}
+}
+
+func TestFile(t *testing.T) {
+	t.Run("should persist published subgraphs across reopens", func(subT *testing.T) {
+		name := filepath.Join(subT.TempDir(), "subgraphs")
+
+		a := newTestSubgraph("1", "Alice")
+		b := newTestSubgraph("2", "Bob")
+		for _, g := range []*subgraph.Subgraph{a, b} {
+			p, err := NewFile(name)
+			if !assert.Nil(subT, err) {
+				return
+			}
+
+			err = p.Publish(context.Background(), g)
+			if !assert.Nil(subT, err) {
+				return
+			}
+
+			err = p.Close()
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+
+		gs, err := readFile(name)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, gs, 2) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(a, gs[0])) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(b, gs[1])) {
+			return
+		}
+	})
+
+	t.Run("should fail to publish after being closed", func(subT *testing.T) {
+		p, err := NewFile(filepath.Join(subT.TempDir(), "subgraphs"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		err = p.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		err = p.Publish(context.Background(), newTestSubgraph("1", "Alice"))
+		if !assert.NotNil(subT, err) {
+			return
+		}
+	})
+}
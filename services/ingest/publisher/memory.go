@@ -0,0 +1,59 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package publisher provides sinks for subgraphs accepted by the ingest service.
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Memory is a Publisher which keeps every published subgraph in memory.
+type Memory struct {
+	mu        sync.Mutex
+	subgraphs []*subgraph.Subgraph
+}
+
+// NewMemory
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Publish
+func (m *Memory) Publish(ctx context.Context, g *subgraph.Subgraph) error {
+	g = proto.Clone(g).(*subgraph.Subgraph)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subgraphs = append(m.subgraphs, g)
+	return nil
+}
+
+// Subgraphs returns every subgraph published so far, in the order they were published.
+func (m *Memory) Subgraphs() []*subgraph.Subgraph {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	gs := make([]*subgraph.Subgraph, len(m.subgraphs))
+	copy(gs, m.subgraphs)
+	return gs
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package publisher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestSubgraph(tuid, name string) *subgraph.Subgraph {
+	return &subgraph.Subgraph{
+		Triples: []*subgraph.Triple{
+			{
+				Subject: &subgraph.Subject{
+					Type: "Person",
+					Tuid: tuid,
+				},
+				Predicate: &subgraph.Predicate{
+					Name: "name",
+				},
+				Object: &subgraph.Object{
+					Value: &subgraph.Object_String_{
+						String_: name,
+					},
+				},
+				Facets: map[string]*subgraph.FacetValue{
+					"confidence": {Value: &subgraph.FacetValue_Float64{Float64: 0.9}},
+					"source":     {Value: &subgraph.FacetValue_String_{String_: "census"}},
+				},
+			},
+		},
+	}
+}
+
+func TestMemory(t *testing.T) {
+	t.Run("should return published subgraphs in order", func(subT *testing.T) {
+		p := NewMemory()
+
+		a := newTestSubgraph("1", "Alice")
+		b := newTestSubgraph("2", "Bob")
+		for _, g := range []*subgraph.Subgraph{a, b} {
+			err := p.Publish(context.Background(), g)
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 2) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(a, gs[0])) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(b, gs[1])) {
+			return
+		}
+	})
+
+	t.Run("should not be affected by changes to a published subgraph", func(subT *testing.T) {
+		p := NewMemory()
+
+		g := newTestSubgraph("1", "Alice")
+		err := p.Publish(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		g.Triples = nil
+
+		gs := p.Subgraphs()
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		if !assert.Len(subT, gs[0].Triples, 1) {
+			return
+		}
+	})
+}
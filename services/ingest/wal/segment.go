@@ -0,0 +1,158 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrCorrupt is returned when an entry fails its checksum or
+// a segment is missing entries.
+var ErrCorrupt = errors.New("wal: corrupt entry")
+
+const (
+	segmentExt = ".wal"
+
+	// entry header layout: sequence number (8) | payload length (4) | payload crc (4)
+	headerSize = 16
+
+	// maxPayloadSize bounds what a corrupt payload length
+	// can make readEntry allocate.
+	maxPayloadSize = 256 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func segmentName(dir string, firstSeq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", firstSeq, segmentExt))
+}
+
+// listSegments returns the first sequence number of every segment in dir, in order.
+func listSegments(dir string) ([]uint64, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segs []uint64
+	for _, de := range des {
+		name := de.Name()
+		if de.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, seq)
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i] < segs[j] })
+	return segs, nil
+}
+
+func encodeEntry(seq uint64, payload []byte) []byte {
+	b := make([]byte, headerSize+len(payload))
+	binary.LittleEndian.PutUint64(b[0:8], seq)
+	binary.LittleEndian.PutUint32(b[8:12], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(b[12:16], crc32.Checksum(payload, crcTable))
+	copy(b[headerSize:], payload)
+	return b
+}
+
+// readEntry reads the next entry from r. It returns io.EOF only when r
+// ends exactly on an entry boundary; a torn or mismatched entry is
+// reported as ErrCorrupt.
+func readEntry(r *bufio.Reader) (uint64, []byte, error) {
+	var hdr [headerSize]byte
+	_, err := io.ReadFull(r, hdr[:])
+	if err == io.EOF {
+		return 0, nil, io.EOF
+	}
+	if err == io.ErrUnexpectedEOF {
+		return 0, nil, ErrCorrupt
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	seq := binary.LittleEndian.Uint64(hdr[0:8])
+	n := binary.LittleEndian.Uint32(hdr[8:12])
+	sum := binary.LittleEndian.Uint32(hdr[12:16])
+	if n > maxPayloadSize {
+		return 0, nil, ErrCorrupt
+	}
+
+	payload := make([]byte, n)
+	_, err = io.ReadFull(r, payload)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, nil, ErrCorrupt
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if crc32.Checksum(payload, crcTable) != sum {
+		return 0, nil, ErrCorrupt
+	}
+	return seq, payload, nil
+}
+
+// scanSegment validates every entry in a segment and returns the sequence
+// number following its last entry, along with the size of the valid prefix.
+func scanSegment(name string, firstSeq uint64) (nextSeq uint64, size int64, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	nextSeq = firstSeq
+	br := bufio.NewReader(f)
+	for {
+		seq, payload, err := readEntry(br)
+		if err == io.EOF {
+			return nextSeq, size, nil
+		}
+		if err != nil {
+			return nextSeq, size, err
+		}
+		if seq != nextSeq {
+			return nextSeq, size, ErrCorrupt
+		}
+		nextSeq += 1
+		size += int64(headerSize + len(payload))
+	}
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
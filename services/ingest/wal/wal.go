@@ -0,0 +1,518 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package wal implements a segmented, on-disk write-ahead log of subgraphs.
+//
+// Every appended subgraph is assigned a monotonically increasing sequence
+// number. Once a subgraph has been published it is acknowledged with Ack,
+// and segments which only contain acknowledged entries are removed. Any
+// entries still unacknowledged when the log is reopened are handed back
+// by Replay, so publishing through the log is at-least-once.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrClosed is returned when using a Log after it has been closed.
+	ErrClosed = errors.New("wal: closed")
+
+	// ErrFailed is returned by Append once a failed write could not be
+	// undone, since any entry appended after it could be lost.
+	ErrFailed = errors.New("wal: failed")
+
+	// ErrTooLarge is returned when appending a subgraph which
+	// encodes to more than the maximum entry size.
+	ErrTooLarge = errors.New("wal: entry too large")
+)
+
+const checkpointName = "checkpoint"
+
+// SyncPolicy controls when appended entries are fsynced to disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs the active segment before Append returns.
+	SyncAlways SyncPolicy = iota
+
+	// SyncInterval fsyncs the active segment periodically.
+	SyncInterval
+
+	// SyncNever leaves flushing entries to disk up to the operating system.
+	SyncNever
+)
+
+// Option
+type Option func(*Log)
+
+// WithSyncPolicy sets when entries are fsynced. The default is SyncAlways.
+func WithSyncPolicy(p SyncPolicy) Option {
+	return func(l *Log) {
+		l.policy = p
+	}
+}
+
+// WithSyncInterval sets how often entries are fsynced when using SyncInterval.
+func WithSyncInterval(d time.Duration) Option {
+	return func(l *Log) {
+		l.interval = d
+	}
+}
+
+// WithMaxSegmentSize sets the size, in bytes, after which a new segment is started.
+func WithMaxSegmentSize(n int64) Option {
+	return func(l *Log) {
+		l.maxSegmentSize = n
+	}
+}
+
+// segmentFile is the active segment. It is an interface
+// so that tests can make writes to it fail.
+type segmentFile interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// Log
+type Log struct {
+	dir            string
+	policy         SyncPolicy
+	interval       time.Duration
+	maxSegmentSize int64
+
+	mu         sync.Mutex
+	segments   []uint64
+	active     segmentFile
+	activeSize int64
+	err        error
+	nextSeq    uint64
+	committed  uint64
+	acked      map[uint64]struct{}
+	dirty      bool
+	closed     bool
+
+	stopOnce sync.Once
+	stopSync chan struct{}
+	syncDone chan struct{}
+}
+
+// Open opens the log stored in dir, creating it if it does not exist yet.
+// A torn entry at the end of the last segment, e.g. from a crash during
+// Append, is truncated away.
+func Open(dir string, opts ...Option) (*Log, error) {
+	l := &Log{
+		dir:            dir,
+		policy:         SyncAlways,
+		interval:       time.Second,
+		maxSegmentSize: 64 << 20,
+		acked:          make(map[uint64]struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	l.committed, err = readCheckpoint(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l.segments, err = listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l.nextSeq = l.committed
+	for i, first := range l.segments {
+		name := segmentName(dir, first)
+		next, size, err := scanSegment(name, first)
+		last := i == len(l.segments)-1
+		if errors.Is(err, ErrCorrupt) && last {
+			err = os.Truncate(name, size)
+		}
+		if err != nil {
+			return nil, err
+		}
+		l.nextSeq = next
+		if last {
+			l.activeSize = size
+		}
+	}
+	if l.nextSeq < l.committed {
+		l.nextSeq = l.committed
+	}
+
+	err = l.truncate()
+	if err != nil {
+		return nil, err
+	}
+
+	if n := len(l.segments); n > 0 {
+		l.active, err = os.OpenFile(segmentName(dir, l.segments[n-1]), os.O_WRONLY|os.O_APPEND, 0o644)
+	} else {
+		err = l.rotate()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if l.policy == SyncInterval {
+		l.stopSync = make(chan struct{})
+		l.syncDone = make(chan struct{})
+		go l.syncLoop()
+	}
+	return l, nil
+}
+
+// Append durably records the subgraph, according to the sync policy,
+// and returns its sequence number.
+func (l *Log) Append(g *subgraph.Subgraph) (uint64, error) {
+	payload, err := proto.Marshal(g)
+	if err != nil {
+		return 0, err
+	}
+	if len(payload) > maxPayloadSize {
+		return 0, ErrTooLarge
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, ErrClosed
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	if l.activeSize > 0 && l.activeSize >= l.maxSegmentSize {
+		err = l.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	seq := l.nextSeq
+	b := encodeEntry(seq, payload)
+	_, err = l.active.Write(b)
+	if err != nil {
+		// A torn entry must not be left in the segment, otherwise every
+		// entry appended after it is discarded when the log is reopened.
+		terr := l.active.Truncate(l.activeSize)
+		if terr != nil {
+			l.err = fmt.Errorf("%w: %v", ErrFailed, terr)
+		}
+		return 0, err
+	}
+	l.activeSize += int64(len(b))
+	if l.policy == SyncAlways {
+		// Whether the entry reached the disk is unknown after a failed
+		// fsync, so it can neither be kept nor safely written again.
+		err = l.active.Sync()
+		if err != nil {
+			l.err = fmt.Errorf("%w: %v", ErrFailed, err)
+			return 0, err
+		}
+	} else {
+		l.dirty = true
+	}
+
+	l.nextSeq += 1
+	return seq, nil
+}
+
+// Ack marks the entry as published. Entries may be acknowledged in any
+// order, but segments are only removed once every entry in them, and
+// every entry before them, has been acknowledged.
+func (l *Log) Ack(seq uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	if seq < l.committed || seq >= l.nextSeq {
+		return nil
+	}
+
+	l.acked[seq] = struct{}{}
+	for {
+		if _, ok := l.acked[l.committed]; !ok {
+			break
+		}
+		delete(l.acked, l.committed)
+		l.committed += 1
+	}
+	return l.truncate()
+}
+
+// Replay calls fn, in order, for every entry which has not been acknowledged
+// at the time Replay is called. Replay stops at the first error returned by fn.
+// Entries appended while replaying are not replayed.
+func (l *Log) Replay(fn func(seq uint64, g *subgraph.Subgraph) error) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrClosed
+	}
+	segs := append([]uint64(nil), l.segments...)
+	committed, end := l.committed, l.nextSeq
+
+	// The active segment is only read up to its last complete entry,
+	// since a concurrent Append may be writing the next one.
+	activeSeg, activeSize := segs[len(segs)-1], l.activeSize
+	acked := make(map[uint64]struct{}, len(l.acked))
+	for seq := range l.acked {
+		acked[seq] = struct{}{}
+	}
+	l.mu.Unlock()
+
+	for _, first := range segs {
+		if first >= end {
+			return nil
+		}
+
+		limit := int64(-1)
+		if first == activeSeg {
+			limit = activeSize
+		}
+		done, err := replaySegment(segmentName(l.dir, first), limit, func(seq uint64, payload []byte) (bool, error) {
+			if seq >= end {
+				return true, nil
+			}
+			if _, ok := acked[seq]; ok || seq < committed {
+				return false, nil
+			}
+
+			var g subgraph.Subgraph
+			err := proto.Unmarshal(payload, &g)
+			if err != nil {
+				return true, err
+			}
+			return false, fn(seq, &g)
+		})
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Close syncs the active segment and records which entries have been acknowledged.
+func (l *Log) Close() error {
+	if l.stopSync != nil {
+		l.stopOnce.Do(func() {
+			close(l.stopSync)
+			<-l.syncDone
+		})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	l.closed = true
+
+	err := l.active.Sync()
+	if err != nil {
+		l.active.Close()
+		return err
+	}
+	err = l.active.Close()
+	if err != nil {
+		return err
+	}
+	return writeCheckpoint(l.dir, l.committed)
+}
+
+func (l *Log) syncLoop() {
+	defer close(l.syncDone)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stopSync:
+			return
+		case <-ticker.C:
+		}
+
+		l.mu.Lock()
+		if l.dirty && !l.closed {
+			// a failed sync will be retried on the next tick or by Close
+			if l.active.Sync() == nil {
+				l.dirty = false
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// rotate closes the active segment, if any, and starts a new one.
+func (l *Log) rotate() error {
+	if l.active != nil {
+		err := l.active.Sync()
+		if err != nil {
+			return err
+		}
+		err = l.active.Close()
+		if err != nil {
+			return err
+		}
+		l.dirty = false
+	}
+
+	f, err := os.OpenFile(segmentName(l.dir, l.nextSeq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	err = syncDir(l.dir)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.active = f
+	l.activeSize = 0
+	l.segments = append(l.segments, l.nextSeq)
+	return nil
+}
+
+// truncate removes every segment which only contains acknowledged entries.
+// The active segment is never removed.
+func (l *Log) truncate() error {
+	n := 0
+	for n+1 < len(l.segments) && l.segments[n+1] <= l.committed {
+		n += 1
+	}
+	if n == 0 {
+		return nil
+	}
+
+	// the checkpoint must cover the removed segments before they are
+	// deleted, otherwise a crash could make earlier entries look unpublished
+	err := writeCheckpoint(l.dir, l.committed)
+	if err != nil {
+		return err
+	}
+	for _, first := range l.segments[:n] {
+		err = os.Remove(segmentName(l.dir, first))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	l.segments = l.segments[n:]
+	return nil
+}
+
+// replaySegment calls fn for every entry in the first limit bytes of the
+// segment, or in all of it if limit is negative, until fn is done.
+func replaySegment(name string, limit int64, fn func(uint64, []byte) (bool, error)) (bool, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		// removed by a concurrent Ack, so every entry in it has been published
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	br := bufio.NewReader(r)
+	for {
+		seq, payload, err := readEntry(br)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		done, err := fn(seq, payload)
+		if done || err != nil {
+			return done, err
+		}
+	}
+}
+
+func readCheckpoint(dir string) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(dir, checkpointName))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 8 {
+		return 0, ErrCorrupt
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func writeCheckpoint(dir string, committed uint64) error {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], committed)
+
+	tmp := filepath.Join(dir, checkpointName+".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b[:])
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, filepath.Join(dir, checkpointName))
+	if err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
@@ -0,0 +1,423 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSubgraph(tuid string) *subgraph.Subgraph {
+	return &subgraph.Subgraph{
+		Triples: []*subgraph.Triple{
+			{
+				Subject: &subgraph.Subject{
+					Type: "Person",
+					Tuid: tuid,
+				},
+				Predicate: &subgraph.Predicate{
+					Name: "name",
+				},
+				Object: &subgraph.Object{
+					Value: &subgraph.Object_String_{
+						String_: "Bob",
+					},
+				},
+			},
+		},
+	}
+}
+
+func appendN(l *Log, n int) error {
+	for i := 0; i < n; i++ {
+		_, err := l.Append(newTestSubgraph(strconv.Itoa(i)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func replayed(l *Log) ([]uint64, []string, error) {
+	var seqs []uint64
+	var tuids []string
+	err := l.Replay(func(seq uint64, g *subgraph.Subgraph) error {
+		seqs = append(seqs, seq)
+		tuids = append(tuids, g.Triples[0].Subject.Tuid)
+		return nil
+	})
+	return seqs, tuids, err
+}
+
+// tornFile writes half of the next entry to the segment and then fails.
+type tornFile struct {
+	*os.File
+	truncateErr error
+}
+
+var errTorn = errors.New("torn write")
+
+func (f *tornFile) Write(b []byte) (int, error) {
+	n, err := f.File.Write(b[:len(b)/2])
+	if err != nil {
+		return n, err
+	}
+	return n, errTorn
+}
+
+func (f *tornFile) Truncate(size int64) error {
+	if f.truncateErr != nil {
+		return f.truncateErr
+	}
+	return f.File.Truncate(size)
+}
+
+func TestLog(t *testing.T) {
+	t.Run("should replay every unacknowledged entry", func(subT *testing.T) {
+		l, err := Open(subT.TempDir())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer l.Close()
+
+		err = appendN(l, 4)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = l.Ack(2)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = l.Ack(0)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		seqs, tuids, err := replayed(l)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []uint64{1, 3}, seqs) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1", "3"}, tuids) {
+			return
+		}
+	})
+
+	t.Run("should replay unacknowledged entries after being reopened", func(subT *testing.T) {
+		dir := subT.TempDir()
+		l, err := Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		err = appendN(l, 3)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = l.Ack(0)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = l.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		l, err = Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer l.Close()
+
+		seqs, _, err := replayed(l)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []uint64{1, 2}, seqs) {
+			return
+		}
+
+		seq, err := l.Append(newTestSubgraph("3"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, uint64(3), seq) {
+			return
+		}
+	})
+
+	t.Run("should remove segments once all their entries are acknowledged", func(subT *testing.T) {
+		dir := subT.TempDir()
+		l, err := Open(dir, WithMaxSegmentSize(1))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer l.Close()
+
+		err = appendN(l, 5)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		segs, err := listSegments(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, segs, 5) {
+			return
+		}
+
+		for seq := uint64(0); seq < 5; seq++ {
+			err = l.Ack(seq)
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+
+		segs, err = listSegments(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []uint64{4}, segs) {
+			return
+		}
+	})
+
+	t.Run("should truncate a torn entry at the end of the last segment", func(subT *testing.T) {
+		dir := subT.TempDir()
+		l, err := Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = appendN(l, 2)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = l.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		f, err := os.OpenFile(segmentName(dir, 0), os.O_WRONLY|os.O_APPEND, 0o644)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		_, err = f.Write(encodeEntry(2, []byte("partial"))[:headerSize+3])
+		if !assert.Nil(subT, err) {
+			return
+		}
+		f.Close()
+
+		l, err = Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer l.Close()
+
+		seq, err := l.Append(newTestSubgraph("2"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, uint64(2), seq) {
+			return
+		}
+
+		seqs, _, err := replayed(l)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []uint64{0, 1, 2}, seqs) {
+			return
+		}
+	})
+
+	t.Run("should not lose entries appended after a failed write", func(subT *testing.T) {
+		dir := subT.TempDir()
+		l, err := Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = appendN(l, 1)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		active := l.active.(*os.File)
+		l.active = &tornFile{File: active}
+		_, err = l.Append(newTestSubgraph("torn"))
+		if !assert.Equal(subT, errTorn, err) {
+			return
+		}
+		l.active = active
+
+		seq, err := l.Append(newTestSubgraph("1"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, uint64(1), seq) {
+			return
+		}
+		err = l.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		l, err = Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer l.Close()
+
+		seqs, tuids, err := replayed(l)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []uint64{0, 1}, seqs) {
+			return
+		}
+		if !assert.Equal(subT, []string{"0", "1"}, tuids) {
+			return
+		}
+	})
+
+	t.Run("should reject appends once a failed write could not be undone", func(subT *testing.T) {
+		l, err := Open(subT.TempDir())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer l.Close()
+
+		active := l.active.(*os.File)
+		l.active = &tornFile{File: active, truncateErr: errors.New("read-only file system")}
+		_, err = l.Append(newTestSubgraph("torn"))
+		if !assert.Equal(subT, errTorn, err) {
+			return
+		}
+		l.active = active
+
+		_, err = l.Append(newTestSubgraph("1"))
+		if !assert.ErrorIs(subT, err, ErrFailed) {
+			return
+		}
+	})
+
+	t.Run("should only replay complete entries while an entry is being appended", func(subT *testing.T) {
+		dir := subT.TempDir()
+		l, err := Open(dir)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer l.Close()
+
+		err = appendN(l, 2)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		// Simulate an Append which has only written part of its entry so far.
+		f, err := os.OpenFile(segmentName(dir, 0), os.O_WRONLY|os.O_APPEND, 0o644)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		_, err = f.Write(encodeEntry(2, []byte("partial"))[:headerSize+3])
+		f.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		seqs, _, err := replayed(l)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []uint64{0, 1}, seqs) {
+			return
+		}
+	})
+
+	t.Run("should not trust the payload length of a corrupt entry", func(subT *testing.T) {
+		b := encodeEntry(0, []byte("payload"))
+		b[8], b[9], b[10], b[11] = 0xff, 0xff, 0xff, 0xff
+
+		_, _, err := readEntry(bufio.NewReader(bytes.NewReader(b)))
+		if !assert.Equal(subT, ErrCorrupt, err) {
+			return
+		}
+	})
+
+	t.Run("should fail to open if an earlier segment is corrupt", func(subT *testing.T) {
+		dir := subT.TempDir()
+		l, err := Open(dir, WithMaxSegmentSize(1))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = appendN(l, 2)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = l.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		name := segmentName(dir, 0)
+		b, err := os.ReadFile(name)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		b[len(b)-1] ^= 0xff
+		err = os.WriteFile(name, b, 0o644)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		_, err = Open(dir)
+		if !assert.ErrorIs(subT, err, ErrCorrupt) {
+			return
+		}
+	})
+
+	t.Run("should return ErrClosed after being closed", func(subT *testing.T) {
+		l, err := Open(filepath.Join(subT.TempDir(), "wal"), WithSyncPolicy(SyncInterval))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = l.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		_, err = l.Append(newTestSubgraph("0"))
+		if !assert.Equal(subT, ErrClosed, err) {
+			return
+		}
+
+		err = l.Close()
+		if !assert.Equal(subT, ErrClosed, err) {
+			return
+		}
+	})
+}
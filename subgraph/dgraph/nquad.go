@@ -0,0 +1,131 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dgraph
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/rdf"
+)
+
+var (
+	// ErrInvalidType is returned for a subject type which cannot be used as a Dgraph type name.
+	ErrInvalidType = errors.New("dgraph: subject type must only contain letters, digits, underscores and dots")
+
+	// ErrInvalidPredicate is returned for a predicate name which cannot be used as a Dgraph predicate.
+	ErrInvalidPredicate = errors.New("dgraph: predicate name must not contain whitespace or any of <>\"{}|^`\\")
+
+	// ErrInvalidFacet is returned for a facet key which cannot be used as a Dgraph facet.
+	ErrInvalidFacet = errors.New("dgraph: facet key must only contain letters, digits and underscores")
+)
+
+var (
+	typeName      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	predicateName = regexp.MustCompile("^[^\\s<>\"{}|^`\\\\]+$")
+	facetKey      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// literal encodes the object as an N-Quad literal using the
+// types Dgraph understands. Subjects are not literals.
+func literal(o *subgraph.Object) (string, error) {
+	switch x := o.GetValue().(type) {
+	case *subgraph.Object_String_:
+		return rdf.Quote(x.String_), nil
+	case *subgraph.Object_Int64:
+		return typed(strconv.FormatInt(x.Int64, 10), "xs:int"), nil
+	case *subgraph.Object_Float64:
+		return typed(formatFloat(x.Float64), "xs:float"), nil
+	case *subgraph.Object_Bool:
+		return typed(strconv.FormatBool(x.Bool), "xs:boolean"), nil
+	case *subgraph.Object_Timestamp:
+		return typed(x.Timestamp.AsTime().Format(time.RFC3339Nano), "xs:dateTime"), nil
+	case *subgraph.Object_Bytes:
+		return typed(base64.StdEncoding.EncodeToString(x.Bytes), "xs:base64Binary"), nil
+	case *subgraph.Object_Geo:
+		geojson := fmt.Sprintf(
+			`{"type":"Point","coordinates":[%s,%s]}`,
+			formatFloat(x.Geo.GetLongitude()),
+			formatFloat(x.Geo.GetLatitude()),
+		)
+		return typed(geojson, "geo:geojson"), nil
+	case *subgraph.Object_LangString:
+		return rdf.Quote(x.LangString.GetValue()) + "@" + x.LangString.GetLang(), nil
+	default:
+		return "", fmt.Errorf("dgraph: unsupported object: %T", x)
+	}
+}
+
+// facets encodes the facets as an N-Quad facet list, e.g. (confidence=0.5, verified=true),
+// or returns an empty string if there are none.
+func facets(fs map[string]*subgraph.FacetValue) (string, error) {
+	if len(fs) == 0 {
+		return "", nil
+	}
+
+	keys := make([]string, 0, len(fs))
+	for key := range fs {
+		if !facetKey.MatchString(key) {
+			return "", ErrInvalidFacet
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		var v string
+		switch x := fs[key].GetValue().(type) {
+		case *subgraph.FacetValue_String_:
+			v = rdf.Quote(x.String_)
+		case *subgraph.FacetValue_Int64:
+			v = strconv.FormatInt(x.Int64, 10)
+		case *subgraph.FacetValue_Float64:
+			v = formatFloat(x.Float64)
+		case *subgraph.FacetValue_Bool:
+			v = strconv.FormatBool(x.Bool)
+		case *subgraph.FacetValue_Timestamp:
+			v = x.Timestamp.AsTime().Format(time.RFC3339Nano)
+		default:
+			return "", fmt.Errorf("dgraph: unsupported facet value: %T", x)
+		}
+		pairs[i] = key + "=" + v
+	}
+	return "(" + strings.Join(pairs, ", ") + ")", nil
+}
+
+func typed(v, typ string) string {
+	return rdf.Quote(v) + "^^<" + typ + ">"
+}
+
+// formatFloat always includes a decimal point so that
+// Dgraph does not mistake whole numbers for integers.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !math.IsInf(f, 0) && !math.IsNaN(f) && !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dgraph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestLiteral(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Object   *subgraph.Object
+		Expected string
+	}{
+		{
+			Name:     "string",
+			Object:   &subgraph.Object{Value: &subgraph.Object_String_{String_: "Bob \"The\" Builder\n\\"}},
+			Expected: `"Bob \"The\" Builder\n\\"`,
+		},
+		{
+			Name:     "int64",
+			Object:   &subgraph.Object{Value: &subgraph.Object_Int64{Int64: -42}},
+			Expected: `"-42"^^<xs:int>`,
+		},
+		{
+			Name:     "float64",
+			Object:   &subgraph.Object{Value: &subgraph.Object_Float64{Float64: 2}},
+			Expected: `"2.0"^^<xs:float>`,
+		},
+		{
+			Name:     "bool",
+			Object:   &subgraph.Object{Value: &subgraph.Object_Bool{Bool: true}},
+			Expected: `"true"^^<xs:boolean>`,
+		},
+		{
+			Name:     "timestamp",
+			Object:   &subgraph.Object{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.New(time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC))}},
+			Expected: `"2022-09-01T12:00:00Z"^^<xs:dateTime>`,
+		},
+		{
+			Name:     "bytes",
+			Object:   &subgraph.Object{Value: &subgraph.Object_Bytes{Bytes: []byte("megamind")}},
+			Expected: `"bWVnYW1pbmQ="^^<xs:base64Binary>`,
+		},
+		{
+			Name:     "geo",
+			Object:   &subgraph.Object{Value: &subgraph.Object_Geo{Geo: &subgraph.GeoPoint{Latitude: 51.5, Longitude: -0.12}}},
+			Expected: `"{\"type\":\"Point\",\"coordinates\":[-0.12,51.5]}"^^<geo:geojson>`,
+		},
+		{
+			Name:     "lang string",
+			Object:   &subgraph.Object{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Bonjour", Lang: "fr"}}},
+			Expected: `"Bonjour"@fr`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run("should encode a "+tc.Name, func(subT *testing.T) {
+			lit, err := literal(tc.Object)
+			if !assert.Nil(subT, err) {
+				return
+			}
+			if !assert.Equal(subT, tc.Expected, lit) {
+				return
+			}
+		})
+	}
+}
+
+func TestFacets(t *testing.T) {
+	t.Run("should encode facets sorted by key", func(subT *testing.T) {
+		fs, err := facets(map[string]*subgraph.FacetValue{
+			"verified":   {Value: &subgraph.FacetValue_Bool{Bool: true}},
+			"confidence": {Value: &subgraph.FacetValue_Float64{Float64: 1}},
+			"source":     {Value: &subgraph.FacetValue_String_{String_: "wiki"}},
+			"since":      {Value: &subgraph.FacetValue_Timestamp{Timestamp: timestamppb.New(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))}},
+			"rank":       {Value: &subgraph.FacetValue_Int64{Int64: 3}},
+		})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, `(confidence=1.0, rank=3, since=2020-01-01T00:00:00Z, source="wiki", verified=true)`, fs) {
+			return
+		}
+	})
+
+	t.Run("should reject an invalid facet key", func(subT *testing.T) {
+		_, err := facets(map[string]*subgraph.FacetValue{
+			"bad key": {Value: &subgraph.FacetValue_Bool{Bool: true}},
+		})
+		if !assert.ErrorIs(subT, err, ErrInvalidFacet) {
+			return
+		}
+	})
+}
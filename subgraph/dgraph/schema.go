@@ -0,0 +1,325 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dgraph
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+)
+
+// SchemaOption
+type SchemaOption func(*Schema)
+
+// WithCardinalities uses the schema to decide which predicates hold a
+// single value. Otherwise, every predicate is inferred to be a list,
+// the same way the merge package treats undeclared predicates as sets.
+func WithCardinalities(s *schema.Schema) SchemaOption {
+	return func(sc *Schema) {
+		sc.cardinalities = s
+	}
+}
+
+// WithIndexes indexes every scalar predicate with its default tokenizer.
+func WithIndexes() SchemaOption {
+	return func(sc *Schema) {
+		sc.index = true
+	}
+}
+
+// WithReverseEdges adds reverse edges to every predicate which points to subjects.
+func WithReverseEdges() SchemaOption {
+	return func(sc *Schema) {
+		sc.reverse = true
+	}
+}
+
+// valueKind is the kind of value seen for a predicate.
+type valueKind int
+
+const (
+	uidKind valueKind = iota
+	stringKind
+	intKind
+	floatKind
+	boolKind
+	datetimeKind
+	bytesKind
+	geoKind
+	langKind
+)
+
+type predicateInfo struct {
+	kinds map[valueKind]struct{}
+
+	// many is set once any type which has the predicate
+	// has not declared it with a cardinality of one.
+	many bool
+}
+
+// Schema is a Dgraph schema inferred from the triples added to it.
+// A Schema is not safe for concurrent use.
+type Schema struct {
+	cardinalities *schema.Schema
+	index         bool
+	reverse       bool
+
+	predicates map[string]*predicateInfo
+	types      map[string]map[string]struct{}
+}
+
+// NewSchema returns an empty schema.
+func NewSchema(opts ...SchemaOption) *Schema {
+	s := &Schema{
+		predicates: make(map[string]*predicateInfo),
+		types:      make(map[string]map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add infers the type of the triples predicate from its object and
+// records the predicate as a member of the subjects type. Only assertions
+// are used since retractions and subject deletions may not have a value.
+func (s *Schema) Add(t *subgraph.Triple) error {
+	if t == nil {
+		return ErrMissingTriple
+	}
+	if t.Operation != subgraph.Operation_ASSERT {
+		return nil
+	}
+
+	typ := t.GetSubject().GetType()
+	if !typeName.MatchString(typ) {
+		return ErrInvalidType
+	}
+	name := t.GetPredicate().GetName()
+	if !predicateName.MatchString(name) {
+		return ErrInvalidPredicate
+	}
+
+	kind, ok := kindOf(t.Object)
+	if !ok {
+		return nil
+	}
+	if x, ok := t.Object.Value.(*subgraph.Object_Subject); ok {
+		err := s.addType(x.Subject.GetType())
+		if err != nil {
+			return err
+		}
+	}
+
+	pred, ok := s.predicates[name]
+	if !ok {
+		pred = &predicateInfo{kinds: make(map[valueKind]struct{})}
+		s.predicates[name] = pred
+	}
+	pred.kinds[kind] = struct{}{}
+	if !s.one(typ, name) {
+		pred.many = true
+	}
+
+	err := s.addType(typ)
+	if err != nil {
+		return err
+	}
+	s.types[typ][name] = struct{}{}
+	return nil
+}
+
+func (s *Schema) addType(typ string) error {
+	if !typeName.MatchString(typ) {
+		return ErrInvalidType
+	}
+	if _, ok := s.types[typ]; !ok {
+		s.types[typ] = map[string]struct{}{"tuid": {}}
+	}
+	return nil
+}
+
+func (s *Schema) one(typ, name string) bool {
+	if s.cardinalities == nil {
+		return false
+	}
+	p, ok := s.cardinalities.Predicate(typ, name)
+	return ok && p.Cardinality == schema.One
+}
+
+// Conflicts returns every predicate which was seen with values that cannot
+// be stored as the same Dgraph type, e.g. both a subject and a string.
+// These predicates are given the default type.
+func (s *Schema) Conflicts() []string {
+	var names []string
+	for name, pred := range s.predicates {
+		if _, ok := pred.scalarType(); !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scalarType resolves every kind of value seen for the
+// predicate into a single Dgraph type, if possible.
+func (p *predicateInfo) scalarType() (string, bool) {
+	has := func(kinds ...valueKind) bool {
+		if len(p.kinds) != len(kinds) {
+			return false
+		}
+		for _, kind := range kinds {
+			if _, ok := p.kinds[kind]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case len(p.kinds) == 1:
+		for kind := range p.kinds {
+			return dgraphTypes[kind], true
+		}
+	case has(stringKind, langKind):
+		return "string", true
+	case has(intKind, floatKind):
+		return "float", true
+	}
+	return "default", false
+}
+
+// dgraphTypes maps each kind of value to the Dgraph type it is stored as.
+// Dgraph has no binary type so bytes are stored untouched with the default type.
+var dgraphTypes = map[valueKind]string{
+	uidKind:      "uid",
+	stringKind:   "string",
+	intKind:      "int",
+	floatKind:    "float",
+	boolKind:     "bool",
+	datetimeKind: "datetime",
+	bytesKind:    "default",
+	geoKind:      "geo",
+	langKind:     "string",
+}
+
+// tokenizers are the default index tokenizers for each Dgraph type.
+var tokenizers = map[string]string{
+	"string":   "exact",
+	"int":      "int",
+	"float":    "float",
+	"bool":     "bool",
+	"datetime": "year",
+	"geo":      "geo",
+}
+
+// String returns the schema in the Dgraph schema language.
+func (s *Schema) String() string {
+	var sb strings.Builder
+
+	lines := []string{"<tuid>: string @index(exact) @upsert ."}
+	for name, pred := range s.predicates {
+		if name == "tuid" {
+			continue
+		}
+		lines = append(lines, pred.line(name, s.index, s.reverse))
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+
+	typs := make([]string, 0, len(s.types))
+	for typ := range s.types {
+		typs = append(typs, typ)
+	}
+	sort.Strings(typs)
+
+	for _, typ := range typs {
+		names := make([]string, 0, len(s.types[typ]))
+		for name := range s.types[typ] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		sb.WriteString("\ntype " + typ + " {\n")
+		for _, name := range names {
+			sb.WriteString("\t" + name + "\n")
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+func (p *predicateInfo) line(name string, index, reverse bool) string {
+	typ, _ := p.scalarType()
+	_, lang := p.kinds[langKind]
+
+	// Dgraph does not allow language tags on lists.
+	if p.many && !lang {
+		typ = "[" + typ + "]"
+	}
+
+	directives := []string{"<" + name + ">:", typ}
+	if tokenizer, ok := tokenizers[strings.Trim(typ, "[]")]; ok && index {
+		directives = append(directives, "@index("+tokenizer+")")
+	}
+	if strings.Trim(typ, "[]") == "uid" && reverse {
+		directives = append(directives, "@reverse")
+	}
+	if lang {
+		directives = append(directives, "@lang")
+	}
+	return strings.Join(directives, " ") + " ."
+}
+
+// Alter applies the schema to the Dgraph cluster.
+func (s *Schema) Alter(ctx context.Context, dg *dgo.Dgraph) error {
+	return dg.Alter(ctx, &api.Operation{Schema: s.String()})
+}
+
+func kindOf(o *subgraph.Object) (valueKind, bool) {
+	switch o.GetValue().(type) {
+	case *subgraph.Object_Subject:
+		return uidKind, true
+	case *subgraph.Object_String_:
+		return stringKind, true
+	case *subgraph.Object_Int64:
+		return intKind, true
+	case *subgraph.Object_Float64:
+		return floatKind, true
+	case *subgraph.Object_Bool:
+		return boolKind, true
+	case *subgraph.Object_Timestamp:
+		return datetimeKind, true
+	case *subgraph.Object_Bytes:
+		return bytesKind, true
+	case *subgraph.Object_Geo:
+		return geoKind, true
+	case *subgraph.Object_LangString:
+		return langKind, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dgraph
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestTriples() []*subgraph.Triple {
+	person := func(tuid string) *subgraph.Subject {
+		return &subgraph.Subject{Type: "Person", Tuid: tuid}
+	}
+	triple := func(subj *subgraph.Subject, pred string, obj *subgraph.Object) *subgraph.Triple {
+		return &subgraph.Triple{
+			Subject:   subj,
+			Predicate: &subgraph.Predicate{Name: pred},
+			Object:    obj,
+		}
+	}
+
+	return []*subgraph.Triple{
+		triple(person("1"), "name", &subgraph.Object{Value: &subgraph.Object_String_{String_: "Bob"}}),
+		triple(person("1"), "age", &subgraph.Object{Value: &subgraph.Object_Int64{Int64: 42}}),
+		triple(person("1"), "height", &subgraph.Object{Value: &subgraph.Object_Int64{Int64: 2}}),
+		triple(person("2"), "height", &subgraph.Object{Value: &subgraph.Object_Float64{Float64: 1.8}}),
+		triple(person("1"), "born", &subgraph.Object{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.New(time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC))}}),
+		triple(person("1"), "greeting", &subgraph.Object{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Bonjour", Lang: "fr"}}}),
+		triple(person("1"), "worksAt", &subgraph.Object{Value: &subgraph.Object_Subject{Subject: &subgraph.Subject{Type: "Company", Tuid: "1"}}}),
+		triple(&subgraph.Subject{Type: "Company", Tuid: "1"}, "location", &subgraph.Object{Value: &subgraph.Object_Geo{Geo: &subgraph.GeoPoint{Latitude: 51.5, Longitude: -0.12}}}),
+		{Subject: person("2"), Predicate: &subgraph.Predicate{Name: "retracted"}, Operation: subgraph.Operation_RETRACT},
+	}
+}
+
+func inferSchema(triples []*subgraph.Triple, opts ...SchemaOption) (*Schema, error) {
+	s := NewSchema(opts...)
+	for _, t := range triples {
+		err := s.Add(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func TestSchema_String(t *testing.T) {
+	t.Run("should infer a list type for every predicate by default", func(subT *testing.T) {
+		s, err := inferSchema(newTestTriples())
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		expected := strings.Join([]string{
+			`<age>: [int] .`,
+			`<born>: [datetime] .`,
+			`<greeting>: string @lang .`,
+			`<height>: [float] .`,
+			`<location>: [geo] .`,
+			`<name>: [string] .`,
+			`<tuid>: string @index(exact) @upsert .`,
+			`<worksAt>: [uid] .`,
+			``,
+			`type Company {`,
+			"\tlocation",
+			"\ttuid",
+			`}`,
+			``,
+			`type Person {`,
+			"\tage",
+			"\tborn",
+			"\tgreeting",
+			"\theight",
+			"\tname",
+			"\ttuid",
+			"\tworksAt",
+			`}`,
+			``,
+		}, "\n")
+		if !assert.Equal(subT, expected, s.String()) {
+			return
+		}
+	})
+
+	t.Run("should use declared cardinalities, indexes and reverse edges", func(subT *testing.T) {
+		sc, err := schema.Parse([]byte(`
+types:
+  Person:
+    predicates:
+      name:
+        kind: string
+        cardinality: one
+      worksAt:
+        kind: subject
+        type: Company
+        cardinality: one
+  Company:
+    predicates:
+      location:
+        kind: geo
+`))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		s, err := inferSchema(newTestTriples(), WithCardinalities(sc), WithIndexes(), WithReverseEdges())
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		out := s.String()
+		for _, line := range []string{
+			`<age>: [int] @index(int) .`,
+			`<greeting>: string @index(exact) @lang .`,
+			`<location>: [geo] @index(geo) .`,
+			`<name>: string @index(exact) .`,
+			`<worksAt>: uid @reverse .`,
+		} {
+			if !assert.Contains(subT, out, line+"\n") {
+				return
+			}
+		}
+	})
+
+	t.Run("should report predicates with conflicting types", func(subT *testing.T) {
+		triples := append(newTestTriples(), &subgraph.Triple{
+			Subject:   &subgraph.Subject{Type: "Person", Tuid: "3"},
+			Predicate: &subgraph.Predicate{Name: "worksAt"},
+			Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Acme"}},
+		})
+		s, err := inferSchema(triples)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []string{"worksAt"}, s.Conflicts()) {
+			return
+		}
+		if !assert.Contains(subT, s.String(), "<worksAt>: [default] .\n") {
+			return
+		}
+	})
+
+	t.Run("should reject a type which cannot be used in a schema", func(subT *testing.T) {
+		_, err := inferSchema([]*subgraph.Triple{
+			{
+				Subject:   &subgraph.Subject{Type: "Person {", Tuid: "1"},
+				Predicate: &subgraph.Predicate{Name: "name"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Bob"}},
+			},
+		})
+		if !assert.ErrorIs(subT, err, ErrInvalidType) {
+			return
+		}
+	})
+}
+
+func TestSchema_Alter(t *testing.T) {
+	t.Run("should alter the schema of the cluster", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		f, dg := newFakeDgraph(subT, 0)
+		s, err := inferSchema(newTestTriples())
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		err = s.Alter(ctx, dg)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, f.altered, 1) {
+			return
+		}
+		if !assert.Equal(subT, s.String(), f.altered[0].Schema) {
+			return
+		}
+	})
+}
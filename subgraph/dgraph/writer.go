@@ -0,0 +1,333 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dgraph writes triples to Dgraph.
+//
+// Every subject is a node with its type stored in the dgraph.type predicate
+// and its TUID stored in the tuid predicate. Nodes are upserted by their
+// type and TUID, so the tuid predicate must be indexed in the Dgraph schema.
+// Operations are written in the order they are given, e.g. after they have
+// been converged by the merge package.
+package dgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/rdf"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// Facets which Publish adds to every asserted triple to record
+// the provenance of the subgraph it was published in.
+const (
+	SourceURIFacet  = "provenance_source_uri"
+	ReceivedAtFacet = "provenance_received_at"
+	CallerFacet     = "provenance_caller"
+)
+
+var (
+	// ErrMissingTriple is returned when a nil triple is written.
+	ErrMissingTriple = errors.New("dgraph: missing triple")
+
+	// ErrUnsupportedOperation is returned when a triple has an unknown operation.
+	ErrUnsupportedOperation = errors.New("dgraph: unsupported operation")
+)
+
+// Option
+type Option func(*Writer)
+
+// WithBatchSize sets the maximum number of triples written in a single transaction.
+func WithBatchSize(n int) Option {
+	return func(w *Writer) {
+		if n > 0 {
+			w.batchSize = n
+		}
+	}
+}
+
+// WithRetries sets how many times a transaction which was aborted, e.g. due
+// to a conflict with a concurrent transaction, is retried. The backoff
+// between retries starts at the given duration and doubles after each retry.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(w *Writer) {
+		w.retries = n
+		w.backoff = backoff
+	}
+}
+
+// Writer writes triples to Dgraph. A Writer is safe for concurrent use.
+type Writer struct {
+	dg        *dgo.Dgraph
+	batchSize int
+	retries   int
+	backoff   time.Duration
+}
+
+// NewWriter returns a Writer which writes to the given Dgraph cluster.
+func NewWriter(dg *dgo.Dgraph, opts ...Option) *Writer {
+	w := &Writer{
+		dg:        dg,
+		batchSize: 1000,
+		retries:   5,
+		backoff:   100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Publish writes every triple in the subgraph. It allows
+// Dgraph to be used as a sink for ingested subgraphs. The source URI,
+// receive time and caller of the subgraph are kept as facets of every
+// triple it asserts, see SourceURIFacet, ReceivedAtFacet and CallerFacet.
+func (w *Writer) Publish(ctx context.Context, g *subgraph.Subgraph) error {
+	return w.Write(ctx, withProvenance(g.GetTriples(), g.GetProvenance())...)
+}
+
+// withProvenance returns the triples with the provenance facets added
+// to every assertion. The given triples are left unmodified.
+func withProvenance(triples []*subgraph.Triple, prov *subgraph.Provenance) []*subgraph.Triple {
+	fs := make(map[string]*subgraph.FacetValue)
+	if uri := prov.GetSourceUri(); uri != "" {
+		fs[SourceURIFacet] = &subgraph.FacetValue{Value: &subgraph.FacetValue_String_{String_: uri}}
+	}
+	if at := prov.GetReceivedAt(); at != nil {
+		fs[ReceivedAtFacet] = &subgraph.FacetValue{Value: &subgraph.FacetValue_Timestamp{Timestamp: at}}
+	}
+	if caller := prov.GetCaller(); caller != "" {
+		fs[CallerFacet] = &subgraph.FacetValue{Value: &subgraph.FacetValue_String_{String_: caller}}
+	}
+	if len(fs) == 0 {
+		return triples
+	}
+
+	out := make([]*subgraph.Triple, len(triples))
+	for i, t := range triples {
+		if t == nil || t.Operation != subgraph.Operation_ASSERT {
+			out[i] = t
+			continue
+		}
+		t = proto.Clone(t).(*subgraph.Triple)
+		if t.Facets == nil {
+			t.Facets = make(map[string]*subgraph.FacetValue, len(fs))
+		}
+		for key, v := range fs {
+			t.Facets[key] = v
+		}
+		out[i] = t
+	}
+	return out
+}
+
+// Write writes the triples, in order, in transactions of at most the batch size.
+// The triples are expected to have already passed validate.Triple.
+func (w *Writer) Write(ctx context.Context, triples ...*subgraph.Triple) error {
+	for len(triples) > 0 {
+		n := w.batchSize
+		if n > len(triples) {
+			n = len(triples)
+		}
+
+		reqs, err := upserts(triples[:n])
+		if err != nil {
+			return err
+		}
+		err = w.commit(ctx, reqs)
+		if err != nil {
+			return err
+		}
+		triples = triples[n:]
+	}
+	return nil
+}
+
+// commit runs the requests in a single transaction,
+// retrying the whole transaction if it is aborted.
+func (w *Writer) commit(ctx context.Context, reqs []*api.Request) error {
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		err := w.do(ctx, reqs)
+		if !errors.Is(err, dgo.ErrAborted) || attempt >= w.retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (w *Writer) do(ctx context.Context, reqs []*api.Request) error {
+	txn := w.dg.NewTxn()
+	defer txn.Discard(ctx)
+
+	for _, req := range reqs {
+		_, err := txn.Do(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit(ctx)
+}
+
+// upserts returns an upsert request for every run of assertions
+// or removals, so that the order of operations is kept.
+func upserts(triples []*subgraph.Triple) ([]*api.Request, error) {
+	var reqs []*api.Request
+	var b *upsert
+	for _, t := range triples {
+		if t == nil {
+			return nil, ErrMissingTriple
+		}
+
+		del := t.Operation != subgraph.Operation_ASSERT
+		if b == nil || b.del != del {
+			if b != nil {
+				reqs = append(reqs, b.request())
+			}
+			b = newUpsert(del)
+		}
+
+		err := b.add(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if b != nil {
+		reqs = append(reqs, b.request())
+	}
+	return reqs, nil
+}
+
+type subjectKey struct {
+	typ  string
+	tuid string
+}
+
+// upsert builds a single upsert block which finds every node by its type
+// and TUID and then either sets or deletes the N-Quads on them.
+type upsert struct {
+	del    bool
+	vars   map[subjectKey]string
+	query  strings.Builder
+	params []string
+	values map[string]string
+	nquads strings.Builder
+}
+
+func newUpsert(del bool) *upsert {
+	return &upsert{
+		del:    del,
+		vars:   make(map[subjectKey]string),
+		values: make(map[string]string),
+	}
+}
+
+// uid returns the uid function for the node of the subject,
+// adding a query block which finds it if needed.
+func (b *upsert) uid(s *subgraph.Subject) (string, error) {
+	key := subjectKey{typ: s.GetType(), tuid: s.GetTuid()}
+	if v, ok := b.vars[key]; ok {
+		return "uid(" + v + ")", nil
+	}
+	if !typeName.MatchString(key.typ) {
+		return "", ErrInvalidType
+	}
+
+	v := fmt.Sprintf("s%d", len(b.vars))
+	param := fmt.Sprintf("$t%d", len(b.vars))
+	b.vars[key] = v
+	b.params = append(b.params, param+": string")
+	b.values[param] = key.tuid
+	fmt.Fprintf(&b.query, "\t%s as var(func: eq(tuid, %s)) @filter(type(%s))\n", v, param, key.typ)
+
+	uid := "uid(" + v + ")"
+	if !b.del {
+		fmt.Fprintf(&b.nquads, "%s <dgraph.type> %s .\n", uid, rdf.Quote(key.typ))
+		fmt.Fprintf(&b.nquads, "%s <tuid> %s .\n", uid, rdf.Quote(key.tuid))
+	}
+	return uid, nil
+}
+
+func (b *upsert) add(t *subgraph.Triple) error {
+	subj, err := b.uid(t.Subject)
+	if err != nil {
+		return err
+	}
+
+	switch t.Operation {
+	case subgraph.Operation_ASSERT, subgraph.Operation_RETRACT:
+	case subgraph.Operation_DELETE_SUBJECT:
+		fmt.Fprintf(&b.nquads, "%s * * .\n", subj)
+		return nil
+	default:
+		return ErrUnsupportedOperation
+	}
+
+	pred := t.GetPredicate().GetName()
+	if !predicateName.MatchString(pred) {
+		return ErrInvalidPredicate
+	}
+
+	obj := "*"
+	switch x := t.GetObject().GetValue().(type) {
+	case nil:
+	case *subgraph.Object_Subject:
+		obj, err = b.uid(x.Subject)
+	default:
+		obj, err = literal(t.Object)
+	}
+	if err != nil {
+		return err
+	}
+
+	fs, err := facets(t.Facets)
+	if err != nil {
+		return err
+	}
+	if fs != "" {
+		obj += " " + fs
+	}
+	fmt.Fprintf(&b.nquads, "%s <%s> %s .\n", subj, pred, obj)
+	return nil
+}
+
+func (b *upsert) request() *api.Request {
+	mu := &api.Mutation{}
+	if b.del {
+		mu.DelNquads = []byte(b.nquads.String())
+	} else {
+		mu.SetNquads = []byte(b.nquads.String())
+	}
+
+	return &api.Request{
+		Query:     fmt.Sprintf("query q(%s) {\n%s}", strings.Join(b.params, ", "), b.query.String()),
+		Vars:      b.values,
+		Mutations: []*api.Mutation{mu},
+	}
+}
@@ -0,0 +1,374 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dgraph
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeDgraph implements the Dgraph gRPC API by recording every
+// schema alteration and the requests of every committed transaction.
+type fakeDgraph struct {
+	api.UnimplementedDgraphServer
+
+	mu        sync.Mutex
+	ts        uint64
+	aborts    int
+	pending   map[uint64][]*api.Request
+	committed [][]*api.Request
+	altered   []*api.Operation
+}
+
+func (f *fakeDgraph) Alter(ctx context.Context, op *api.Operation) (*api.Payload, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.altered = append(f.altered, op)
+	return &api.Payload{}, nil
+}
+
+func (f *fakeDgraph) Query(ctx context.Context, req *api.Request) (*api.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	startTs := req.StartTs
+	if startTs == 0 {
+		f.ts += 1
+		startTs = f.ts
+	}
+	f.pending[startTs] = append(f.pending[startTs], req)
+	return &api.Response{Txn: &api.TxnContext{StartTs: startTs}}, nil
+}
+
+func (f *fakeDgraph) CommitOrAbort(ctx context.Context, tc *api.TxnContext) (*api.TxnContext, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reqs := f.pending[tc.StartTs]
+	delete(f.pending, tc.StartTs)
+	if tc.Aborted {
+		return tc, nil
+	}
+	if f.aborts > 0 {
+		f.aborts -= 1
+		return nil, status.Error(codes.Aborted, "transaction has been aborted")
+	}
+
+	f.ts += 1
+	f.committed = append(f.committed, reqs)
+	return &api.TxnContext{StartTs: tc.StartTs, CommitTs: f.ts}, nil
+}
+
+func (f *fakeDgraph) transactions() [][]*api.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.committed
+}
+
+func newFakeDgraph(t *testing.T, aborts int) (*fakeDgraph, *dgo.Dgraph) {
+	ls, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeDgraph{
+		aborts:  aborts,
+		pending: make(map[uint64][]*api.Request),
+	}
+	s := grpc.NewServer()
+	api.RegisterDgraphServer(s, f)
+	go s.Serve(ls)
+	t.Cleanup(s.Stop)
+
+	cc, err := grpc.Dial(ls.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+
+	return f, dgo.NewDgraphClient(api.NewDgraphClient(cc))
+}
+
+func assertName(tuid, name string) *subgraph.Triple {
+	return &subgraph.Triple{
+		Subject:   &subgraph.Subject{Type: "Person", Tuid: tuid},
+		Predicate: &subgraph.Predicate{Name: "name"},
+		Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: name}},
+	}
+}
+
+func TestWriter_Write(t *testing.T) {
+	t.Run("should upsert nodes by their type and tuid", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		f, dg := newFakeDgraph(subT, 0)
+		w := NewWriter(dg)
+
+		knows := &subgraph.Triple{
+			Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+			Predicate: &subgraph.Predicate{Name: "knows"},
+			Object: &subgraph.Object{
+				Value: &subgraph.Object_Subject{Subject: &subgraph.Subject{Type: "Person", Tuid: "2"}},
+			},
+			Facets: map[string]*subgraph.FacetValue{
+				"since": {Value: &subgraph.FacetValue_Int64{Int64: 2020}},
+			},
+		}
+		err := w.Write(ctx, assertName("1", "Bob"), knows)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		txns := f.transactions()
+		if !assert.Len(subT, txns, 1) {
+			return
+		}
+		if !assert.Len(subT, txns[0], 1) {
+			return
+		}
+
+		req := txns[0][0]
+		expectedQuery := strings.Join([]string{
+			"query q($t0: string, $t1: string) {",
+			"\ts0 as var(func: eq(tuid, $t0)) @filter(type(Person))",
+			"\ts1 as var(func: eq(tuid, $t1)) @filter(type(Person))",
+			"}",
+		}, "\n")
+		if !assert.Equal(subT, expectedQuery, req.Query) {
+			return
+		}
+		if !assert.Equal(subT, map[string]string{"$t0": "1", "$t1": "2"}, req.Vars) {
+			return
+		}
+		if !assert.Len(subT, req.Mutations, 1) {
+			return
+		}
+
+		expectedNquads := strings.Join([]string{
+			`uid(s0) <dgraph.type> "Person" .`,
+			`uid(s0) <tuid> "1" .`,
+			`uid(s0) <name> "Bob" .`,
+			`uid(s1) <dgraph.type> "Person" .`,
+			`uid(s1) <tuid> "2" .`,
+			`uid(s0) <knows> uid(s1) (since=2020) .`,
+			``,
+		}, "\n")
+		if !assert.Equal(subT, expectedNquads, string(req.Mutations[0].SetNquads)) {
+			return
+		}
+		if !assert.Empty(subT, req.Mutations[0].DelNquads) {
+			return
+		}
+	})
+
+	t.Run("should keep removals in order with assertions", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		f, dg := newFakeDgraph(subT, 0)
+		w := NewWriter(dg)
+
+		retractName := &subgraph.Triple{
+			Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+			Predicate: &subgraph.Predicate{Name: "name"},
+			Operation: subgraph.Operation_RETRACT,
+		}
+		del := &subgraph.Triple{
+			Subject:   &subgraph.Subject{Type: "Person", Tuid: "2"},
+			Operation: subgraph.Operation_DELETE_SUBJECT,
+		}
+		err := w.Write(ctx, assertName("1", "Bob"), retractName, del, assertName("1", "Alice"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		txns := f.transactions()
+		if !assert.Len(subT, txns, 1) {
+			return
+		}
+		if !assert.Len(subT, txns[0], 3) {
+			return
+		}
+
+		if !assert.Contains(subT, string(txns[0][0].Mutations[0].SetNquads), `uid(s0) <name> "Bob" .`) {
+			return
+		}
+		expectedDel := strings.Join([]string{
+			`uid(s0) <name> * .`,
+			`uid(s1) * * .`,
+			``,
+		}, "\n")
+		if !assert.Equal(subT, expectedDel, string(txns[0][1].Mutations[0].DelNquads)) {
+			return
+		}
+		if !assert.Contains(subT, string(txns[0][2].Mutations[0].SetNquads), `uid(s0) <name> "Alice" .`) {
+			return
+		}
+	})
+
+	t.Run("should batch triples into transactions", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		f, dg := newFakeDgraph(subT, 0)
+		w := NewWriter(dg, WithBatchSize(2))
+
+		var triples []*subgraph.Triple
+		for _, tuid := range []string{"1", "2", "3", "4", "5"} {
+			triples = append(triples, assertName(tuid, "Bob"))
+		}
+		err := w.Write(ctx, triples...)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		txns := f.transactions()
+		if !assert.Len(subT, txns, 3) {
+			return
+		}
+		for i, vars := range []map[string]string{
+			{"$t0": "1", "$t1": "2"},
+			{"$t0": "3", "$t1": "4"},
+			{"$t0": "5"},
+		} {
+			if !assert.Equal(subT, vars, txns[i][0].Vars) {
+				return
+			}
+		}
+	})
+
+	t.Run("should retry aborted transactions", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		f, dg := newFakeDgraph(subT, 2)
+		w := NewWriter(dg, WithRetries(2, time.Millisecond))
+
+		err := w.Write(ctx, assertName("1", "Bob"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, f.transactions(), 1) {
+			return
+		}
+	})
+
+	t.Run("should fail once the retries are exhausted", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		f, dg := newFakeDgraph(subT, 2)
+		w := NewWriter(dg, WithRetries(1, time.Millisecond))
+
+		err := w.Write(ctx, assertName("1", "Bob"))
+		if !assert.ErrorIs(subT, err, dgo.ErrAborted) {
+			return
+		}
+		if !assert.Empty(subT, f.transactions()) {
+			return
+		}
+	})
+
+	t.Run("should reject a type which cannot be used in a query", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		f, dg := newFakeDgraph(subT, 0)
+		w := NewWriter(dg)
+
+		triple := assertName("1", "Bob")
+		triple.Subject.Type = "Person) @filter(has(secret)"
+		err := w.Write(ctx, triple)
+		if !assert.ErrorIs(subT, err, ErrInvalidType) {
+			return
+		}
+		if !assert.Empty(subT, f.transactions()) {
+			return
+		}
+	})
+}
+
+func TestWriter_Publish(t *testing.T) {
+	t.Run("should keep the provenance of the subgraph as facets of its assertions", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		f, dg := newFakeDgraph(subT, 0)
+		w := NewWriter(dg)
+
+		name := assertName("1", "Bob")
+		g := &subgraph.Subgraph{
+			Provenance: &subgraph.Provenance{
+				SourceUri:  "s3://docs/bob.pdf",
+				ReceivedAt: timestamppb.New(time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)),
+				Caller:     "alice",
+			},
+			Triples: []*subgraph.Triple{
+				name,
+				{
+					Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+					Predicate: &subgraph.Predicate{Name: "age"},
+					Operation: subgraph.Operation_RETRACT,
+				},
+			},
+		}
+		err := w.Publish(ctx, g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, name.Facets) {
+			return
+		}
+
+		txns := f.transactions()
+		if !assert.Len(subT, txns, 1) {
+			return
+		}
+
+		var set, del []string
+		for _, req := range txns[0] {
+			for _, mu := range req.Mutations {
+				set = append(set, string(mu.SetNquads))
+				del = append(del, string(mu.DelNquads))
+			}
+		}
+		expectedFacets := `(provenance_caller="alice", provenance_received_at=2022-01-02T03:04:05Z, provenance_source_uri="s3://docs/bob.pdf")`
+		if !assert.Contains(subT, strings.Join(set, ""), `uid(s0) <name> "Bob" `+expectedFacets+" .") {
+			return
+		}
+		if !assert.NotContains(subT, strings.Join(del, ""), "provenance_") {
+			return
+		}
+	})
+}
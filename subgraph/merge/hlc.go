@@ -0,0 +1,175 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+)
+
+// DefaultMaxDrift is how far ahead of the local wall clock a timestamp
+// observed by a Clock may be, unless changed with WithMaxDrift.
+const DefaultMaxDrift = time.Minute
+
+// ErrClockDrift is returned for timestamps which are further ahead of
+// the local wall clock than the clock's maximum drift.
+var ErrClockDrift = errors.New("timestamp is too far ahead of the wall clock")
+
+// ClockOption
+type ClockOption func(*Clock)
+
+// WithMaxDrift sets how far ahead of the local wall clock a timestamp
+// passed to Update may be. Without a bound, a single timestamp from
+// the far future would pin every timestamp the clock issues to it.
+func WithMaxDrift(d time.Duration) ClockOption {
+	return func(c *Clock) {
+		c.maxDrift = d
+	}
+}
+
+// Clock is a hybrid logical clock. The timestamps it issues never go
+// backwards, even if the wall clock does, and are always after every
+// timestamp the clock has observed through Update.
+type Clock struct {
+	nodeID   string
+	now      func() time.Time
+	maxDrift time.Duration
+
+	mu      sync.Mutex
+	wall    int64
+	logical uint32
+}
+
+// NewClock returns a clock which issues timestamps for the given node.
+// The node ID should be unique across every node issuing timestamps
+// since it is used to order otherwise identical timestamps.
+func NewClock(nodeID string, opts ...ClockOption) *Clock {
+	c := &Clock{
+		nodeID:   nodeID,
+		now:      time.Now,
+		maxDrift: DefaultMaxDrift,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Now returns a timestamp after every timestamp previously issued or observed.
+func (c *Clock) Now() *subgraph.HybridTimestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pt := c.now().UnixNano()
+	if pt > c.wall {
+		c.wall = pt
+		c.logical = 0
+	} else {
+		c.logical += 1
+	}
+	return c.timestamp()
+}
+
+// Check returns an error wrapping ErrClockDrift if the timestamp is further
+// ahead of the local wall clock than the maximum drift allowed by Update.
+func (c *Clock) Check(ts *subgraph.HybridTimestamp) error {
+	return c.check(ts, c.now().UnixNano())
+}
+
+func (c *Clock) check(ts *subgraph.HybridTimestamp, pt int64) error {
+	drift := time.Duration(ts.WallTime - pt)
+	if drift > c.maxDrift {
+		return fmt.Errorf("%w: %s ahead, at most %s is allowed", ErrClockDrift, drift, c.maxDrift)
+	}
+	return nil
+}
+
+// Update advances the clock past a timestamp issued by another node.
+// Timestamps rejected by Check are not observed and their error is returned.
+func (c *Clock) Update(ts *subgraph.HybridTimestamp) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pt := c.now().UnixNano()
+	err := c.check(ts, pt)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case pt > c.wall && pt > ts.WallTime:
+		c.wall = pt
+		c.logical = 0
+	case ts.WallTime > c.wall:
+		c.wall = ts.WallTime
+		c.logical = ts.Logical + 1
+	case ts.WallTime == c.wall:
+		c.logical = max(c.logical, ts.Logical) + 1
+	default:
+		c.logical += 1
+	}
+	return nil
+}
+
+func (c *Clock) timestamp() *subgraph.HybridTimestamp {
+	return &subgraph.HybridTimestamp{
+		WallTime: c.wall,
+		Logical:  c.logical,
+		NodeId:   c.nodeID,
+	}
+}
+
+// Compare returns -1 if a is before b, 1 if a is after b and 0 if they are
+// the same timestamp. A nil timestamp is before every other timestamp.
+func Compare(a, b *subgraph.HybridTimestamp) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch {
+	case a.WallTime < b.WallTime:
+		return -1
+	case a.WallTime > b.WallTime:
+		return 1
+	case a.Logical < b.Logical:
+		return -1
+	case a.Logical > b.Logical:
+		return 1
+	case a.NodeId < b.NodeId:
+		return -1
+	case a.NodeId > b.NodeId:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func latest(a, b *subgraph.HybridTimestamp) *subgraph.HybridTimestamp {
+	if Compare(a, b) >= 0 {
+		return a
+	}
+	return b
+}
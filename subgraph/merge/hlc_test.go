@@ -0,0 +1,152 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package merge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClock(nodeID string, now *time.Time, opts ...ClockOption) *Clock {
+	c := NewClock(nodeID, opts...)
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestClock(t *testing.T) {
+	t.Run("should issue increasing timestamps when the wall clock stands still", func(subT *testing.T) {
+		now := time.Unix(100, 0)
+		c := newTestClock("a", &now)
+
+		prev := c.Now()
+		for i := 0; i < 10; i++ {
+			ts := c.Now()
+			if !assert.Equal(subT, 1, Compare(ts, prev)) {
+				return
+			}
+			prev = ts
+		}
+	})
+
+	t.Run("should issue increasing timestamps when the wall clock goes backwards", func(subT *testing.T) {
+		now := time.Unix(100, 0)
+		c := newTestClock("a", &now)
+
+		prev := c.Now()
+		now = now.Add(-time.Minute)
+		ts := c.Now()
+		if !assert.Equal(subT, 1, Compare(ts, prev)) {
+			return
+		}
+	})
+
+	t.Run("should issue timestamps after every observed timestamp", func(subT *testing.T) {
+		now := time.Unix(100, 0)
+		c := newTestClock("a", &now)
+
+		remote := &subgraph.HybridTimestamp{
+			WallTime: time.Unix(130, 0).UnixNano(),
+			Logical:  5,
+			NodeId:   "b",
+		}
+		err := c.Update(remote)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		ts := c.Now()
+		if !assert.Equal(subT, 1, Compare(ts, remote)) {
+			return
+		}
+		if !assert.Equal(subT, remote.WallTime, ts.WallTime) {
+			return
+		}
+	})
+
+	t.Run("should go back to the wall clock once it catches up", func(subT *testing.T) {
+		now := time.Unix(100, 0)
+		c := newTestClock("a", &now)
+
+		err := c.Update(&subgraph.HybridTimestamp{WallTime: time.Unix(130, 0).UnixNano(), Logical: 5})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		now = time.Unix(300, 0)
+		ts := c.Now()
+		if !assert.Equal(subT, now.UnixNano(), ts.WallTime) {
+			return
+		}
+		if !assert.Equal(subT, uint32(0), ts.Logical) {
+			return
+		}
+	})
+
+	t.Run("should not observe timestamps too far ahead of the wall clock", func(subT *testing.T) {
+		now := time.Unix(100, 0)
+		c := newTestClock("a", &now, WithMaxDrift(time.Second))
+
+		remote := &subgraph.HybridTimestamp{
+			WallTime: time.Unix(102, 0).UnixNano(),
+			NodeId:   "b",
+		}
+		err := c.Check(remote)
+		if !assert.ErrorIs(subT, err, ErrClockDrift) {
+			return
+		}
+		err = c.Update(remote)
+		if !assert.ErrorIs(subT, err, ErrClockDrift) {
+			return
+		}
+
+		ts := c.Now()
+		if !assert.Equal(subT, now.UnixNano(), ts.WallTime) {
+			return
+		}
+	})
+}
+
+func TestCompare(t *testing.T) {
+	a := &subgraph.HybridTimestamp{WallTime: 1, Logical: 1, NodeId: "a"}
+
+	testCases := []struct {
+		Name     string
+		B        *subgraph.HybridTimestamp
+		Expected int
+	}{
+		{Name: "nil", B: nil, Expected: 1},
+		{Name: "later wall time", B: &subgraph.HybridTimestamp{WallTime: 2}, Expected: -1},
+		{Name: "later logical counter", B: &subgraph.HybridTimestamp{WallTime: 1, Logical: 2}, Expected: -1},
+		{Name: "earlier logical counter", B: &subgraph.HybridTimestamp{WallTime: 1, Logical: 0, NodeId: "z"}, Expected: 1},
+		{Name: "greater node id", B: &subgraph.HybridTimestamp{WallTime: 1, Logical: 1, NodeId: "b"}, Expected: -1},
+		{Name: "same timestamp", B: &subgraph.HybridTimestamp{WallTime: 1, Logical: 1, NodeId: "a"}, Expected: 0},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run("should order against "+tc.Name, func(subT *testing.T) {
+			if !assert.Equal(subT, tc.Expected, Compare(a, tc.B)) {
+				return
+			}
+			if !assert.Equal(subT, -tc.Expected, Compare(tc.B, a)) {
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package merge folds triples from many producers into a single graph.
+//
+// Every triple must carry a hybrid logical clock timestamp. How the
+// operations on a subject and predicate are resolved depends on how
+// the predicate is declared in the schema:
+//
+//   - Predicates declared with a cardinality of one hold a single value and
+//     conflicting assertions are resolved by last-writer-wins.
+//   - Predicates declared with a cardinality of many hold an observed-remove
+//     set of values. A retraction which lists the assertions it observed
+//     removes only those, so an assertion made concurrently on another
+//     replica survives it even if it is timestamped before the retraction.
+//     A retraction which does not list them, and a subject deletion, remove
+//     every assertion timestamped before them instead. See Observed.
+//   - Predicates which are not declared hold a set of values where each
+//     value is independently resolved by last-writer-wins.
+//
+// Last-writer-wins operations are ordered by their timestamp, not by when
+// they were applied, so every Graph which has been given the same triples
+// converges to the same state regardless of the order they arrived in.
+// At equal timestamps a retraction, or subject deletion, wins over an assertion.
+//
+// Replicas, e.g. one per ingest service replica, converge by exchanging
+// their state with Merge. No coordination between them is needed.
+package merge
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrMissingTriple    = errors.New("merge: missing triple")
+	ErrMissingTimestamp = errors.New("merge: triple is missing its hybrid logical clock timestamp")
+)
+
+// Option
+type Option func(*Graph)
+
+// WithSchema uses the schema to decide how the values of each predicate are merged.
+func WithSchema(s *schema.Schema) Option {
+	return func(g *Graph) {
+		g.schema = s
+	}
+}
+
+// semantics is how the values of a predicate are merged.
+type semantics int
+
+const (
+	lwwSet semantics = iota
+	lwwRegister
+	orSet
+)
+
+type subjectKey struct {
+	typ  string
+	tuid string
+}
+
+type subjectState struct {
+	subject    *subgraph.Subject
+	deleted    *subgraph.HybridTimestamp
+	predicates map[string]*predicateState
+}
+
+type predicateState struct {
+	retracted *subgraph.HybridTimestamp
+	values    map[string]*valueState
+
+	// assertions removed by retractions of every value of
+	// the predicate which listed them, see orset.go
+	removedTags tags
+}
+
+type valueState struct {
+	asserted  *subgraph.Triple
+	retracted *subgraph.HybridTimestamp
+
+	// observed-remove set state, see orset.go
+	adds        map[tag]*subgraph.Triple
+	removedTags tags
+	removedAt   *subgraph.HybridTimestamp
+}
+
+// Graph is the converged state of every triple applied to it.
+// A Graph is not safe for concurrent use.
+type Graph struct {
+	schema   *schema.Schema
+	subjects map[subjectKey]*subjectState
+}
+
+// New returns an empty graph.
+func New(opts ...Option) *Graph {
+	g := &Graph{
+		subjects: make(map[subjectKey]*subjectState),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Apply merges the triple into the graph. The triple is expected to
+// have already passed validate.Triple.
+func (g *Graph) Apply(t *subgraph.Triple) error {
+	if t == nil {
+		return ErrMissingTriple
+	}
+	if t.Hlc == nil {
+		return ErrMissingTimestamp
+	}
+
+	subj := g.subject(t.Subject)
+	if t.Operation == subgraph.Operation_DELETE_SUBJECT {
+		subj.deleted = latest(subj.deleted, t.Hlc)
+		return nil
+	}
+
+	name := t.GetPredicate().GetName()
+	sem := g.semantics(subj.subject.Type, name)
+	pred := subj.predicate(name)
+	observed := sem == orSet && len(t.Observed) > 0
+	if t.Operation == subgraph.Operation_RETRACT && t.Object == nil {
+		if !observed {
+			pred.retracted = latest(pred.retracted, t.Hlc)
+			return nil
+		}
+		for _, ts := range t.Observed {
+			pred.removedTags.add(tagOf(ts))
+		}
+		return nil
+	}
+
+	key, err := objectKey(t.Object)
+	if err != nil {
+		return err
+	}
+	val := pred.value(key)
+
+	switch {
+	case t.Operation == subgraph.Operation_RETRACT && observed:
+		val.removeObserved(t.Observed)
+	case t.Operation == subgraph.Operation_RETRACT && sem == orSet:
+		val.removeBefore(t.Hlc)
+	case t.Operation == subgraph.Operation_RETRACT:
+		val.retracted = latest(val.retracted, t.Hlc)
+	case sem == orSet:
+		val.add(t)
+	case After(t, val.asserted):
+		val.asserted = proto.Clone(t).(*subgraph.Triple)
+	}
+	return nil
+}
+
+// Merge merges the state of another replica into the graph. Both graphs
+// are expected to have been created with the same schema.
+func (g *Graph) Merge(other *Graph) {
+	for _, osubj := range other.subjects {
+		subj := g.subject(osubj.subject)
+		subj.deleted = latest(subj.deleted, osubj.deleted)
+
+		for name, opred := range osubj.predicates {
+			pred := subj.predicate(name)
+			pred.retracted = latest(pred.retracted, opred.retracted)
+			pred.removedTags.union(opred.removedTags)
+
+			for vkey, oval := range opred.values {
+				val := pred.value(vkey)
+				val.retracted = latest(val.retracted, oval.retracted)
+				if oval.asserted != nil && After(oval.asserted, val.asserted) {
+					val.asserted = proto.Clone(oval.asserted).(*subgraph.Triple)
+				}
+				val.merge(oval)
+			}
+		}
+	}
+}
+
+// Triples returns every asserted triple which has not been retracted,
+// or deleted, in a deterministic order.
+func (g *Graph) Triples() []*subgraph.Triple {
+	keys := make([]subjectKey, 0, len(g.subjects))
+	for key := range g.subjects {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].typ != keys[j].typ {
+			return keys[i].typ < keys[j].typ
+		}
+		return keys[i].tuid < keys[j].tuid
+	})
+
+	var triples []*subgraph.Triple
+	for _, key := range keys {
+		subj := g.subjects[key]
+
+		names := make([]string, 0, len(subj.predicates))
+		for name := range subj.predicates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			triples = append(triples, g.values(subj, name)...)
+		}
+	}
+	return triples
+}
+
+// Observed returns the timestamps of the assertions of the object, or of
+// every object if it is nil, which the subjects predicate currently holds.
+// A retraction which lists them, in its observed field, removes only those
+// assertions from predicates declared with a cardinality of many.
+func (g *Graph) Observed(s *subgraph.Subject, predicate string, o *subgraph.Object) ([]*subgraph.HybridTimestamp, error) {
+	var key string
+	if o != nil {
+		var err error
+		key, err = objectKey(o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	subj, ok := g.subjects[subjectKey{typ: s.GetType(), tuid: s.GetTuid()}]
+	if !ok || subj.predicates[predicate] == nil {
+		return nil, nil
+	}
+	pred := subj.predicates[predicate]
+	removedAt := latest(subj.deleted, pred.retracted)
+
+	var observed []*subgraph.HybridTimestamp
+	if g.semantics(subj.subject.Type, predicate) != orSet {
+		for _, t := range g.values(subj, predicate) {
+			vkey, err := objectKey(t.Object)
+			if err != nil {
+				return nil, err
+			}
+			if o == nil || vkey == key {
+				observed = append(observed, t.Hlc)
+			}
+		}
+		return observed, nil
+	}
+	for vkey, val := range pred.values {
+		if o != nil && vkey != key {
+			continue
+		}
+		for k, t := range val.adds {
+			if pred.removedTags.has(k) || Compare(t.Hlc, removedAt) <= 0 {
+				continue
+			}
+			observed = append(observed, k.timestamp())
+		}
+	}
+	sort.Slice(observed, func(i, j int) bool {
+		return Compare(observed[i], observed[j]) < 0
+	})
+	return observed, nil
+}
+
+func (g *Graph) subject(s *subgraph.Subject) *subjectState {
+	key := subjectKey{typ: s.GetType(), tuid: s.GetTuid()}
+	subj, ok := g.subjects[key]
+	if !ok {
+		subj = &subjectState{
+			subject:    proto.Clone(s).(*subgraph.Subject),
+			predicates: make(map[string]*predicateState),
+		}
+		g.subjects[key] = subj
+	}
+	return subj
+}
+
+func (s *subjectState) predicate(name string) *predicateState {
+	pred, ok := s.predicates[name]
+	if !ok {
+		pred = &predicateState{
+			values: make(map[string]*valueState),
+		}
+		s.predicates[name] = pred
+	}
+	return pred
+}
+
+func (p *predicateState) value(key string) *valueState {
+	val, ok := p.values[key]
+	if !ok {
+		val = new(valueState)
+		p.values[key] = val
+	}
+	return val
+}
+
+func (g *Graph) semantics(typ, name string) semantics {
+	if g.schema == nil {
+		return lwwSet
+	}
+	p, ok := g.schema.Predicate(typ, name)
+	switch {
+	case !ok:
+		return lwwSet
+	case p.Cardinality == schema.One:
+		return lwwRegister
+	default:
+		return orSet
+	}
+}
+
+// values returns the current values of the subjects predicate.
+func (g *Graph) values(subj *subjectState, name string) []*subgraph.Triple {
+	pred := subj.predicates[name]
+
+	keys := make([]string, 0, len(pred.values))
+	for key := range pred.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var triples []*subgraph.Triple
+	switch g.semantics(subj.subject.Type, name) {
+	case lwwRegister:
+		var winner *valueState
+		for _, key := range keys {
+			val := pred.values[key]
+			if val.asserted != nil && (winner == nil || After(val.asserted, winner.asserted)) {
+				winner = val
+			}
+		}
+		if winner == nil || removed(subj, pred, winner) {
+			return nil
+		}
+		return []*subgraph.Triple{proto.Clone(winner.asserted).(*subgraph.Triple)}
+	case orSet:
+		for _, key := range keys {
+			t := pred.values[key].observed(pred.removedTags, latest(subj.deleted, pred.retracted))
+			if t == nil {
+				continue
+			}
+			triples = append(triples, proto.Clone(t).(*subgraph.Triple))
+		}
+	default:
+		for _, key := range keys {
+			val := pred.values[key]
+			if val.asserted == nil || removed(subj, pred, val) {
+				continue
+			}
+			triples = append(triples, proto.Clone(val.asserted).(*subgraph.Triple))
+		}
+	}
+	return triples
+}
+
+// removed reports whether the latest assertion of the value
+// is not after its latest retraction or deletion.
+func removed(subj *subjectState, pred *predicateState, val *valueState) bool {
+	ts := latest(subj.deleted, latest(pred.retracted, val.retracted))
+	return ts != nil && Compare(val.asserted.Hlc, ts) <= 0
+}
+
+// After reports whether the assertion t supersedes the assertion u under
+// last-writer-wins. Assertions with the same timestamp are ordered by their
+// encoding so that the winner does not depend on which was applied first.
+func After(t, u *subgraph.Triple) bool {
+	if u == nil {
+		return true
+	}
+	if c := Compare(t.Hlc, u.Hlc); c != 0 {
+		return c > 0
+	}
+	return bytes.Compare(marshal(t), marshal(u)) > 0
+}
+
+func objectKey(o *subgraph.Object) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(o)
+	return string(b), err
+}
+
+func marshal(m proto.Message) []byte {
+	b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	return b
+}
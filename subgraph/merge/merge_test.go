@@ -0,0 +1,272 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package merge
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func person(tuid string) *subgraph.Subject {
+	return &subgraph.Subject{Type: "Person", Tuid: tuid}
+}
+
+func str(s string) *subgraph.Object {
+	return &subgraph.Object{Value: &subgraph.Object_String_{String_: s}}
+}
+
+func hlc(wall int64, nodeID string) *subgraph.HybridTimestamp {
+	return &subgraph.HybridTimestamp{WallTime: wall, NodeId: nodeID}
+}
+
+func assertTriple(subj *subgraph.Subject, pred string, obj *subgraph.Object, ts *subgraph.HybridTimestamp) *subgraph.Triple {
+	return &subgraph.Triple{
+		Subject:   subj,
+		Predicate: &subgraph.Predicate{Name: pred},
+		Object:    obj,
+		Hlc:       ts,
+	}
+}
+
+func retractTriple(subj *subgraph.Subject, pred string, obj *subgraph.Object, ts *subgraph.HybridTimestamp) *subgraph.Triple {
+	t := assertTriple(subj, pred, obj, ts)
+	t.Operation = subgraph.Operation_RETRACT
+	return t
+}
+
+func deleteSubject(subj *subgraph.Subject, ts *subgraph.HybridTimestamp) *subgraph.Triple {
+	return &subgraph.Triple{
+		Subject:   subj,
+		Operation: subgraph.Operation_DELETE_SUBJECT,
+		Hlc:       ts,
+	}
+}
+
+func newTestSchema(t *testing.T) *schema.Schema {
+	s, err := schema.Parse([]byte(`
+types:
+  Person:
+    predicates:
+      name:
+        kind: string
+        cardinality: one
+      nickname:
+        kind: string
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func apply(g *Graph, triples ...*subgraph.Triple) error {
+	for _, t := range triples {
+		err := g.Apply(t)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func objects(triples []*subgraph.Triple) []string {
+	var vs []string
+	for _, t := range triples {
+		vs = append(vs, fmt.Sprintf("%s/%s=%s", t.Subject.Tuid, t.Predicate.Name, t.Object.GetString_()))
+	}
+	return vs
+}
+
+func TestGraph_Apply(t *testing.T) {
+	t.Run("should fail if the triple has no timestamp", func(subT *testing.T) {
+		g := New()
+		err := g.Apply(assertTriple(person("1"), "name", str("Bob"), nil))
+		if !assert.Equal(subT, ErrMissingTimestamp, err) {
+			return
+		}
+	})
+
+	t.Run("should keep the last written value of a single valued predicate", func(subT *testing.T) {
+		g := New(WithSchema(newTestSchema(subT)))
+		err := apply(
+			g,
+			assertTriple(person("1"), "name", str("Robert"), hlc(2, "b")),
+			assertTriple(person("1"), "name", str("Bob"), hlc(1, "a")),
+			assertTriple(person("1"), "name", str("Rob"), hlc(2, "a")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1/name=Robert"}, objects(g.Triples())) {
+			return
+		}
+	})
+
+	t.Run("should keep every value of a multi valued predicate", func(subT *testing.T) {
+		g := New(WithSchema(newTestSchema(subT)))
+		err := apply(
+			g,
+			assertTriple(person("1"), "nickname", str("Rob"), hlc(2, "b")),
+			assertTriple(person("1"), "nickname", str("Bob"), hlc(1, "a")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1/nickname=Bob", "1/nickname=Rob"}, objects(g.Triples())) {
+			return
+		}
+	})
+
+	t.Run("should only remove assertions which were made before a retraction", func(subT *testing.T) {
+		g := New()
+		err := apply(
+			g,
+			retractTriple(person("1"), "nickname", str("Bob"), hlc(2, "a")),
+			assertTriple(person("1"), "nickname", str("Bob"), hlc(1, "a")),
+			assertTriple(person("1"), "nickname", str("Rob"), hlc(3, "a")),
+			retractTriple(person("1"), "nickname", nil, hlc(4, "a")),
+			assertTriple(person("1"), "nickname", str("Bobby"), hlc(5, "a")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1/nickname=Bobby"}, objects(g.Triples())) {
+			return
+		}
+	})
+
+	t.Run("should prefer a retraction with the same timestamp as an assertion", func(subT *testing.T) {
+		g := New()
+		err := apply(
+			g,
+			assertTriple(person("1"), "nickname", str("Bob"), hlc(1, "a")),
+			retractTriple(person("1"), "nickname", str("Bob"), hlc(1, "a")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, g.Triples()) {
+			return
+		}
+	})
+
+	t.Run("should remove everything asserted about a subject before it was deleted", func(subT *testing.T) {
+		g := New(WithSchema(newTestSchema(subT)))
+		err := apply(
+			g,
+			assertTriple(person("1"), "name", str("Bob"), hlc(1, "a")),
+			assertTriple(person("1"), "nickname", str("Bobby"), hlc(1, "a")),
+			assertTriple(person("2"), "name", str("Alice"), hlc(1, "a")),
+			assertTriple(person("1"), "nickname", str("Rob"), hlc(3, "a")),
+			deleteSubject(person("1"), hlc(2, "b")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1/nickname=Rob", "2/name=Alice"}, objects(g.Triples())) {
+			return
+		}
+	})
+}
+
+// randomTriples returns n operations, from a handful of producers,
+// on a small number of subjects, predicates and values so that
+// they frequently conflict with each other.
+func randomTriples(rng *rand.Rand, n int) []*subgraph.Triple {
+	nodes := []string{"a", "b", "c"}
+	clocks := make(map[string]int64, len(nodes))
+
+	triples := make([]*subgraph.Triple, n)
+	for i := range triples {
+		node := nodes[rng.Intn(len(nodes))]
+		clocks[node] += int64(rng.Intn(3))
+		ts := &subgraph.HybridTimestamp{
+			WallTime: clocks[node],
+			Logical:  uint32(i),
+			NodeId:   node,
+		}
+
+		subj := person(fmt.Sprint(rng.Intn(2)))
+		pred := []string{"name", "nickname", "alias"}[rng.Intn(3)]
+		obj := str([]string{"Bob", "Rob", "Bobby"}[rng.Intn(3)])
+
+		switch rng.Intn(10) {
+		case 0:
+			triples[i] = deleteSubject(subj, ts)
+		case 1:
+			triples[i] = retractTriple(subj, pred, nil, ts)
+		case 2, 3:
+			triples[i] = retractTriple(subj, pred, obj, ts)
+		default:
+			triples[i] = assertTriple(subj, pred, obj, ts)
+		}
+	}
+	return triples
+}
+
+func TestGraph_Convergence(t *testing.T) {
+	s := newTestSchema(t)
+
+	for seed := int64(0); seed < 100; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		triples := randomTriples(rng, 50)
+
+		expected := New(WithSchema(s))
+		err := apply(expected, triples...)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		for i := 0; i < 10; i++ {
+			shuffled := make([]*subgraph.Triple, len(triples))
+			copy(shuffled, triples)
+			rng.Shuffle(len(shuffled), func(i, j int) {
+				shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+			})
+			// Redelivering a triple must not change the result either.
+			shuffled = append(shuffled, shuffled[:rng.Intn(len(shuffled))]...)
+
+			g := New(WithSchema(s))
+			err := apply(g, shuffled...)
+			if !assert.Nil(t, err) {
+				return
+			}
+			if !assertSameTriples(t, expected.Triples(), g.Triples(), "seed %d, permutation %d", seed, i) {
+				return
+			}
+		}
+	}
+}
+
+func assertSameTriples(t *testing.T, expected, actual []*subgraph.Triple, msgAndArgs ...any) bool {
+	if !assert.Len(t, actual, len(expected), msgAndArgs...) {
+		return false
+	}
+	for i := range expected {
+		if !assert.True(t, proto.Equal(expected[i], actual[i]), msgAndArgs...) {
+			return false
+		}
+	}
+	return true
+}
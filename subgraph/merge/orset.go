@@ -0,0 +1,144 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package merge
+
+import (
+	"github.com/z5labs/megamind/subgraph"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// tag uniquely identifies an assertion in an observed-remove set.
+// The hybrid logical clock timestamp of the assertion is used since
+// no two assertions are ever issued the same timestamp.
+type tag struct {
+	wall    int64
+	logical uint32
+	nodeID  string
+}
+
+func tagOf(ts *subgraph.HybridTimestamp) tag {
+	return tag{
+		wall:    ts.WallTime,
+		logical: ts.Logical,
+		nodeID:  ts.NodeId,
+	}
+}
+
+func (t tag) timestamp() *subgraph.HybridTimestamp {
+	return &subgraph.HybridTimestamp{
+		WallTime: t.wall,
+		Logical:  t.logical,
+		NodeId:   t.nodeID,
+	}
+}
+
+// tags is a set of assertions which have been removed by a retraction
+// which observed them.
+type tags map[tag]struct{}
+
+func (ts *tags) add(k tag) {
+	if *ts == nil {
+		*ts = make(tags)
+	}
+	(*ts)[k] = struct{}{}
+}
+
+func (ts tags) has(k tag) bool {
+	_, ok := ts[k]
+	return ok
+}
+
+func (ts *tags) union(other tags) {
+	for k := range other {
+		ts.add(k)
+	}
+}
+
+// add tags the assertion, unless a retraction which observed it, or
+// which is after it, has already been applied.
+func (v *valueState) add(t *subgraph.Triple) {
+	if v.removedAt != nil && Compare(t.Hlc, v.removedAt) <= 0 {
+		return
+	}
+	k := tagOf(t.Hlc)
+	if v.removedTags.has(k) {
+		return
+	}
+	if _, ok := v.adds[k]; ok {
+		return
+	}
+	if v.adds == nil {
+		v.adds = make(map[tag]*subgraph.Triple)
+	}
+	v.adds[k] = proto.Clone(t).(*subgraph.Triple)
+}
+
+// removeObserved removes the assertions of the value which a retraction
+// observed, including those which are only applied after the retraction.
+// Every other assertion survives it, however it is timestamped.
+func (v *valueState) removeObserved(observed []*subgraph.HybridTimestamp) {
+	for _, ts := range observed {
+		k := tagOf(ts)
+		v.removedTags.add(k)
+		delete(v.adds, k)
+	}
+}
+
+// removeBefore removes every assertion of the value which is not after the
+// retraction, including those which are only applied after the retraction.
+// It is used for retractions which do not list the assertions they observed.
+func (v *valueState) removeBefore(ts *subgraph.HybridTimestamp) {
+	if ts == nil {
+		return
+	}
+	v.removedAt = latest(v.removedAt, ts)
+	for k, t := range v.adds {
+		if Compare(t.Hlc, v.removedAt) <= 0 {
+			delete(v.adds, k)
+		}
+	}
+}
+
+// merge joins the observed-remove set state of another replica into v.
+func (v *valueState) merge(other *valueState) {
+	v.removeBefore(other.removedAt)
+	for k := range other.removedTags {
+		v.removedTags.add(k)
+		delete(v.adds, k)
+	}
+	for _, t := range other.adds {
+		v.add(t)
+	}
+}
+
+// observed returns the latest assertion of the value which has not been
+// removed by a retraction of the predicate which observed it, in removed,
+// and which is after the given retraction, or subject deletion, or nil if
+// there is none.
+func (v *valueState) observed(removed tags, removedAt *subgraph.HybridTimestamp) *subgraph.Triple {
+	var t *subgraph.Triple
+	for k, u := range v.adds {
+		if removed.has(k) || Compare(u.Hlc, removedAt) <= 0 {
+			continue
+		}
+		if After(u, t) {
+			t = u
+		}
+	}
+	return t
+}
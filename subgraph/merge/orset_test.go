@@ -0,0 +1,328 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package merge
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGraph_ObservedRemoveSet(t *testing.T) {
+	t.Run("should keep assertions made after a retraction on another replica", func(subT *testing.T) {
+		s := newTestSchema(subT)
+		a := New(WithSchema(s))
+		b := New(WithSchema(s))
+
+		err := a.Apply(assertTriple(person("1"), "nickname", str("Bob"), hlc(1, "a")))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		b.Merge(a)
+
+		// b retracts Bob while a asserts it again
+		err = b.Apply(retractTriple(person("1"), "nickname", str("Bob"), hlc(3, "b")))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = a.Apply(assertTriple(person("1"), "nickname", str("Bob"), hlc(4, "a")))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		a.Merge(b)
+		b.Merge(a)
+		if !assert.Equal(subT, []string{"1/nickname=Bob"}, objects(a.Triples())) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1/nickname=Bob"}, objects(b.Triples())) {
+			return
+		}
+	})
+
+	t.Run("should keep a concurrent assertion a retraction did not observe even if it is timestamped before it", func(subT *testing.T) {
+		s := newTestSchema(subT)
+		a := New(WithSchema(s))
+		b := New(WithSchema(s))
+
+		err := a.Apply(assertTriple(person("1"), "nickname", str("Bob"), hlc(1, "a")))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		b.Merge(a)
+
+		// a asserts Bob again before b retracts it, but b has not seen it yet
+		err = a.Apply(assertTriple(person("1"), "nickname", str("Bob"), hlc(2, "a")))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		observed, err := b.Observed(person("1"), "nickname", str("Bob"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, observed, 1) {
+			return
+		}
+		retract := retractTriple(person("1"), "nickname", str("Bob"), hlc(3, "b"))
+		retract.Observed = observed
+		err = b.Apply(retract)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, b.Triples()) {
+			return
+		}
+
+		a.Merge(b)
+		b.Merge(a)
+		for _, g := range []*Graph{a, b} {
+			triples := g.Triples()
+			if !assert.Equal(subT, []string{"1/nickname=Bob"}, objects(triples)) {
+				return
+			}
+			if !assert.True(subT, proto.Equal(hlc(2, "a"), triples[0].Hlc)) {
+				return
+			}
+		}
+	})
+
+	t.Run("should only remove the observed assertions of every value of the predicate", func(subT *testing.T) {
+		g := New(WithSchema(newTestSchema(subT)))
+		retract := retractTriple(person("1"), "nickname", nil, hlc(5, "b"))
+		retract.Observed = []*subgraph.HybridTimestamp{hlc(1, "a"), hlc(2, "a")}
+		err := apply(
+			g,
+			retract,
+			assertTriple(person("1"), "nickname", str("Bob"), hlc(1, "a")),
+			assertTriple(person("1"), "nickname", str("Rob"), hlc(2, "a")),
+			assertTriple(person("1"), "nickname", str("Bobby"), hlc(3, "c")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1/nickname=Bobby"}, objects(g.Triples())) {
+			return
+		}
+	})
+
+	t.Run("should not let a stale retraction remove a later assertion", func(subT *testing.T) {
+		for _, triples := range [][]*subgraph.Triple{
+			{
+				assertTriple(person("1"), "nickname", str("Bob"), hlc(5, "a")),
+				retractTriple(person("1"), "nickname", str("Bob"), hlc(3, "b")),
+			},
+			{
+				retractTriple(person("1"), "nickname", str("Bob"), hlc(3, "b")),
+				assertTriple(person("1"), "nickname", str("Bob"), hlc(5, "a")),
+			},
+		} {
+			g := New(WithSchema(newTestSchema(subT)))
+			err := apply(g, triples...)
+			if !assert.Nil(subT, err) {
+				return
+			}
+			if !assert.Equal(subT, []string{"1/nickname=Bob"}, objects(g.Triples())) {
+				return
+			}
+		}
+	})
+
+	t.Run("should remove assertions which arrive after a later retraction", func(subT *testing.T) {
+		g := New(WithSchema(newTestSchema(subT)))
+		err := apply(
+			g,
+			retractTriple(person("1"), "nickname", nil, hlc(3, "b")),
+			deleteSubject(person("2"), hlc(3, "b")),
+			assertTriple(person("1"), "nickname", str("Bob"), hlc(2, "a")),
+			assertTriple(person("2"), "nickname", str("Rob"), hlc(2, "a")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, g.Triples()) {
+			return
+		}
+	})
+
+	t.Run("should retract every observed value of the predicate", func(subT *testing.T) {
+		g := New(WithSchema(newTestSchema(subT)))
+		err := apply(
+			g,
+			assertTriple(person("1"), "nickname", str("Bob"), hlc(1, "a")),
+			assertTriple(person("1"), "nickname", str("Rob"), hlc(2, "a")),
+			retractTriple(person("1"), "nickname", nil, hlc(3, "a")),
+			assertTriple(person("1"), "nickname", str("Bobby"), hlc(4, "a")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1/nickname=Bobby"}, objects(g.Triples())) {
+			return
+		}
+	})
+
+	t.Run("should not be affected by merging the same state more than once", func(subT *testing.T) {
+		s := newTestSchema(subT)
+		a := New(WithSchema(s))
+		b := New(WithSchema(s))
+
+		err := apply(
+			a,
+			assertTriple(person("1"), "nickname", str("Bob"), hlc(1, "a")),
+			retractTriple(person("1"), "nickname", str("Bob"), hlc(2, "a")),
+			assertTriple(person("1"), "nickname", str("Rob"), hlc(3, "a")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		b.Merge(a)
+		b.Merge(a)
+		b.Merge(b)
+		if !assert.Equal(subT, []string{"1/nickname=Rob"}, objects(b.Triples())) {
+			return
+		}
+	})
+}
+
+type replica struct {
+	graph *Graph
+	clock *Clock
+}
+
+// randomOperation returns an operation, issued by the replica, on a small
+// number of subjects, predicates and values so that replicas frequently
+// operate on the same values concurrently. Half of the retractions list
+// the assertions the replica has observed.
+func randomOperation(rng *rand.Rand, r replica) (*subgraph.Triple, error) {
+	ts := r.clock.Now()
+	subj := person(fmt.Sprint(rng.Intn(2)))
+	pred := []string{"name", "nickname", "alias"}[rng.Intn(3)]
+	obj := str([]string{"Bob", "Rob", "Bobby"}[rng.Intn(3)])
+
+	var t *subgraph.Triple
+	switch rng.Intn(10) {
+	case 0:
+		return deleteSubject(subj, ts), nil
+	case 1:
+		t = retractTriple(subj, pred, nil, ts)
+	case 2, 3:
+		t = retractTriple(subj, pred, obj, ts)
+	default:
+		return assertTriple(subj, pred, obj, ts), nil
+	}
+	if rng.Intn(2) == 0 {
+		return t, nil
+	}
+	observed, err := r.graph.Observed(t.Subject, pred, t.Object)
+	if err != nil {
+		return nil, err
+	}
+	t.Observed = observed
+	return t, nil
+}
+
+func sameTriples(a, b []*subgraph.Triple) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGraph_ReplicaConvergence(t *testing.T) {
+	s := newTestSchema(t)
+
+	converges := func(seed int64) bool {
+		rng := rand.New(rand.NewSource(seed))
+
+		replicas := make([]replica, 3)
+		for i := range replicas {
+			replicas[i] = replica{
+				graph: New(WithSchema(s)),
+				clock: NewClock(fmt.Sprintf("replica-%d", i)),
+			}
+		}
+
+		// Randomly interleave operations on each replica
+		// with replicas exchanging state with each other.
+		var ops []*subgraph.Triple
+		for step := 0; step < 100; step++ {
+			r := replicas[rng.Intn(len(replicas))]
+			if rng.Intn(4) > 0 {
+				op, err := randomOperation(rng, r)
+				if err != nil {
+					t.Log(err)
+					return false
+				}
+				err = r.graph.Apply(op)
+				if err != nil {
+					t.Log(err)
+					return false
+				}
+				ops = append(ops, op)
+				continue
+			}
+			r.graph.Merge(replicas[rng.Intn(len(replicas))].graph)
+		}
+
+		// Once every replica has seen the state of every
+		// other replica they must all be the same.
+		for _, r := range replicas {
+			for _, other := range replicas {
+				r.graph.Merge(other.graph)
+			}
+		}
+		for _, r := range replicas {
+			for _, other := range replicas {
+				r.graph.Merge(other.graph)
+			}
+		}
+
+		expected := replicas[0].graph.Triples()
+		for _, r := range replicas[1:] {
+			if !sameTriples(expected, r.graph.Triples()) {
+				return false
+			}
+		}
+
+		// Applying every operation in any order must converge to the same state.
+		g := New(WithSchema(s))
+		for _, i := range rng.Perm(len(ops)) {
+			err := g.Apply(ops[i])
+			if err != nil {
+				t.Log(err)
+				return false
+			}
+		}
+		return sameTriples(expected, g.Triples())
+	}
+
+	err := quick.Check(converges, &quick.Config{MaxCount: 200})
+	if !assert.Nil(t, err) {
+		return
+	}
+}
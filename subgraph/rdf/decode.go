@@ -0,0 +1,846 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError describes malformed RDF.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("rdf: line %d: %s", e.Line, e.Msg)
+}
+
+// Decoder reads statements from N-Triples, N-Quads or Turtle.
+// Statements are read as they are needed so any amount of RDF
+// can be decoded in constant memory.
+type Decoder struct {
+	r      *bufio.Reader
+	format Format
+	line   int
+
+	// Turtle state
+	base     *url.URL
+	prefixes map[string]string
+	bnodes   int
+
+	queue []Statement
+}
+
+// NewDecoder returns a Decoder which reads from r. By default, N-Triples are decoded.
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	o := options{format: NTriples}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Decoder{
+		r:        bufio.NewReader(r),
+		format:   o.format,
+		line:     1,
+		prefixes: make(map[string]string),
+	}
+}
+
+// Decode returns the next statement. It returns io.EOF once every statement has been read.
+func (d *Decoder) Decode() (Statement, error) {
+	for len(d.queue) == 0 {
+		err := d.next()
+		if err != nil {
+			return Statement{}, err
+		}
+	}
+
+	st := d.queue[0]
+	d.queue = d.queue[1:]
+	return st, nil
+}
+
+func (d *Decoder) next() error {
+	d.queue = d.queue[:0]
+	err := d.skipSpace()
+	if err != nil {
+		return err
+	}
+
+	if d.format == Turtle {
+		err = d.turtleStatement()
+	} else {
+		err = d.quad()
+	}
+	if err == io.EOF {
+		return d.errorf("unexpected end of input")
+	}
+	return err
+}
+
+func (d *Decoder) emit(s, p, o Term) {
+	d.queue = append(d.queue, Statement{Subject: s, Predicate: p, Object: o})
+}
+
+// quad reads a single N-Triples or N-Quads statement.
+func (d *Decoder) quad() error {
+	var st Statement
+	var err error
+	st.Subject, err = d.ntTerm(IRI, BlankNode)
+	if err != nil {
+		return err
+	}
+	st.Predicate, err = d.ntTerm(IRI)
+	if err != nil {
+		return err
+	}
+	st.Object, err = d.ntTerm(IRI, BlankNode, Literal)
+	if err != nil {
+		return err
+	}
+
+	r, err := d.peek()
+	if err != nil {
+		return err
+	}
+	if r != '.' {
+		if d.format != NQuads {
+			return d.errorf("expected '.' but found %q", r)
+		}
+		st.Graph, err = d.ntTerm(IRI, BlankNode)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.expect('.')
+	if err != nil {
+		return err
+	}
+	d.queue = append(d.queue, st)
+	return nil
+}
+
+func (d *Decoder) ntTerm(kinds ...TermKind) (Term, error) {
+	r, err := d.peek()
+	if err != nil {
+		return Term{}, err
+	}
+
+	var t Term
+	switch r {
+	case '<':
+		t, err = d.iri()
+	case '_':
+		t, err = d.blankNode()
+	case '"':
+		t, err = d.literal()
+	default:
+		return Term{}, d.errorf("unexpected %q", r)
+	}
+	if err != nil {
+		return Term{}, err
+	}
+
+	for _, kind := range kinds {
+		if t.Kind == kind {
+			return t, d.skipSpace()
+		}
+	}
+	return Term{}, d.errorf("unexpected %s", t)
+}
+
+// turtleStatement reads a single Turtle directive or set of triples.
+func (d *Decoder) turtleStatement() error {
+	r, err := d.peek()
+	if err != nil {
+		return err
+	}
+	if r == '@' {
+		d.read()
+		word, err := d.word()
+		if err != nil {
+			return err
+		}
+		return d.directive(word, true)
+	}
+
+	var subj Term
+	switch r {
+	case '<':
+		subj, err = d.iri()
+	case '_':
+		subj, err = d.blankNode()
+	case '(':
+		subj, err = d.collection()
+	case '[':
+		subj, err = d.blankNodePropertyList()
+		if err != nil {
+			return err
+		}
+		err = d.skipSpace()
+		if err != nil {
+			return err
+		}
+		if r, _ := d.peek(); r == '.' {
+			d.read()
+			return nil
+		}
+	default:
+		var word string
+		word, err = d.word()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(word, ":") {
+			return d.directive(word, false)
+		}
+		subj, err = d.prefixedName(word)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = d.predicateObjectList(subj)
+	if err != nil {
+		return err
+	}
+	return d.expect('.')
+}
+
+// directive reads the rest of a @prefix or @base directive,
+// or their SPARQL style PREFIX or BASE equivalents.
+func (d *Decoder) directive(word string, at bool) error {
+	if at && word != "prefix" && word != "base" || !at && !strings.EqualFold(word, "prefix") && !strings.EqualFold(word, "base") {
+		return d.errorf("unexpected %q", word)
+	}
+
+	err := d.skipSpace()
+	if err != nil {
+		return err
+	}
+
+	var prefix string
+	if strings.EqualFold(word, "prefix") {
+		prefix, err = d.word()
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(prefix, ":") || strings.Count(prefix, ":") != 1 {
+			return d.errorf("invalid prefix %q", prefix)
+		}
+		err = d.skipSpace()
+		if err != nil {
+			return err
+		}
+	}
+
+	iri, err := d.iri()
+	if err != nil {
+		return err
+	}
+	if prefix != "" {
+		d.prefixes[strings.TrimSuffix(prefix, ":")] = iri.Value
+	} else {
+		d.base, err = url.Parse(iri.Value)
+		if err != nil {
+			return d.errorf("invalid base %q", iri.Value)
+		}
+	}
+
+	if !at {
+		return nil
+	}
+	return d.expect('.')
+}
+
+func (d *Decoder) predicateObjectList(subj Term) error {
+	for {
+		err := d.skipSpace()
+		if err != nil {
+			return err
+		}
+		pred, err := d.verb()
+		if err != nil {
+			return err
+		}
+
+		err = d.objectList(subj, pred)
+		if err != nil {
+			return err
+		}
+
+		r, err := d.peek()
+		if err != nil {
+			return err
+		}
+		if r != ';' {
+			return nil
+		}
+		for r == ';' {
+			d.read()
+			err = d.skipSpace()
+			if err != nil {
+				return err
+			}
+			r, err = d.peek()
+			if err != nil {
+				return err
+			}
+		}
+		if r == '.' || r == ']' {
+			return nil
+		}
+	}
+}
+
+func (d *Decoder) objectList(subj, pred Term) error {
+	for {
+		err := d.skipSpace()
+		if err != nil {
+			return err
+		}
+		obj, err := d.object()
+		if err != nil {
+			return err
+		}
+		d.emit(subj, pred, obj)
+
+		err = d.skipSpace()
+		if err != nil {
+			return err
+		}
+		r, err := d.peek()
+		if err != nil {
+			return err
+		}
+		if r != ',' {
+			return nil
+		}
+		d.read()
+	}
+}
+
+func (d *Decoder) verb() (Term, error) {
+	r, err := d.peek()
+	if err != nil {
+		return Term{}, err
+	}
+	if r == '<' {
+		return d.iri()
+	}
+
+	word, err := d.word()
+	if err != nil {
+		return Term{}, err
+	}
+	if word == "a" {
+		return Term{Kind: IRI, Value: RDFType}, nil
+	}
+	return d.prefixedName(word)
+}
+
+func (d *Decoder) object() (Term, error) {
+	r, err := d.peek()
+	if err != nil {
+		return Term{}, err
+	}
+
+	switch {
+	case r == '<':
+		return d.iri()
+	case r == '_':
+		return d.blankNode()
+	case r == '[':
+		return d.blankNodePropertyList()
+	case r == '(':
+		return d.collection()
+	case r == '"' || r == '\'':
+		return d.literal()
+	case r == '+' || r == '-' || r == '.' || r >= '0' && r <= '9':
+		return d.number()
+	}
+
+	word, err := d.word()
+	if err != nil {
+		return Term{}, err
+	}
+	switch word {
+	case "true", "false":
+		return Term{Kind: Literal, Value: word, Datatype: XSDBoolean}, nil
+	}
+	return d.prefixedName(word)
+}
+
+func (d *Decoder) blankNodePropertyList() (Term, error) {
+	err := d.expect('[')
+	if err != nil {
+		return Term{}, err
+	}
+	subj := d.newBlankNode()
+
+	err = d.skipSpace()
+	if err != nil {
+		return Term{}, err
+	}
+	if r, _ := d.peek(); r == ']' {
+		d.read()
+		return subj, nil
+	}
+
+	err = d.predicateObjectList(subj)
+	if err != nil {
+		return Term{}, err
+	}
+	return subj, d.expect(']')
+}
+
+func (d *Decoder) collection() (Term, error) {
+	err := d.expect('(')
+	if err != nil {
+		return Term{}, err
+	}
+
+	var items []Term
+	for {
+		err = d.skipSpace()
+		if err != nil {
+			return Term{}, err
+		}
+		if r, _ := d.peek(); r == ')' {
+			d.read()
+			break
+		}
+
+		item, err := d.object()
+		if err != nil {
+			return Term{}, err
+		}
+		items = append(items, item)
+	}
+
+	head := Term{Kind: IRI, Value: RDFNil}
+	for i := len(items) - 1; i >= 0; i-- {
+		node := d.newBlankNode()
+		d.emit(node, Term{Kind: IRI, Value: RDFFirst}, items[i])
+		d.emit(node, Term{Kind: IRI, Value: RDFRest}, head)
+		head = node
+	}
+	return head, nil
+}
+
+// newBlankNode returns a blank node with a label which
+// cannot collide with any label written in the document.
+func (d *Decoder) newBlankNode() Term {
+	d.bnodes += 1
+	return Term{Kind: BlankNode, Value: "genid#" + strconv.Itoa(d.bnodes)}
+}
+
+func (d *Decoder) prefixedName(word string) (Term, error) {
+	prefix, local, ok := strings.Cut(word, ":")
+	if !ok {
+		return Term{}, d.errorf("unexpected %q", word)
+	}
+	ns, ok := d.prefixes[prefix]
+	if !ok {
+		return Term{}, d.errorf("undefined prefix %q", prefix)
+	}
+	return Term{Kind: IRI, Value: ns + local}, nil
+}
+
+// word reads a prefixed name or keyword.
+func (d *Decoder) word() (string, error) {
+	var sb strings.Builder
+	err := d.name(&sb, true)
+	if err != nil {
+		return "", err
+	}
+	if sb.Len() == 0 {
+		r, err := d.peek()
+		if err != nil {
+			return "", err
+		}
+		return "", d.errorf("unexpected %q", r)
+	}
+	return sb.String(), nil
+}
+
+// name reads name characters, excluding any trailing dots
+// since they end the statement rather than the name.
+func (d *Decoder) name(sb *strings.Builder, prefixed bool) error {
+	isName := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '_' || c == '-' || c >= utf8.RuneSelf ||
+			prefixed && (c == ':' || c == '%' || c == '\\')
+	}
+
+	for {
+		b, err := d.r.Peek(1)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		c := b[0]
+		switch {
+		case c == '.':
+			b, err := d.r.Peek(2)
+			if err != nil || !isName(b[1]) {
+				return nil
+			}
+		case !isName(c):
+			return nil
+		}
+
+		r, err := d.read()
+		if err != nil {
+			return err
+		}
+		if r == '\\' {
+			r, err = d.read()
+			if err != nil {
+				return err
+			}
+			if !strings.ContainsRune("_~.-!$&'()*+,;=/?#@%", r) {
+				return d.errorf("invalid escape %q", "\\"+string(r))
+			}
+		}
+		sb.WriteRune(r)
+	}
+}
+
+func (d *Decoder) iri() (Term, error) {
+	err := d.expect('<')
+	if err != nil {
+		return Term{}, err
+	}
+
+	var sb strings.Builder
+	for {
+		r, err := d.read()
+		if err != nil {
+			return Term{}, err
+		}
+
+		switch {
+		case r == '>':
+			return Term{Kind: IRI, Value: d.resolve(sb.String())}, nil
+		case r == '\\':
+			r, err = d.unicodeEscape()
+			if err != nil {
+				return Term{}, err
+			}
+		case r <= ' ' || strings.ContainsRune("<\"{}|^`", r):
+			return Term{}, d.errorf("invalid character %q in IRI", r)
+		}
+		sb.WriteRune(r)
+	}
+}
+
+// resolve resolves a relative IRI against the base declared in a Turtle document.
+func (d *Decoder) resolve(iri string) string {
+	if d.base == nil {
+		return iri
+	}
+	u, err := url.Parse(iri)
+	if err != nil || u.IsAbs() {
+		return iri
+	}
+	return d.base.ResolveReference(u).String()
+}
+
+func (d *Decoder) blankNode() (Term, error) {
+	err := d.expect('_')
+	if err != nil {
+		return Term{}, err
+	}
+	err = d.expect(':')
+	if err != nil {
+		return Term{}, err
+	}
+
+	var sb strings.Builder
+	err = d.name(&sb, false)
+	if err != nil {
+		return Term{}, err
+	}
+	if sb.Len() == 0 {
+		return Term{}, d.errorf("blank node label must not be empty")
+	}
+	return Term{Kind: BlankNode, Value: sb.String()}, nil
+}
+
+func (d *Decoder) literal() (Term, error) {
+	value, err := d.str()
+	if err != nil {
+		return Term{}, err
+	}
+	t := Term{Kind: Literal, Value: value, Datatype: XSDString}
+
+	r, err := d.peek()
+	if err == io.EOF {
+		return t, nil
+	}
+	if err != nil {
+		return Term{}, err
+	}
+
+	switch r {
+	case '@':
+		d.read()
+		var sb strings.Builder
+		err = d.name(&sb, false)
+		if err != nil {
+			return Term{}, err
+		}
+		if sb.Len() == 0 {
+			return Term{}, d.errorf("language tag must not be empty")
+		}
+		t.Lang = sb.String()
+		t.Datatype = RDFLangString
+	case '^':
+		d.read()
+		err = d.expect('^')
+		if err != nil {
+			return Term{}, err
+		}
+
+		var datatype Term
+		if r, _ := d.peek(); r == '<' || d.format != Turtle {
+			datatype, err = d.iri()
+		} else {
+			var word string
+			word, err = d.word()
+			if err == nil {
+				datatype, err = d.prefixedName(word)
+			}
+		}
+		if err != nil {
+			return Term{}, err
+		}
+		t.Datatype = datatype.Value
+	}
+	return t, nil
+}
+
+// str reads a quoted string. Only Turtle allows single
+// quotes and long strings which may span lines.
+func (d *Decoder) str() (string, error) {
+	q, err := d.read()
+	if err != nil {
+		return "", err
+	}
+	if q != '"' && (q != '\'' || d.format != Turtle) {
+		return "", d.errorf("unexpected %q", q)
+	}
+
+	long := false
+	if b, _ := d.r.Peek(2); d.format == Turtle && len(b) == 2 && rune(b[0]) == q && rune(b[1]) == q {
+		d.read()
+		d.read()
+		long = true
+	}
+
+	var sb strings.Builder
+	for {
+		r, err := d.peek()
+		if err != nil {
+			return "", err
+		}
+		if (r == '\n' || r == '\r') && !long {
+			return "", d.errorf("unterminated string")
+		}
+		d.read()
+
+		switch {
+		case r == q && !long:
+			return sb.String(), nil
+		case r == q:
+			if b, _ := d.r.Peek(2); len(b) == 2 && rune(b[0]) == q && rune(b[1]) == q {
+				d.read()
+				d.read()
+				return sb.String(), nil
+			}
+		case r == '\\':
+			r, err = d.escape()
+			if err != nil {
+				return "", err
+			}
+		}
+		sb.WriteRune(r)
+	}
+}
+
+func (d *Decoder) escape() (rune, error) {
+	r, err := d.peek()
+	if err != nil {
+		return 0, err
+	}
+
+	escapes := map[rune]rune{'t': '\t', 'b': '\b', 'n': '\n', 'r': '\r', 'f': '\f', '"': '"', '\'': '\'', '\\': '\\'}
+	if e, ok := escapes[r]; ok {
+		d.read()
+		return e, nil
+	}
+	return d.unicodeEscape()
+}
+
+func (d *Decoder) unicodeEscape() (rune, error) {
+	r, err := d.read()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 4
+	switch r {
+	case 'u':
+	case 'U':
+		n = 8
+	default:
+		return 0, d.errorf("invalid escape %q", "\\"+string(r))
+	}
+
+	hex := make([]rune, n)
+	for i := range hex {
+		hex[i], err = d.read()
+		if err != nil {
+			return 0, err
+		}
+	}
+	v, err := strconv.ParseUint(string(hex), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(v)) {
+		return 0, d.errorf("invalid escape %q", "\\"+string(r)+string(hex))
+	}
+	return rune(v), nil
+}
+
+// number reads a Turtle integer, decimal or double.
+func (d *Decoder) number() (Term, error) {
+	var sb strings.Builder
+	digits := func() int {
+		n := 0
+		for {
+			b, err := d.r.Peek(1)
+			if err != nil || b[0] < '0' || b[0] > '9' {
+				return n
+			}
+			r, _ := d.read()
+			sb.WriteRune(r)
+			n += 1
+		}
+	}
+
+	if r, _ := d.peek(); r == '+' || r == '-' {
+		d.read()
+		sb.WriteRune(r)
+	}
+
+	datatype := XSDInteger
+	n := digits()
+	if b, _ := d.r.Peek(2); len(b) == 2 && b[0] == '.' && b[1] >= '0' && b[1] <= '9' {
+		d.read()
+		sb.WriteByte('.')
+		n += digits()
+		datatype = XSDDecimal
+	}
+	if n == 0 {
+		return Term{}, d.errorf("invalid number %q", sb.String())
+	}
+
+	if r, err := d.peek(); err == nil && (r == 'e' || r == 'E') {
+		d.read()
+		sb.WriteRune(r)
+		if r, _ := d.peek(); r == '+' || r == '-' {
+			d.read()
+			sb.WriteRune(r)
+		}
+		if digits() == 0 {
+			return Term{}, d.errorf("invalid number %q", sb.String())
+		}
+		datatype = XSDDouble
+	}
+	return Term{Kind: Literal, Value: sb.String(), Datatype: datatype}, nil
+}
+
+// skipSpace skips whitespace and comments.
+// It returns io.EOF if there is nothing else to read.
+func (d *Decoder) skipSpace() error {
+	for {
+		r, err := d.peek()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case r == '#':
+			for r != '\n' {
+				r, err = d.read()
+				if err != nil {
+					return err
+				}
+			}
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			d.read()
+		default:
+			return nil
+		}
+	}
+}
+
+func (d *Decoder) expect(want rune) error {
+	err := d.skipSpace()
+	if err != nil {
+		return err
+	}
+	r, err := d.read()
+	if err != nil {
+		return err
+	}
+	if r != want {
+		return d.errorf("expected %q but found %q", want, r)
+	}
+	return nil
+}
+
+func (d *Decoder) peek() (rune, error) {
+	r, _, err := d.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	return r, d.r.UnreadRune()
+}
+
+func (d *Decoder) read() (rune, error) {
+	r, _, err := d.r.ReadRune()
+	if r == '\n' {
+		d.line += 1
+	}
+	return r, err
+}
+
+func (d *Decoder) errorf(format string, args ...any) error {
+	return &SyntaxError{Line: d.line, Msg: fmt.Sprintf(format, args...)}
+}
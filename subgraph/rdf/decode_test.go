@@ -0,0 +1,170 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeAll(src string, opts ...Option) ([]Statement, error) {
+	dec := NewDecoder(strings.NewReader(src), opts...)
+
+	var sts []Statement
+	for {
+		st, err := dec.Decode()
+		if err == io.EOF {
+			return sts, nil
+		}
+		if err != nil {
+			return sts, err
+		}
+		sts = append(sts, st)
+	}
+}
+
+func iri(v string) Term {
+	return Term{Kind: IRI, Value: v}
+}
+
+func blank(v string) Term {
+	return Term{Kind: BlankNode, Value: v}
+}
+
+func literal(v, datatype string) Term {
+	return Term{Kind: Literal, Value: v, Datatype: datatype}
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	t.Run("should decode N-Triples", func(subT *testing.T) {
+		src := strings.Join([]string{
+			`# people`,
+			`<http://example.com/alice> <http://example.com/name> "Alice \"Al\"é" .`,
+			`<http://example.com/alice> <http://example.com/age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+			``,
+			`_:b0 <http://example.com/greeting> "Bonjour"@fr-CA.`,
+			`_:b0 <http://example.com/knows> <http://example.com/alice> . # trailing comment`,
+		}, "\n")
+
+		sts, err := decodeAll(src)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		expected := []Statement{
+			{Subject: iri("http://example.com/alice"), Predicate: iri("http://example.com/name"), Object: literal(`Alice "Al"é`, XSDString)},
+			{Subject: iri("http://example.com/alice"), Predicate: iri("http://example.com/age"), Object: literal("42", XSDInteger)},
+			{Subject: blank("b0"), Predicate: iri("http://example.com/greeting"), Object: Term{Kind: Literal, Value: "Bonjour", Datatype: RDFLangString, Lang: "fr-CA"}},
+			{Subject: blank("b0"), Predicate: iri("http://example.com/knows"), Object: iri("http://example.com/alice")},
+		}
+		if !assert.Equal(subT, expected, sts) {
+			return
+		}
+	})
+
+	t.Run("should decode the graph of N-Quads", func(subT *testing.T) {
+		src := `<http://example.com/alice> <http://example.com/name> "Alice" <http://example.com/people> .`
+
+		sts, err := decodeAll(src, WithFormat(NQuads))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, sts, 1) {
+			return
+		}
+		if !assert.Equal(subT, iri("http://example.com/people"), sts[0].Graph) {
+			return
+		}
+
+		_, err = decodeAll(src)
+		var serr *SyntaxError
+		if !assert.True(subT, errors.As(err, &serr), "expected a syntax error for a graph in N-Triples: %v", err) {
+			return
+		}
+	})
+
+	t.Run("should decode Turtle", func(subT *testing.T) {
+		src := strings.Join([]string{
+			`@prefix ex: <http://example.com/> .`,
+			`@base <http://example.com/people/> .`,
+			`PREFIX foaf: <http://xmlns.com/foaf/0.1/>`,
+			``,
+			`ex:alice a foaf:Person ;`,
+			`  foaf:name "Alice"@en, 'Al' ;`,
+			`  foaf:age 42 ; ex:height 1.5 ; ex:mass -6e1 ; ex:admin true ;`,
+			`  foaf:knows <bob>, [ foaf:name """Carol`,
+			`"C" Smith""" ] ;`,
+			`  ex:list ( 1 ex:x ) ;`,
+			`.`,
+			`[] ex:p ex:o.`,
+		}, "\n")
+
+		sts, err := decodeAll(src, WithFormat(Turtle))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		alice := iri("http://example.com/alice")
+		expected := []Statement{
+			{Subject: alice, Predicate: iri(RDFType), Object: iri("http://xmlns.com/foaf/0.1/Person")},
+			{Subject: alice, Predicate: iri("http://xmlns.com/foaf/0.1/name"), Object: Term{Kind: Literal, Value: "Alice", Datatype: RDFLangString, Lang: "en"}},
+			{Subject: alice, Predicate: iri("http://xmlns.com/foaf/0.1/name"), Object: literal("Al", XSDString)},
+			{Subject: alice, Predicate: iri("http://xmlns.com/foaf/0.1/age"), Object: literal("42", XSDInteger)},
+			{Subject: alice, Predicate: iri("http://example.com/height"), Object: literal("1.5", XSDDecimal)},
+			{Subject: alice, Predicate: iri("http://example.com/mass"), Object: literal("-6e1", XSDDouble)},
+			{Subject: alice, Predicate: iri("http://example.com/admin"), Object: literal("true", XSDBoolean)},
+			{Subject: alice, Predicate: iri("http://xmlns.com/foaf/0.1/knows"), Object: iri("http://example.com/people/bob")},
+			{Subject: blank("genid#1"), Predicate: iri("http://xmlns.com/foaf/0.1/name"), Object: literal("Carol\n\"C\" Smith", XSDString)},
+			{Subject: alice, Predicate: iri("http://xmlns.com/foaf/0.1/knows"), Object: blank("genid#1")},
+			{Subject: blank("genid#2"), Predicate: iri(RDFFirst), Object: iri("http://example.com/x")},
+			{Subject: blank("genid#2"), Predicate: iri(RDFRest), Object: iri(RDFNil)},
+			{Subject: blank("genid#3"), Predicate: iri(RDFFirst), Object: literal("1", XSDInteger)},
+			{Subject: blank("genid#3"), Predicate: iri(RDFRest), Object: blank("genid#2")},
+			{Subject: alice, Predicate: iri("http://example.com/list"), Object: blank("genid#3")},
+			{Subject: blank("genid#4"), Predicate: iri("http://example.com/p"), Object: iri("http://example.com/o")},
+		}
+		if !assert.Equal(subT, expected, sts) {
+			return
+		}
+	})
+
+	t.Run("should report the line of a syntax error", func(subT *testing.T) {
+		testCases := map[string]string{
+			"undefined prefix":     "@prefix ex: <http://example.com/> .\n\nfoo:a ex:b ex:c .",
+			"unterminated string":  "<http://example.com/a>\n\n<http://example.com/b> \"c\n\" .",
+			"missing dot":          "<http://example.com/a> <http://example.com/b> <http://example.com/c>\n\n<http://example.com/d>",
+			"invalid iri":          "<http://example.com/a>\n\n<http://example.com/ b> <http://example.com/c> .",
+			"unexpected end input": "<http://example.com/a>\n\n<http://example.com/b>",
+		}
+
+		for name, src := range testCases {
+			_, err := decodeAll(src, WithFormat(Turtle))
+
+			var serr *SyntaxError
+			if !assert.True(subT, errors.As(err, &serr), "%s: expected a syntax error: %v", name, err) {
+				return
+			}
+			if !assert.Equal(subT, 3, serr.Line, name) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+)
+
+// ErrUnsupportedFormat is returned when encoding to a format other than N-Triples or N-Quads.
+var ErrUnsupportedFormat = errors.New("rdf: only N-Triples and N-Quads can be encoded")
+
+// Encoder writes subgraphs as N-Triples or N-Quads. Each triple is written
+// as soon as it is encoded so any number of subgraphs can be encoded in
+// constant memory.
+//
+// RDF has no way to retract a statement so only assertions are written.
+// The facets of an assertion are written on a reification of it, i.e. a
+// blank node of type rdf:Statement, with a predicate per facet named the
+// same way as triple predicates. Timestamps are not written.
+type Encoder struct {
+	w      *bufio.Writer
+	base   string
+	format Format
+
+	// statements counts reified statements so their blank nodes are unique
+	statements int
+}
+
+// NewEncoder returns an Encoder which writes to w.
+func NewEncoder(w io.Writer, opts ...Option) (*Encoder, error) {
+	o := options{base: DefaultBaseIRI, format: NTriples}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.format != NTriples && o.format != NQuads {
+		return nil, ErrUnsupportedFormat
+	}
+	err := CheckIRI(o.base)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Encoder{
+		w:      bufio.NewWriter(w),
+		base:   o.base,
+		format: o.format,
+	}, nil
+}
+
+// Encode writes every assertion in the subgraph. When encoding N-Quads,
+// the source URI of the subgraph, if any, is used as the graph label.
+// The subgraph is expected to have already passed validate.Subgraph.
+func (e *Encoder) Encode(g *subgraph.Subgraph) error {
+	var label string
+	if e.format == NQuads && g.GetProvenance().GetSourceUri() != "" {
+		err := CheckIRI(g.Provenance.SourceUri)
+		if err != nil {
+			return err
+		}
+		label = " <" + g.Provenance.SourceUri + ">"
+	}
+
+	for _, t := range g.GetTriples() {
+		if t.GetOperation() != subgraph.Operation_ASSERT {
+			continue
+		}
+
+		subj := "<" + e.SubjectIRI(t.Subject) + ">"
+		pred := "<" + e.PredicateIRI(t.Predicate) + ">"
+		obj, err := e.object(t.Object)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(e.w, "%s %s %s%s .\n", subj, pred, obj, label)
+		if err != nil {
+			return err
+		}
+
+		err = e.facets(subj, pred, obj, label, t.Facets)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// facets writes a reification of the statement with every facet, in key order.
+func (e *Encoder) facets(subj, pred, obj, label string, fs map[string]*subgraph.FacetValue) error {
+	if len(fs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(fs))
+	for key := range fs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	stmts := [][2]string{
+		{"<" + RDFType + ">", "<" + RDFStatement + ">"},
+		{"<" + RDFSubject + ">", subj},
+		{"<" + RDFPredicate + ">", pred},
+		{"<" + RDFObject + ">", obj},
+	}
+	for _, key := range keys {
+		v, err := facetValue(fs[key])
+		if err != nil {
+			return err
+		}
+		stmts = append(stmts, [2]string{"<" + e.base + url.PathEscape(key) + ">", v})
+	}
+
+	e.statements += 1
+	node := "_:f" + strconv.Itoa(e.statements)
+	for _, stmt := range stmts {
+		_, err := fmt.Fprintf(e.w, "%s %s %s%s .\n", node, stmt[0], stmt[1], label)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func facetValue(v *subgraph.FacetValue) (string, error) {
+	switch x := v.GetValue().(type) {
+	case *subgraph.FacetValue_String_:
+		return Quote(x.String_), nil
+	case *subgraph.FacetValue_Int64:
+		return typed(strconv.FormatInt(x.Int64, 10), XSDLong), nil
+	case *subgraph.FacetValue_Float64:
+		return typed(strconv.FormatFloat(x.Float64, 'G', -1, 64), XSDDouble), nil
+	case *subgraph.FacetValue_Bool:
+		return typed(strconv.FormatBool(x.Bool), XSDBoolean), nil
+	case *subgraph.FacetValue_Timestamp:
+		return typed(x.Timestamp.AsTime().Format(time.RFC3339Nano), XSDDateTime), nil
+	default:
+		return "", fmt.Errorf("rdf: unsupported facet value: %T", x)
+	}
+}
+
+// Flush writes any buffered statements to the underlying writer.
+func (e *Encoder) Flush() error {
+	return e.w.Flush()
+}
+
+// SubjectIRI returns the IRI of the subject.
+func (e *Encoder) SubjectIRI(s *subgraph.Subject) string {
+	return e.base + url.PathEscape(s.GetType()) + "/" + url.PathEscape(s.GetTuid())
+}
+
+// PredicateIRI returns the IRI of the predicate.
+func (e *Encoder) PredicateIRI(p *subgraph.Predicate) string {
+	return e.base + url.PathEscape(p.GetName())
+}
+
+func (e *Encoder) object(o *subgraph.Object) (string, error) {
+	switch x := o.GetValue().(type) {
+	case *subgraph.Object_Subject:
+		return "<" + e.SubjectIRI(x.Subject) + ">", nil
+	case *subgraph.Object_String_:
+		return Quote(x.String_), nil
+	case *subgraph.Object_Int64:
+		return typed(strconv.FormatInt(x.Int64, 10), XSDLong), nil
+	case *subgraph.Object_Float64:
+		return typed(strconv.FormatFloat(x.Float64, 'G', -1, 64), XSDDouble), nil
+	case *subgraph.Object_Bool:
+		return typed(strconv.FormatBool(x.Bool), XSDBoolean), nil
+	case *subgraph.Object_Timestamp:
+		return typed(x.Timestamp.AsTime().Format(time.RFC3339Nano), XSDDateTime), nil
+	case *subgraph.Object_Bytes:
+		return typed(base64.StdEncoding.EncodeToString(x.Bytes), XSDBase64Binary), nil
+	case *subgraph.Object_Geo:
+		wkt := fmt.Sprintf(
+			"POINT(%s %s)",
+			strconv.FormatFloat(x.Geo.GetLongitude(), 'f', -1, 64),
+			strconv.FormatFloat(x.Geo.GetLatitude(), 'f', -1, 64),
+		)
+		return typed(wkt, WKTLiteral), nil
+	case *subgraph.Object_LangString:
+		return Quote(x.LangString.GetValue()) + "@" + x.LangString.GetLang(), nil
+	default:
+		return "", fmt.Errorf("rdf: unsupported object: %T", x)
+	}
+}
+
+func typed(v, datatype string) string {
+	return Quote(v) + "^^<" + datatype + ">"
+}
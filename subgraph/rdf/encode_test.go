@@ -0,0 +1,191 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newObjectsSubgraph() *subgraph.Subgraph {
+	objects := []*subgraph.Object{
+		{Value: &subgraph.Object_Subject{Subject: &subgraph.Subject{Type: "Person", Tuid: "2 3"}}},
+		{Value: &subgraph.Object_String_{String_: "Bob \"The\" Builder\n"}},
+		{Value: &subgraph.Object_Int64{Int64: -42}},
+		{Value: &subgraph.Object_Float64{Float64: 1.5}},
+		{Value: &subgraph.Object_Bool{Bool: true}},
+		{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.New(time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC))}},
+		{Value: &subgraph.Object_Bytes{Bytes: []byte("megamind")}},
+		{Value: &subgraph.Object_Geo{Geo: &subgraph.GeoPoint{Latitude: 51.5, Longitude: -0.12}}},
+		{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Bonjour", Lang: "fr"}}},
+	}
+
+	g := &subgraph.Subgraph{
+		Provenance: &subgraph.Provenance{SourceUri: "https://example.com/people"},
+	}
+	for _, object := range objects {
+		g.Triples = append(g.Triples, &subgraph.Triple{
+			Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+			Predicate: &subgraph.Predicate{Name: "value"},
+			Object:    object,
+		})
+	}
+	return g
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	t.Run("should encode every kind of object as N-Triples", func(subT *testing.T) {
+		var buf bytes.Buffer
+		enc, err := NewEncoder(&buf, WithBaseIRI("https://example.com/"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		err = enc.Encode(newObjectsSubgraph())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = enc.Flush()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		prefix := "<https://example.com/Person/1> <https://example.com/value> "
+		expected := strings.Join([]string{
+			prefix + `<https://example.com/Person/2%203> .`,
+			prefix + `"Bob \"The\" Builder\n" .`,
+			prefix + `"-42"^^<http://www.w3.org/2001/XMLSchema#long> .`,
+			prefix + `"1.5"^^<http://www.w3.org/2001/XMLSchema#double> .`,
+			prefix + `"true"^^<http://www.w3.org/2001/XMLSchema#boolean> .`,
+			prefix + `"2022-09-01T12:00:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .`,
+			prefix + `"bWVnYW1pbmQ="^^<http://www.w3.org/2001/XMLSchema#base64Binary> .`,
+			prefix + `"POINT(-0.12 51.5)"^^<http://www.opengis.net/ont/geosparql#wktLiteral> .`,
+			prefix + `"Bonjour"@fr .`,
+			``,
+		}, "\n")
+		if !assert.Equal(subT, expected, buf.String()) {
+			return
+		}
+	})
+
+	t.Run("should label N-Quads with the source of the subgraph", func(subT *testing.T) {
+		var buf bytes.Buffer
+		enc, err := NewEncoder(&buf, WithFormat(NQuads))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		g := newObjectsSubgraph()
+		g.Triples = g.Triples[1:2]
+		err = enc.Encode(g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = enc.Flush()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		expected := `<urn:megamind:Person/1> <urn:megamind:value> "Bob \"The\" Builder\n" <https://example.com/people> .` + "\n"
+		if !assert.Equal(subT, expected, buf.String()) {
+			return
+		}
+	})
+
+	t.Run("should write facets on a reification of the statement", func(subT *testing.T) {
+		var buf bytes.Buffer
+		enc, err := NewEncoder(&buf, WithBaseIRI("https://example.com/"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		g := newObjectsSubgraph()
+		g.Triples = g.Triples[2:3]
+		g.Triples[0].Facets = map[string]*subgraph.FacetValue{
+			"since":  {Value: &subgraph.FacetValue_Int64{Int64: 2020}},
+			"source": {Value: &subgraph.FacetValue_String_{String_: "census"}},
+		}
+		for i := 0; i < 2; i++ {
+			err = enc.Encode(g)
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+		err = enc.Flush()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		var expected []string
+		for _, node := range []string{"_:f1", "_:f2"} {
+			expected = append(expected,
+				`<https://example.com/Person/1> <https://example.com/value> "-42"^^<http://www.w3.org/2001/XMLSchema#long> .`,
+				node+` <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/1999/02/22-rdf-syntax-ns#Statement> .`,
+				node+` <http://www.w3.org/1999/02/22-rdf-syntax-ns#subject> <https://example.com/Person/1> .`,
+				node+` <http://www.w3.org/1999/02/22-rdf-syntax-ns#predicate> <https://example.com/value> .`,
+				node+` <http://www.w3.org/1999/02/22-rdf-syntax-ns#object> "-42"^^<http://www.w3.org/2001/XMLSchema#long> .`,
+				node+` <https://example.com/since> "2020"^^<http://www.w3.org/2001/XMLSchema#long> .`,
+				node+` <https://example.com/source> "census" .`,
+			)
+		}
+		if !assert.Equal(subT, strings.Join(expected, "\n")+"\n", buf.String()) {
+			return
+		}
+	})
+
+	t.Run("should skip retractions and deleted subjects", func(subT *testing.T) {
+		var buf bytes.Buffer
+		enc, err := NewEncoder(&buf)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		g := newObjectsSubgraph()
+		g.Triples[0].Operation = subgraph.Operation_RETRACT
+		g.Triples = append(g.Triples[:1], &subgraph.Triple{
+			Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+			Operation: subgraph.Operation_DELETE_SUBJECT,
+		})
+		err = enc.Encode(g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = enc.Flush()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, buf.String()) {
+			return
+		}
+	})
+
+	t.Run("should reject an invalid base IRI", func(subT *testing.T) {
+		for _, base := range []string{"", "relative/", "https://example.com/ space/", "https://example.com/<"} {
+			_, err := NewEncoder(new(bytes.Buffer), WithBaseIRI(base))
+			if !assert.ErrorIs(subT, err, ErrInvalidIRI, "base: %q", base) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,726 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/z5labs/megamind/subgraph"
+)
+
+var (
+	// ErrUnknownContext is returned by DefaultContextLoader for every
+	// remote context other than schema.org.
+	ErrUnknownContext = errors.New("rdf: unknown remote JSON-LD context")
+
+	// ErrUnsupportedJSONLD is returned for JSON-LD features which cannot be read.
+	ErrUnsupportedJSONLD = errors.New("rdf: unsupported JSON-LD")
+)
+
+// maxContextDepth limits how deeply remote contexts may include each other.
+const maxContextDepth = 10
+
+// ContextLoader returns the remote JSON-LD context document at the IRI.
+// The document may either be the context itself or, as when it is
+// served, an object with the context under its @context key.
+type ContextLoader func(iri string) (any, error)
+
+// DefaultContextLoader knows the schema.org context, without fetching it,
+// and fails with ErrUnknownContext for every other remote context.
+// Only the vocabulary mapping of schema.org is used, so its terms expand
+// to schema.org IRIs but values are not coerced to other datatypes.
+func DefaultContextLoader(iri string) (any, error) {
+	u, err := url.Parse(iri)
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host == "schema.org" {
+		switch strings.TrimSuffix(u.Path, "/") {
+		case "", "/docs/jsonldcontext.json", "/docs/jsonldcontext.jsonld":
+			return map[string]any{"@vocab": "http://schema.org/"}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownContext, iri)
+}
+
+// ReadJSONLD reads a JSON-LD document into a single subgraph.
+//
+// The document is expanded with its @context and every node becomes a
+// subject and every property value a triple. Nodes are mapped to subjects
+// by the rules, as with a SubgraphReader, and otherwise by their first
+// @type, e.g. a node with an @id of https://example.com/people/1 and an
+// @type of schema:Person becomes the Person with the TUID
+// https://example.com/people/1. Properties are mapped to predicates by the
+// rules and otherwise by the last segment of their IRI, e.g. schema:name
+// becomes name.
+//
+// As in JSON-LD expansion, properties which the context does not map to
+// an IRI are dropped. Lists are read as multiple values and types after
+// the first are dropped. Nodes without an @id are blank nodes, which are
+// scoped to the document, see WithBlankNodeScope. Scoped contexts, reverse properties and index,
+// id, type and graph containers are not supported.
+func ReadJSONLD(r io.Reader, opts ...Option) (*subgraph.Subgraph, error) {
+	o := options{base: DefaultBaseIRI, loader: DefaultContextLoader}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	err := CheckIRI(o.base)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := o.blankNodeScope()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := decodeJSON(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &jsonldDecoder{
+		loader: o.loader,
+		types:  make(map[Term][]string),
+	}
+	active := &jsonldContext{terms: make(map[string]*termDef)}
+	for _, item := range asArray(doc) {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: top-level items must be node objects", ErrUnsupportedJSONLD)
+		}
+		_, err := d.node(active, m)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := &jsonldConverter{base: o.base, rules: o.rules, scope: scope, types: d.types}
+	g := new(subgraph.Subgraph)
+	for _, st := range d.statements {
+		t, err := c.triple(st)
+		if err != nil {
+			return nil, err
+		}
+		g.Triples = append(g.Triples, t)
+	}
+	return g, nil
+}
+
+func decodeJSON(r io.Reader) (any, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var doc any
+	err := dec.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("rdf: unexpected data after JSON-LD document")
+	}
+	return doc, nil
+}
+
+// jsonldContext is an active context.
+type jsonldContext struct {
+	base  string
+	vocab string
+	lang  string
+	terms map[string]*termDef
+}
+
+// termDef is a term definition. A nil termDef means
+// the term was explicitly mapped to null.
+type termDef struct {
+	id        string
+	typ       string
+	lang      string
+	hasLang   bool
+	container string
+}
+
+func (c *jsonldContext) clone() *jsonldContext {
+	nc := *c
+	nc.terms = make(map[string]*termDef, len(c.terms))
+	for term, def := range c.terms {
+		nc.terms[term] = def
+	}
+	return &nc
+}
+
+// expandIRI expands a term, compact IRI or relative IRI. Relative IRIs are
+// resolved against the vocabulary if vocab is set, and otherwise against
+// the base IRI. It returns an empty string if the value cannot be expanded.
+func (c *jsonldContext) expandIRI(value string, vocab bool) string {
+	if strings.HasPrefix(value, "@") {
+		return value
+	}
+	if vocab {
+		if def, ok := c.terms[value]; ok {
+			if def == nil {
+				return ""
+			}
+			return def.id
+		}
+	}
+	if prefix, suffix, ok := strings.Cut(value, ":"); ok {
+		if prefix == "_" || strings.HasPrefix(suffix, "//") {
+			return value
+		}
+		if def, ok := c.terms[prefix]; ok && def != nil && def.id != "" {
+			return def.id + suffix
+		}
+		if u, err := url.Parse(value); err == nil && u.IsAbs() {
+			return value
+		}
+	}
+	if vocab {
+		if c.vocab == "" {
+			return ""
+		}
+		return c.vocab + value
+	}
+	return resolveIRI(c.base, value)
+}
+
+// keywords returns the values of the keys in m which expand to keywords.
+func (c *jsonldContext) keywords(m map[string]any) map[string]any {
+	kws := make(map[string]any)
+	for key, v := range m {
+		if iri := c.expandIRI(key, true); strings.HasPrefix(iri, "@") {
+			kws[iri] = v
+		}
+	}
+	return kws
+}
+
+func resolveIRI(base, ref string) string {
+	if base == "" {
+		return ref
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+type jsonldDecoder struct {
+	loader     ContextLoader
+	bnodes     int
+	statements []Statement
+
+	// types are the expanded types of each node, in document order
+	types map[Term][]string
+}
+
+// context processes a local context, as found under the @context key, on top of the active context.
+func (d *jsonldDecoder) context(active *jsonldContext, local any, depth int) (*jsonldContext, error) {
+	if depth > maxContextDepth {
+		return nil, fmt.Errorf("%w: remote contexts nested too deeply", ErrUnsupportedJSONLD)
+	}
+
+	result := active
+	for _, item := range asArray(local) {
+		switch x := item.(type) {
+		case nil:
+			result = &jsonldContext{base: result.base, terms: make(map[string]*termDef)}
+		case string:
+			iri := resolveIRI(result.base, x)
+			remote, err := d.loader(iri)
+			if err != nil {
+				return nil, err
+			}
+			if m, ok := remote.(map[string]any); ok {
+				if inner, ok := m["@context"]; ok {
+					remote = inner
+				}
+			}
+			result, err = d.context(result, remote, depth+1)
+			if err != nil {
+				return nil, err
+			}
+		case map[string]any:
+			var err error
+			result, err = define(result, x)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: @context must be null, an IRI or an object", ErrUnsupportedJSONLD)
+		}
+	}
+	return result, nil
+}
+
+// define returns a copy of the active context updated with the context definition m.
+func define(active *jsonldContext, m map[string]any) (*jsonldContext, error) {
+	c := active.clone()
+
+	for _, key := range []string{"@base", "@vocab", "@language"} {
+		v, ok := m[key]
+		if !ok {
+			continue
+		}
+		s, isString := v.(string)
+		if v != nil && !isString {
+			return nil, fmt.Errorf("%w: %s must be a string or null", ErrUnsupportedJSONLD, key)
+		}
+		switch key {
+		case "@base":
+			c.base = resolveIRI(c.base, s)
+		case "@vocab":
+			c.vocab = ""
+			if s != "" {
+				c.vocab = c.expandIRI(s, true)
+			}
+		case "@language":
+			c.lang = strings.ToLower(s)
+		}
+	}
+	if _, ok := m["@import"]; ok {
+		return nil, fmt.Errorf("%w: @import", ErrUnsupportedJSONLD)
+	}
+
+	// Terms may be defined using other terms of the same
+	// context so they are defined depth first.
+	const (
+		defining = iota + 1
+		defined
+	)
+	state := make(map[string]int)
+	var defineTerm func(term string) error
+	defineTerm = func(term string) error {
+		switch state[term] {
+		case defining:
+			return fmt.Errorf("%w: cyclic definition of term %q", ErrUnsupportedJSONLD, term)
+		case defined:
+			return nil
+		}
+		state[term] = defining
+		defer func() { state[term] = defined }()
+
+		// dependsOn defines the prefix of a compact IRI first if it is a term in this context
+		dependsOn := func(value string) error {
+			prefix, _, ok := strings.Cut(value, ":")
+			if _, local := m[prefix]; ok && local && prefix != term {
+				return defineTerm(prefix)
+			}
+			return nil
+		}
+
+		var (
+			def   = new(termDef)
+			id    string
+			hasID bool
+		)
+		switch x := m[term].(type) {
+		case nil:
+			c.terms[term] = nil
+			return nil
+		case string:
+			id, hasID = x, true
+		case map[string]any:
+			for _, key := range []string{"@reverse", "@context", "@nest", "@prefix"} {
+				if _, ok := x[key]; ok {
+					return fmt.Errorf("%w: %s in definition of term %q", ErrUnsupportedJSONLD, key, term)
+				}
+			}
+			if v, ok := x["@id"]; ok {
+				if v == nil {
+					c.terms[term] = nil
+					return nil
+				}
+				s, ok := v.(string)
+				if !ok {
+					return fmt.Errorf("%w: @id of term %q must be a string", ErrUnsupportedJSONLD, term)
+				}
+				id, hasID = s, true
+			}
+			if v, ok := x["@type"]; ok {
+				s, ok := v.(string)
+				if !ok {
+					return fmt.Errorf("%w: @type of term %q must be a string", ErrUnsupportedJSONLD, term)
+				}
+				err := dependsOn(s)
+				if err != nil {
+					return err
+				}
+				def.typ = c.expandIRI(s, true)
+			}
+			if v, ok := x["@language"]; ok {
+				s, _ := v.(string)
+				def.lang, def.hasLang = strings.ToLower(s), true
+			}
+			if v, ok := x["@container"]; ok {
+				for _, item := range asArray(v) {
+					s, _ := item.(string)
+					switch s {
+					case "@set":
+					case "@list", "@language":
+						def.container = s
+					default:
+						return fmt.Errorf("%w: %v container of term %q", ErrUnsupportedJSONLD, v, term)
+					}
+				}
+			}
+		default:
+			return fmt.Errorf("%w: definition of term %q must be a string, an object or null", ErrUnsupportedJSONLD, term)
+		}
+
+		if !hasID {
+			id = term
+		}
+		err := dependsOn(id)
+		if err != nil {
+			return err
+		}
+		if !hasID || id == term {
+			// A term defined as itself must not be looked up as itself.
+			delete(c.terms, term)
+		}
+		def.id = c.expandIRI(id, true)
+		if def.id == "" {
+			return fmt.Errorf("%w: term %q does not expand to an IRI", ErrUnsupportedJSONLD, term)
+		}
+		c.terms[term] = def
+		return nil
+	}
+
+	terms := make([]string, 0, len(m))
+	for term := range m {
+		if !strings.HasPrefix(term, "@") {
+			terms = append(terms, term)
+		}
+	}
+	sort.Strings(terms)
+	for _, term := range terms {
+		err := defineTerm(term)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// node emits the statements of a node object and returns its subject.
+func (d *jsonldDecoder) node(active *jsonldContext, m map[string]any) (Term, error) {
+	if local, ok := m["@context"]; ok {
+		var err error
+		active, err = d.context(active, local, 0)
+		if err != nil {
+			return Term{}, err
+		}
+	}
+
+	kws := active.keywords(m)
+	for _, kw := range []string{"@value", "@list", "@set"} {
+		if _, ok := kws[kw]; ok {
+			return Term{}, fmt.Errorf("%w: %s object where a node object was expected", ErrUnsupportedJSONLD, kw)
+		}
+	}
+	if _, ok := kws["@reverse"]; ok {
+		return Term{}, fmt.Errorf("%w: @reverse", ErrUnsupportedJSONLD)
+	}
+
+	var subj Term
+	switch id := kws["@id"].(type) {
+	case nil:
+		subj = d.newBlankNode()
+	case string:
+		subj = active.nodeTerm(id, false)
+	default:
+		return Term{}, fmt.Errorf("%w: @id must be a string", ErrUnsupportedJSONLD)
+	}
+
+	if v, ok := kws["@type"]; ok {
+		for _, item := range asArray(v) {
+			s, ok := item.(string)
+			if !ok {
+				return Term{}, fmt.Errorf("%w: @type must be a string or an array of strings", ErrUnsupportedJSONLD)
+			}
+			if iri := active.expandIRI(s, true); iri != "" {
+				d.types[subj] = append(d.types[subj], iri)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		iri := active.expandIRI(key, true)
+		if iri == "" || strings.HasPrefix(iri, "@") {
+			continue
+		}
+		if strings.HasPrefix(iri, "_:") {
+			return Term{}, fmt.Errorf("%w: blank node property %q", ErrUnsupportedJSONLD, key)
+		}
+		err := d.values(active, subj, Term{Kind: IRI, Value: iri}, active.terms[key], m[key])
+		if err != nil {
+			return Term{}, err
+		}
+	}
+
+	if v, ok := kws["@graph"]; ok {
+		for _, item := range asArray(v) {
+			gm, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			_, err := d.node(active, gm)
+			if err != nil {
+				return Term{}, err
+			}
+		}
+	}
+	return subj, nil
+}
+
+// values emits a statement for every value of the property.
+func (d *jsonldDecoder) values(active *jsonldContext, subj, pred Term, def *termDef, v any) error {
+	if def == nil {
+		def = new(termDef)
+	}
+
+	if m, ok := v.(map[string]any); ok && def.container == "@language" {
+		langs := make([]string, 0, len(m))
+		for lang := range m {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		for _, lang := range langs {
+			for _, item := range asArray(m[lang]) {
+				s, ok := item.(string)
+				if !ok {
+					continue
+				}
+				obj := Term{Kind: Literal, Value: s, Datatype: RDFLangString, Lang: strings.ToLower(lang)}
+				if lang == "@none" || active.expandIRI(lang, true) == "@none" {
+					obj = Term{Kind: Literal, Value: s, Datatype: XSDString}
+				}
+				d.emit(subj, pred, obj)
+			}
+		}
+		return nil
+	}
+
+	for _, item := range asArray(v) {
+		if m, ok := item.(map[string]any); ok {
+			kws := active.keywords(m)
+			list, isList := kws["@list"]
+			set, isSet := kws["@set"]
+			if isList || isSet {
+				err := d.values(active, subj, pred, def, append(asArray(list), asArray(set)...))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		obj, ok, err := d.object(active, def, item)
+		if err != nil {
+			return err
+		}
+		if ok {
+			d.emit(subj, pred, obj)
+		}
+	}
+	return nil
+}
+
+// object converts a single property value to a term. It reports
+// false for values which are dropped, e.g. null.
+func (d *jsonldDecoder) object(active *jsonldContext, def *termDef, v any) (Term, bool, error) {
+	switch x := v.(type) {
+	case nil:
+		return Term{}, false, nil
+	case string:
+		switch {
+		case def.typ == "@id":
+			return active.nodeTerm(x, false), true, nil
+		case def.typ == "@vocab":
+			return active.nodeTerm(x, true), true, nil
+		case def.typ != "" && !strings.HasPrefix(def.typ, "@"):
+			return Term{Kind: Literal, Value: x, Datatype: def.typ}, true, nil
+		}
+		lang := active.lang
+		if def.hasLang {
+			lang = def.lang
+		}
+		if lang != "" {
+			return Term{Kind: Literal, Value: x, Datatype: RDFLangString, Lang: lang}, true, nil
+		}
+		return Term{Kind: Literal, Value: x, Datatype: XSDString}, true, nil
+	case json.Number:
+		datatype := XSDInteger
+		if strings.ContainsAny(x.String(), ".eE") {
+			datatype = XSDDouble
+		}
+		if def.typ != "" && !strings.HasPrefix(def.typ, "@") {
+			datatype = def.typ
+		}
+		return Term{Kind: Literal, Value: x.String(), Datatype: datatype}, true, nil
+	case bool:
+		return Term{Kind: Literal, Value: strconv.FormatBool(x), Datatype: XSDBoolean}, true, nil
+	case map[string]any:
+		kws := active.keywords(x)
+		value, ok := kws["@value"]
+		if !ok {
+			subj, err := d.node(active, x)
+			return subj, err == nil, err
+		}
+		return valueObject(active, kws, value)
+	default:
+		return Term{}, false, fmt.Errorf("%w: unexpected property value", ErrUnsupportedJSONLD)
+	}
+}
+
+// valueObject converts a value object, i.e. one with an @value key, to a literal.
+func valueObject(active *jsonldContext, kws map[string]any, value any) (Term, bool, error) {
+	var lexical, datatype string
+	switch x := value.(type) {
+	case nil:
+		return Term{}, false, nil
+	case string:
+		lexical, datatype = x, XSDString
+	case json.Number:
+		lexical, datatype = x.String(), XSDInteger
+		if strings.ContainsAny(lexical, ".eE") {
+			datatype = XSDDouble
+		}
+	case bool:
+		lexical, datatype = strconv.FormatBool(x), XSDBoolean
+	default:
+		return Term{}, false, fmt.Errorf("%w: @value must be a string, number, boolean or null", ErrUnsupportedJSONLD)
+	}
+
+	if typ, ok := kws["@type"].(string); ok {
+		datatype = active.expandIRI(typ, true)
+	}
+	if lang, ok := kws["@language"].(string); ok && lang != "" {
+		return Term{Kind: Literal, Value: lexical, Datatype: RDFLangString, Lang: strings.ToLower(lang)}, true, nil
+	}
+	return Term{Kind: Literal, Value: lexical, Datatype: datatype}, true, nil
+}
+
+// nodeTerm returns the IRI, or blank node, identified by a node reference.
+func (c *jsonldContext) nodeTerm(ref string, vocab bool) Term {
+	if label, ok := strings.CutPrefix(ref, "_:"); ok {
+		return Term{Kind: BlankNode, Value: label}
+	}
+	return Term{Kind: IRI, Value: c.expandIRI(ref, vocab)}
+}
+
+// newBlankNode returns a blank node with a label which
+// cannot collide with any label written in the document.
+func (d *jsonldDecoder) newBlankNode() Term {
+	d.bnodes += 1
+	return Term{Kind: BlankNode, Value: "genid#" + strconv.Itoa(d.bnodes)}
+}
+
+func (d *jsonldDecoder) emit(s, p, o Term) {
+	d.statements = append(d.statements, Statement{Subject: s, Predicate: p, Object: o})
+}
+
+func asArray(v any) []any {
+	if arr, ok := v.([]any); ok {
+		return arr
+	}
+	return []any{v}
+}
+
+// jsonldConverter maps the statements of a JSON-LD document to triples.
+type jsonldConverter struct {
+	base  string
+	rules *Rules
+	scope string
+	types map[Term][]string
+}
+
+func (c *jsonldConverter) triple(st Statement) (*subgraph.Triple, error) {
+	subj, err := c.subject(st.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := c.rules.predicate(c.base, st.Predicate.Value)
+	if !ok {
+		name = localName(st.Predicate.Value)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatchingRule, st.Predicate.Value)
+	}
+
+	var obj *subgraph.Object
+	if st.Object.Kind == Literal {
+		obj = object(st.Object)
+	} else {
+		s, err := c.subject(st.Object)
+		if err != nil {
+			return nil, err
+		}
+		obj = &subgraph.Object{Value: &subgraph.Object_Subject{Subject: s}}
+	}
+
+	return &subgraph.Triple{
+		Subject:   subj,
+		Predicate: &subgraph.Predicate{Name: name},
+		Object:    obj,
+	}, nil
+}
+
+func (c *jsonldConverter) subject(t Term) (*subgraph.Subject, error) {
+	var typ string
+	if types := c.types[t]; len(types) > 0 {
+		typ = localName(types[0])
+	}
+
+	if t.Kind == BlankNode {
+		if typ == "" {
+			typ = c.rules.blankNodeType()
+		}
+		return &subgraph.Subject{Type: typ, Tuid: blankNodeTUID(c.scope, t.Value)}, nil
+	}
+
+	if rtyp, tuid, ok := c.rules.subject(c.base, t.Value); ok {
+		return &subgraph.Subject{Type: rtyp, Tuid: tuid}, nil
+	}
+	if typ == "" || t.Value == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatchingRule, t.Value)
+	}
+	return &subgraph.Subject{Type: typ, Tuid: t.Value}, nil
+}
+
+// localName returns the last segment of an IRI, e.g. name for http://schema.org/name.
+func localName(iri string) string {
+	if i := strings.LastIndexAny(iri, "#/:"); i >= 0 {
+		return iri[i+1:]
+	}
+	return iri
+}
@@ -0,0 +1,303 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func assertTriples(t *testing.T, expected []*subgraph.Triple, g *subgraph.Subgraph) bool {
+	if !assert.Len(t, g.Triples, len(expected), "unexpected triples: %v", g.Triples) {
+		return false
+	}
+	for i, triple := range expected {
+		if !assert.True(t, proto.Equal(triple, g.Triples[i]), "expected %v but got %v", triple, g.Triples[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReadJSONLD(t *testing.T) {
+	t.Run("should read schema.org nodes by their type", func(subT *testing.T) {
+		src := `{
+			"@context": "https://schema.org",
+			"@type": "Person",
+			"@id": "https://example.com/people/1",
+			"name": "Alice",
+			"knows": {"@id": "https://example.com/people/2", "@type": "Person", "name": "Bob"}
+		}`
+
+		g, err := ReadJSONLD(strings.NewReader(src))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		alice := &subgraph.Subject{Type: "Person", Tuid: "https://example.com/people/1"}
+		bob := &subgraph.Subject{Type: "Person", Tuid: "https://example.com/people/2"}
+		expected := []*subgraph.Triple{
+			{
+				Subject:   bob,
+				Predicate: &subgraph.Predicate{Name: "name"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Bob"}},
+			},
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "knows"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_Subject{Subject: bob}},
+			},
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "name"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Alice"}},
+			},
+		}
+		if !assertTriples(subT, expected, g) {
+			return
+		}
+	})
+
+	t.Run("should expand terms with an inline context", func(subT *testing.T) {
+		src := `{
+			"@context": {
+				"@base": "https://example.com/people/",
+				"@language": "en",
+				"foaf": "http://xmlns.com/foaf/0.1/",
+				"xsd": "http://www.w3.org/2001/XMLSchema#",
+				"id": "@id",
+				"type": "@type",
+				"Person": "foaf:Person",
+				"name": "foaf:name",
+				"nick": {"@id": "foaf:nick", "@language": null},
+				"age": {"@id": "foaf:age", "@type": "xsd:integer"},
+				"born": {"@id": "foaf:birthday", "@type": "xsd:dateTime"},
+				"knows": {"@id": "foaf:knows", "@type": "@id"},
+				"title": {"@id": "foaf:title", "@container": "@language"},
+				"ignored": null
+			},
+			"id": "1",
+			"type": "Person",
+			"name": "Alice",
+			"nick": ["Al", "Ali"],
+			"age": "42",
+			"born": "1980-01-02T03:04:05Z",
+			"knows": "2",
+			"title": {"fr": "Mme"},
+			"ignored": "dropped",
+			"unmapped": "dropped",
+			"foaf:mbox": {"@value": "alice@example.com"}
+		}`
+
+		rules, err := ParseRules([]byte(`
+subjects:
+  - match: '^https://example\.com/people/(\d+)$'
+    type: Person
+    tuid: $1
+`))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		g, err := ReadJSONLD(strings.NewReader(src), WithRules(rules))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		alice := &subgraph.Subject{Type: "Person", Tuid: "1"}
+		expected := []*subgraph.Triple{
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "age"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_Int64{Int64: 42}},
+			},
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "birthday"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.New(time.Date(1980, 1, 2, 3, 4, 5, 0, time.UTC))}},
+			},
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "mbox"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "alice@example.com"}},
+			},
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "knows"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_Subject{Subject: &subgraph.Subject{Type: "Person", Tuid: "2"}}},
+			},
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "name"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Alice", Lang: "en"}}},
+			},
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "nick"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Al"}},
+			},
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "nick"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Ali"}},
+			},
+			{
+				Subject:   alice,
+				Predicate: &subgraph.Predicate{Name: "title"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Mme", Lang: "fr"}}},
+			},
+		}
+		if !assertTriples(subT, expected, g) {
+			return
+		}
+	})
+
+	t.Run("should read native values, lists and graphs", func(subT *testing.T) {
+		src := `{
+			"@context": {"@vocab": "urn:megamind:"},
+			"@graph": [
+				{
+					"@id": "urn:megamind:Sensor/1",
+					"reading": {"@list": [1.5, 2]},
+					"active": true
+				},
+				{
+					"@type": "Note",
+					"text": "anonymous"
+				}
+			]
+		}`
+
+		g, err := ReadJSONLD(strings.NewReader(src), WithBlankNodeScope("doc"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		sensor := &subgraph.Subject{Type: "Sensor", Tuid: "1"}
+		expected := []*subgraph.Triple{
+			{
+				Subject:   sensor,
+				Predicate: &subgraph.Predicate{Name: "active"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_Bool{Bool: true}},
+			},
+			{
+				Subject:   sensor,
+				Predicate: &subgraph.Predicate{Name: "reading"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_Float64{Float64: 1.5}},
+			},
+			{
+				Subject:   sensor,
+				Predicate: &subgraph.Predicate{Name: "reading"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_Int64{Int64: 2}},
+			},
+			{
+				Subject:   &subgraph.Subject{Type: "Note", Tuid: "doc/genid#2"},
+				Predicate: &subgraph.Predicate{Name: "text"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "anonymous"}},
+			},
+		}
+		if !assertTriples(subT, expected, g) {
+			return
+		}
+	})
+
+	t.Run("should use the context loader for remote contexts", func(subT *testing.T) {
+		loader := func(iri string) (any, error) {
+			if iri != "https://example.com/context.jsonld" {
+				return nil, ErrUnknownContext
+			}
+			return map[string]any{
+				"@context": map[string]any{"@vocab": "https://example.com/vocab#"},
+			}, nil
+		}
+		src := `{"@context": "https://example.com/context.jsonld", "@id": "_:b0", "name": "Alice"}`
+
+		g, err := ReadJSONLD(strings.NewReader(src), WithContextLoader(loader), WithBlankNodeScope("doc"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		expected := []*subgraph.Triple{
+			{
+				Subject:   &subgraph.Subject{Type: DefaultBlankNodeType, Tuid: "doc/b0"},
+				Predicate: &subgraph.Predicate{Name: "name"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: "Alice"}},
+			},
+		}
+		if !assertTriples(subT, expected, g) {
+			return
+		}
+	})
+
+	t.Run("should not reuse blank nodes across documents", func(subT *testing.T) {
+		src := `{"@context": "https://schema.org", "@type": "Person", "name": "Alice"}`
+
+		a, err := ReadJSONLD(strings.NewReader(src))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		b, err := ReadJSONLD(strings.NewReader(src))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.NotEqual(subT, a.Triples[0].Subject.Tuid, b.Triples[0].Subject.Tuid) {
+			return
+		}
+	})
+
+	t.Run("should fail on an unknown remote context", func(subT *testing.T) {
+		src := `{"@context": "https://example.com/context.jsonld", "name": "Alice"}`
+
+		_, err := ReadJSONLD(strings.NewReader(src))
+		if !assert.True(subT, errors.Is(err, ErrUnknownContext), "unexpected error: %v", err) {
+			return
+		}
+	})
+
+	t.Run("should fail if a node cannot be mapped to a subject", func(subT *testing.T) {
+		src := `{"@context": "https://schema.org", "@id": "https://example.com/people/1", "name": "Alice"}`
+
+		_, err := ReadJSONLD(strings.NewReader(src))
+		if !assert.True(subT, errors.Is(err, ErrNoMatchingRule), "unexpected error: %v", err) {
+			return
+		}
+	})
+
+	t.Run("should fail on unsupported features", func(subT *testing.T) {
+		src := `{"@context": {"@vocab": "urn:megamind:", "tags": {"@container": "@index"}}, "@type": "Note", "tags": {}}`
+
+		_, err := ReadJSONLD(strings.NewReader(src))
+		if !assert.True(subT, errors.Is(err, ErrUnsupportedJSONLD), "unexpected error: %v", err) {
+			return
+		}
+	})
+
+	t.Run("should fail on malformed JSON", func(subT *testing.T) {
+		_, err := ReadJSONLD(strings.NewReader(`{"@id": `))
+		if !assert.NotNil(subT, err) {
+			return
+		}
+	})
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package rdf converts subgraphs to and from RDF.
+//
+// Subjects are identified by IRIs built from a base IRI followed by their
+// type and TUID, e.g. urn:megamind:Person/1, and predicates by the base
+// IRI followed by their name, e.g. urn:megamind:name.
+package rdf
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// DefaultBaseIRI is used when no base IRI is configured.
+const DefaultBaseIRI = "urn:megamind:"
+
+// XML Schema and GeoSPARQL datatype IRIs.
+const (
+	XSDString       = "http://www.w3.org/2001/XMLSchema#string"
+	XSDLong         = "http://www.w3.org/2001/XMLSchema#long"
+	XSDInteger      = "http://www.w3.org/2001/XMLSchema#integer"
+	XSDDecimal      = "http://www.w3.org/2001/XMLSchema#decimal"
+	XSDDouble       = "http://www.w3.org/2001/XMLSchema#double"
+	XSDFloat        = "http://www.w3.org/2001/XMLSchema#float"
+	XSDBoolean      = "http://www.w3.org/2001/XMLSchema#boolean"
+	XSDDate         = "http://www.w3.org/2001/XMLSchema#date"
+	XSDDateTime     = "http://www.w3.org/2001/XMLSchema#dateTime"
+	XSDBase64Binary = "http://www.w3.org/2001/XMLSchema#base64Binary"
+	WKTLiteral      = "http://www.opengis.net/ont/geosparql#wktLiteral"
+)
+
+// RDF vocabulary IRIs.
+const (
+	RDFType       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	RDFFirst      = "http://www.w3.org/1999/02/22-rdf-syntax-ns#first"
+	RDFRest       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"
+	RDFNil        = "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"
+	RDFLangString = "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString"
+	RDFStatement  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#Statement"
+	RDFSubject    = "http://www.w3.org/1999/02/22-rdf-syntax-ns#subject"
+	RDFPredicate  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#predicate"
+	RDFObject     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#object"
+)
+
+// ErrInvalidIRI is returned for an IRI which is not absolute or
+// contains characters which are not allowed in N-Triples.
+var ErrInvalidIRI = errors.New("rdf: IRI must be absolute and must not contain whitespace or any of <>\"{}|^`\\")
+
+// Format is an RDF serialization.
+type Format int
+
+const (
+	NTriples Format = iota
+	NQuads
+	Turtle
+)
+
+var formatNames = map[Format]string{
+	NTriples: "ntriples",
+	NQuads:   "nquads",
+	Turtle:   "turtle",
+}
+
+func (f Format) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat parses the name of a format, e.g. nquads.
+func ParseFormat(s string) (Format, error) {
+	for f, name := range formatNames {
+		if name == strings.ToLower(strings.TrimSpace(s)) {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("rdf: unknown format: %q", s)
+}
+
+// Option
+type Option func(*options)
+
+type options struct {
+	base   string
+	format Format
+	rules  *Rules
+	loader ContextLoader
+	scope  string
+}
+
+// WithBaseIRI sets the IRI which subject and predicate IRIs are built under.
+// When reading, IRIs under the base IRI are mapped back to subjects and predicates.
+func WithBaseIRI(base string) Option {
+	return func(o *options) {
+		o.base = base
+	}
+}
+
+// WithFormat sets the format to encode to, or decode from. By default, N-Triples are used.
+func WithFormat(f Format) Option {
+	return func(o *options) {
+		o.format = f
+	}
+}
+
+// WithRules sets the rules used to map IRIs back to subjects and predicates.
+func WithRules(r *Rules) Option {
+	return func(o *options) {
+		o.rules = r
+	}
+}
+
+// WithContextLoader sets how remote JSON-LD contexts are loaded.
+// By default, only the schema.org context is known, see DefaultContextLoader.
+func WithContextLoader(l ContextLoader) Option {
+	return func(o *options) {
+		o.loader = l
+	}
+}
+
+// WithBlankNodeScope sets the scope blank node labels are unique within,
+// e.g. the ID of an ingest request or import run. The TUID of a blank node
+// is its scope followed by its label, so blank nodes with the same label
+// from different scopes never become the same subject. By default, every
+// SubgraphReader and JSON-LD document gets its own random scope.
+func WithBlankNodeScope(scope string) Option {
+	return func(o *options) {
+		o.scope = scope
+	}
+}
+
+// blankNodeScope returns the configured scope, or a new random one.
+func (o options) blankNodeScope() (string, error) {
+	if o.scope != "" {
+		return o.scope, nil
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// blankNodeTUID returns the TUID of the blank node with the given label.
+func blankNodeTUID(scope, label string) string {
+	return scope + "/" + label
+}
+
+// CheckIRI returns ErrInvalidIRI if the IRI cannot be written as an IRI reference.
+func CheckIRI(iri string) error {
+	if strings.ContainsAny(iri, "<>\"{}|^`\\") || strings.IndexFunc(iri, isSpace) >= 0 {
+		return ErrInvalidIRI
+	}
+	u, err := url.Parse(iri)
+	if err != nil || !u.IsAbs() {
+		return ErrInvalidIRI
+	}
+	return nil
+}
+
+func isSpace(r rune) bool {
+	return r <= ' '
+}
+
+// Quote returns s as a double quoted N-Triples string literal.
+func Quote(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrNoMatchingRule is returned for an IRI which no rule maps to a subject or predicate.
+var ErrNoMatchingRule = errors.New("rdf: no rule matches IRI")
+
+// SubgraphReader groups the statements about each subject into subgraphs.
+// Only consecutive statements about the same subject, and in the same graph,
+// are grouped together so that any amount of RDF can be read in constant
+// memory. The graph of a statement, if any, becomes the source URI of its
+// subgraph. Blank nodes are scoped to the reader, see WithBlankNodeScope.
+type SubgraphReader struct {
+	dec   *Decoder
+	base  string
+	rules *Rules
+	scope string
+
+	next *Statement
+}
+
+// NewSubgraphReader returns a SubgraphReader which reads from r.
+func NewSubgraphReader(r io.Reader, opts ...Option) (*SubgraphReader, error) {
+	o := options{base: DefaultBaseIRI}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	err := CheckIRI(o.base)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := o.blankNodeScope()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SubgraphReader{
+		dec:   NewDecoder(r, opts...),
+		base:  o.base,
+		rules: o.rules,
+		scope: scope,
+	}, nil
+}
+
+// Read returns the next subgraph. It returns io.EOF once every statement has been read.
+func (r *SubgraphReader) Read() (*subgraph.Subgraph, error) {
+	first, err := r.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	g := new(subgraph.Subgraph)
+	if first.Graph.Kind == IRI {
+		g.Provenance = &subgraph.Provenance{SourceUri: first.Graph.Value}
+	}
+
+	st := first
+	for {
+		t, err := r.triple(st)
+		if err != nil {
+			return nil, err
+		}
+		g.Triples = append(g.Triples, t)
+
+		st, err = r.statement()
+		if err == io.EOF {
+			return g, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if st.Subject != first.Subject || st.Graph != first.Graph {
+			r.next = &st
+			return g, nil
+		}
+	}
+}
+
+func (r *SubgraphReader) statement() (Statement, error) {
+	if r.next != nil {
+		st := *r.next
+		r.next = nil
+		return st, nil
+	}
+	return r.dec.Decode()
+}
+
+func (r *SubgraphReader) triple(st Statement) (*subgraph.Triple, error) {
+	subj, err := r.subject(st.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := r.rules.predicate(r.base, st.Predicate.Value)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatchingRule, st.Predicate.Value)
+	}
+
+	var obj *subgraph.Object
+	if st.Object.Kind == Literal {
+		obj = object(st.Object)
+	} else {
+		s, err := r.subject(st.Object)
+		if err != nil {
+			return nil, err
+		}
+		obj = &subgraph.Object{Value: &subgraph.Object_Subject{Subject: s}}
+	}
+
+	return &subgraph.Triple{
+		Subject:   subj,
+		Predicate: &subgraph.Predicate{Name: name},
+		Object:    obj,
+	}, nil
+}
+
+func (r *SubgraphReader) subject(t Term) (*subgraph.Subject, error) {
+	if t.Kind == BlankNode {
+		return &subgraph.Subject{Type: r.rules.blankNodeType(), Tuid: blankNodeTUID(r.scope, t.Value)}, nil
+	}
+
+	typ, tuid, ok := r.rules.subject(r.base, t.Value)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatchingRule, t.Value)
+	}
+	return &subgraph.Subject{Type: typ, Tuid: tuid}, nil
+}
+
+// xsdIntegers are the XML Schema datatypes which are read as integers.
+var xsdIntegers = map[string]bool{
+	"integer": true, "long": true, "int": true, "short": true, "byte": true,
+	"nonNegativeInteger": true, "positiveInteger": true,
+	"nonPositiveInteger": true, "negativeInteger": true,
+	"unsignedLong": true, "unsignedInt": true, "unsignedShort": true, "unsignedByte": true,
+}
+
+// object converts the literal to the closest kind of object.
+// Literals which are not valid for their datatype are kept as strings.
+func object(t Term) *subgraph.Object {
+	str := &subgraph.Object{Value: &subgraph.Object_String_{String_: t.Value}}
+	if t.Lang != "" {
+		return &subgraph.Object{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: t.Value, Lang: t.Lang}}}
+	}
+
+	if local, ok := strings.CutPrefix(t.Datatype, "http://www.w3.org/2001/XMLSchema#"); ok && xsdIntegers[local] {
+		i, err := strconv.ParseInt(t.Value, 10, 64)
+		if err != nil {
+			return str
+		}
+		return &subgraph.Object{Value: &subgraph.Object_Int64{Int64: i}}
+	}
+
+	switch t.Datatype {
+	case XSDDouble, XSDFloat, XSDDecimal:
+		f, err := strconv.ParseFloat(t.Value, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return str
+		}
+		return &subgraph.Object{Value: &subgraph.Object_Float64{Float64: f}}
+	case XSDBoolean:
+		switch t.Value {
+		case "true", "1":
+			return &subgraph.Object{Value: &subgraph.Object_Bool{Bool: true}}
+		case "false", "0":
+			return &subgraph.Object{Value: &subgraph.Object_Bool{Bool: false}}
+		}
+	case XSDDateTime, XSDDate:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02Z07:00", "2006-01-02"} {
+			ts, err := time.Parse(layout, t.Value)
+			if err == nil {
+				return &subgraph.Object{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.New(ts)}}
+			}
+		}
+	case XSDBase64Binary:
+		b, err := base64.StdEncoding.DecodeString(t.Value)
+		if err == nil {
+			return &subgraph.Object{Value: &subgraph.Object_Bytes{Bytes: b}}
+		}
+	case WKTLiteral:
+		var lon, lat float64
+		_, err := fmt.Sscanf(strings.ToUpper(strings.TrimSpace(t.Value)), "POINT(%g %g)", &lon, &lat)
+		if err == nil {
+			return &subgraph.Object{Value: &subgraph.Object_Geo{Geo: &subgraph.GeoPoint{Latitude: lat, Longitude: lon}}}
+		}
+	}
+	return str
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func readAll(r *SubgraphReader) ([]*subgraph.Subgraph, error) {
+	var gs []*subgraph.Subgraph
+	for {
+		g, err := r.Read()
+		if err == io.EOF {
+			return gs, nil
+		}
+		if err != nil {
+			return gs, err
+		}
+		gs = append(gs, g)
+	}
+}
+
+func TestSubgraphReader_Read(t *testing.T) {
+	t.Run("should round-trip subgraphs written by the Encoder", func(subT *testing.T) {
+		g := newObjectsSubgraph()
+
+		var buf bytes.Buffer
+		enc, err := NewEncoder(&buf, WithFormat(NQuads))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = enc.Encode(g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = enc.Flush()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		r, err := NewSubgraphReader(&buf, WithFormat(NQuads))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		gs, err := readAll(r)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, gs, 1) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(g, gs[0]), "expected %v but got %v", g, gs[0]) {
+			return
+		}
+	})
+
+	t.Run("should group consecutive statements by subject", func(subT *testing.T) {
+		src := strings.Join([]string{
+			`@prefix m: <urn:megamind:> .`,
+			`m:Person\/1 m:name "Alice" ; m:knows m:Person\/2 .`,
+			`m:Person\/2 m:name "Bob" .`,
+			`m:Person\/1 m:age 42 .`,
+		}, "\n")
+
+		r, err := NewSubgraphReader(strings.NewReader(src), WithFormat(Turtle))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		gs, err := readAll(r)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		var sizes []int
+		for _, g := range gs {
+			sizes = append(sizes, len(g.Triples))
+		}
+		if !assert.Equal(subT, []int{2, 1, 1}, sizes) {
+			return
+		}
+		if !assert.Equal(subT, "Person", gs[0].Triples[1].Object.GetSubject().GetType()) {
+			return
+		}
+		if !assert.Equal(subT, int64(42), gs[2].Triples[0].Object.GetInt64()) {
+			return
+		}
+	})
+
+	t.Run("should map IRIs with rules", func(subT *testing.T) {
+		rules, err := ParseRules([]byte(`
+blank_node_type: Resource
+subjects:
+  - match: '^http://example\.com/people/(\d+)$'
+    type: Person
+    tuid: $1
+  - type: Thing
+    tuid: $0
+predicates:
+  - match: '^http://xmlns\.com/foaf/0\.1/(.+)$'
+    name: foaf_$1
+`))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		src := strings.Join([]string{
+			`<http://example.com/people/1> <http://xmlns.com/foaf/0.1/knows> <http://example.com/other> .`,
+			`_:b0 <http://xmlns.com/foaf/0.1/name> "2020-01-01"^^<http://www.w3.org/2001/XMLSchema#date> .`,
+		}, "\n")
+		r, err := NewSubgraphReader(strings.NewReader(src), WithRules(rules), WithBlankNodeScope("import"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		gs, err := readAll(r)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, gs, 2) {
+			return
+		}
+
+		knows := gs[0].Triples[0]
+		if !assert.Equal(subT, &subgraph.Subject{Type: "Person", Tuid: "1"}, knows.Subject) {
+			return
+		}
+		if !assert.Equal(subT, "foaf_knows", knows.Predicate.Name) {
+			return
+		}
+		if !assert.Equal(subT, &subgraph.Subject{Type: "Thing", Tuid: "http://example.com/other"}, knows.Object.GetSubject()) {
+			return
+		}
+
+		name := gs[1].Triples[0]
+		if !assert.Equal(subT, &subgraph.Subject{Type: "Resource", Tuid: "import/b0"}, name.Subject) {
+			return
+		}
+		if !assert.Equal(subT, int64(1577836800), name.Object.GetTimestamp().GetSeconds()) {
+			return
+		}
+	})
+
+	t.Run("should not reuse blank nodes across readers", func(subT *testing.T) {
+		src := `_:b0 <urn:megamind:name> "Alice" .`
+
+		var tuids []string
+		for i := 0; i < 2; i++ {
+			r, err := NewSubgraphReader(strings.NewReader(src))
+			if !assert.Nil(subT, err) {
+				return
+			}
+			g, err := r.Read()
+			if !assert.Nil(subT, err) {
+				return
+			}
+			tuids = append(tuids, g.Triples[0].Subject.Tuid)
+		}
+		if !assert.NotEqual(subT, tuids[0], tuids[1]) {
+			return
+		}
+	})
+
+	t.Run("should fail if no rule matches an IRI", func(subT *testing.T) {
+		src := `<http://example.com/alice> <urn:megamind:name> "Alice" .`
+
+		r, err := NewSubgraphReader(strings.NewReader(src))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		_, err = r.Read()
+		if !assert.True(subT, errors.Is(err, ErrNoMatchingRule), "unexpected error: %v", err) {
+			return
+		}
+	})
+
+	t.Run("should keep ill-typed literals as strings", func(subT *testing.T) {
+		src := `<urn:megamind:Person/1> <urn:megamind:age> "forty-two"^^<http://www.w3.org/2001/XMLSchema#integer> .`
+
+		r, err := NewSubgraphReader(strings.NewReader(src))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		g, err := r.Read()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, "forty-two", g.Triples[0].Object.GetString_()) {
+			return
+		}
+	})
+}
+
+func TestParseRules(t *testing.T) {
+	testCases := map[string]string{
+		"unknown field":         "subject: []",
+		"missing tuid template": "subjects:\n  - type: Person",
+		"missing name template": "predicates:\n  - match: '.*'",
+		"invalid pattern":       "predicates:\n  - match: '('\n    name: $0",
+	}
+
+	for name, src := range testCases {
+		t.Run("should fail on "+name, func(subT *testing.T) {
+			_, err := ParseRules([]byte(src))
+			if !assert.NotNil(subT, err) {
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultBlankNodeType is the subject type given to blank nodes
+// when the rules do not declare one.
+const DefaultBlankNodeType = "BlankNode"
+
+// Rules map IRIs back to subject types, TUIDs and predicate names.
+// Rules are tried in order and the first one which matches is used.
+// If none match, IRIs under the base IRI are mapped the same way the
+// Encoder builds them.
+//
+// Rules are written in YAML (or JSON) and look like:
+//
+//	blank_node_type: Resource
+//	subjects:
+//	  - match: '^https://example\.com/people/(\d+)$'
+//	    type: Person
+//	    tuid: $1
+//	  # a rule without a match pattern matches every IRI
+//	  - type: Resource
+//	    tuid: $0
+//	predicates:
+//	  - match: '^http://xmlns\.com/foaf/0\.1/(.+)$'
+//	    name: foaf_$1
+//
+// The type, tuid and name are templates which may refer to the
+// submatches of the pattern, as in regexp.Regexp.Expand.
+type Rules struct {
+	BlankNodeType string
+	Subjects      []SubjectRule
+	Predicates    []PredicateRule
+}
+
+// SubjectRule maps matching IRIs to a subject.
+type SubjectRule struct {
+	Match *regexp.Regexp
+	Type  string
+	Tuid  string
+}
+
+// PredicateRule maps matching IRIs to a predicate.
+type PredicateRule struct {
+	Match *regexp.Regexp
+	Name  string
+}
+
+type rulesFile struct {
+	BlankNodeType string              `yaml:"blank_node_type"`
+	Subjects      []subjectRuleFile   `yaml:"subjects"`
+	Predicates    []predicateRuleFile `yaml:"predicates"`
+}
+
+type subjectRuleFile struct {
+	Match string `yaml:"match"`
+	Type  string `yaml:"type"`
+	Tuid  string `yaml:"tuid"`
+}
+
+type predicateRuleFile struct {
+	Match string `yaml:"match"`
+	Name  string `yaml:"name"`
+}
+
+// ParseRules parses YAML, or JSON, encoded rules.
+func ParseRules(b []byte) (*Rules, error) {
+	var rf rulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	err := dec.Decode(&rf)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &Rules{BlankNodeType: rf.BlankNodeType}
+	for i, sf := range rf.Subjects {
+		if strings.TrimSpace(sf.Type) == "" || strings.TrimSpace(sf.Tuid) == "" {
+			return nil, fmt.Errorf("subjects[%d]: type and tuid must not be empty", i)
+		}
+		re, err := compileMatch(sf.Match)
+		if err != nil {
+			return nil, fmt.Errorf("subjects[%d]: %w", i, err)
+		}
+		rules.Subjects = append(rules.Subjects, SubjectRule{Match: re, Type: sf.Type, Tuid: sf.Tuid})
+	}
+	for i, pf := range rf.Predicates {
+		if strings.TrimSpace(pf.Name) == "" {
+			return nil, fmt.Errorf("predicates[%d]: name must not be empty", i)
+		}
+		re, err := compileMatch(pf.Match)
+		if err != nil {
+			return nil, fmt.Errorf("predicates[%d]: %w", i, err)
+		}
+		rules.Predicates = append(rules.Predicates, PredicateRule{Match: re, Name: pf.Name})
+	}
+	return rules, nil
+}
+
+// LoadRules reads and parses the named rules file.
+func LoadRules(filename string) (*Rules, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(b)
+}
+
+func compileMatch(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		pattern = `^.*$`
+	}
+	return regexp.Compile(pattern)
+}
+
+// subject maps the IRI to a subject type and TUID.
+func (r *Rules) subject(base, iri string) (typ, tuid string, ok bool) {
+	if r != nil {
+		for _, rule := range r.Subjects {
+			m := rule.Match.FindStringSubmatchIndex(iri)
+			if m == nil {
+				continue
+			}
+			typ = string(rule.Match.ExpandString(nil, rule.Type, iri, m))
+			tuid = string(rule.Match.ExpandString(nil, rule.Tuid, iri, m))
+			return typ, tuid, typ != "" && tuid != ""
+		}
+	}
+
+	rest, ok := strings.CutPrefix(iri, base)
+	if !ok {
+		return "", "", false
+	}
+	typ, tuid, ok = strings.Cut(rest, "/")
+	if !ok {
+		return "", "", false
+	}
+	typ, err := url.PathUnescape(typ)
+	if err != nil {
+		return "", "", false
+	}
+	tuid, err = url.PathUnescape(tuid)
+	if err != nil {
+		return "", "", false
+	}
+	return typ, tuid, typ != "" && tuid != ""
+}
+
+// predicate maps the IRI to a predicate name.
+func (r *Rules) predicate(base, iri string) (string, bool) {
+	if r != nil {
+		for _, rule := range r.Predicates {
+			m := rule.Match.FindStringSubmatchIndex(iri)
+			if m == nil {
+				continue
+			}
+			name := string(rule.Match.ExpandString(nil, rule.Name, iri, m))
+			return name, name != ""
+		}
+	}
+
+	rest, ok := strings.CutPrefix(iri, base)
+	if !ok || rest == "" || strings.Contains(rest, "/") {
+		return "", false
+	}
+	name, err := url.PathUnescape(rest)
+	return name, err == nil
+}
+
+func (r *Rules) blankNodeType() string {
+	if r == nil || r.BlankNodeType == "" {
+		return DefaultBlankNodeType
+	}
+	return r.BlankNodeType
+}
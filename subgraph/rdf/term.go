@@ -0,0 +1,71 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rdf
+
+import "strings"
+
+// TermKind is the kind of an RDF term.
+type TermKind int
+
+const (
+	IRI TermKind = iota + 1
+	BlankNode
+	Literal
+)
+
+// Term is an IRI, blank node or literal. The zero Term is used
+// for the default graph of a statement.
+type Term struct {
+	Kind TermKind
+
+	// Value is the IRI, the blank node label or the lexical form of the literal.
+	Value string
+
+	// Datatype is the datatype IRI of a literal.
+	Datatype string
+
+	// Lang is the language tag of a literal.
+	Lang string
+}
+
+func (t Term) String() string {
+	switch t.Kind {
+	case IRI:
+		return "<" + t.Value + ">"
+	case BlankNode:
+		return "_:" + t.Value
+	case Literal:
+		var sb strings.Builder
+		sb.WriteString(Quote(t.Value))
+		if t.Lang != "" {
+			sb.WriteString("@" + t.Lang)
+		} else if t.Datatype != "" && t.Datatype != XSDString {
+			sb.WriteString("^^<" + t.Datatype + ">")
+		}
+		return sb.String()
+	default:
+		return ""
+	}
+}
+
+// Statement is a triple, or a quad if its Graph is set.
+type Statement struct {
+	Subject   Term
+	Predicate Term
+	Object    Term
+	Graph     Term
+}
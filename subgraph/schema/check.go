@@ -0,0 +1,132 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"fmt"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/validate"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Check returns every triple in the subgraph which does not conform to the schema.
+// The subgraph is expected to have already passed validate.Subgraph.
+func (s *Schema) Check(g *subgraph.Subgraph) []validate.Violation {
+	var vs []validate.Violation
+	add := func(i int, field, desc string) {
+		vs = append(vs, validate.Violation{
+			TripleIndex: i,
+			Field:       fmt.Sprintf("triples[%d].%s", i, field),
+			Description: desc,
+		})
+	}
+
+	type subjectPredicate struct {
+		typ, tuid, pred string
+	}
+	single := make(map[subjectPredicate]int)
+
+	for i, t := range g.GetTriples() {
+		subj := t.GetSubject()
+		typ, ok := s.Types[subj.GetType()]
+		if !ok {
+			add(i, "subject.type", fmt.Sprintf("unknown type: %q", subj.GetType()))
+			continue
+		}
+		if t.GetOperation() == subgraph.Operation_DELETE_SUBJECT {
+			for key := range single {
+				if key.typ == subj.GetType() && key.tuid == subj.GetTuid() {
+					delete(single, key)
+				}
+			}
+			continue
+		}
+
+		name := t.GetPredicate().GetName()
+		p, ok := typ.Predicates[name]
+		if !ok {
+			add(i, "predicate.name", fmt.Sprintf("predicate %q is not declared on type %q", name, typ.Name))
+			continue
+		}
+
+		retract := t.GetOperation() == subgraph.Operation_RETRACT
+		if retract && t.GetObject() == nil {
+			delete(single, subjectPredicate{typ: subj.GetType(), tuid: subj.GetTuid(), pred: name})
+			continue
+		}
+
+		kind, field := kindOf(t.GetObject())
+		if kind != p.Kind {
+			add(i, "object", fmt.Sprintf("expected %s but got %s", p.Kind, field))
+			continue
+		}
+		if kind == KindSubject {
+			ref := t.GetObject().GetSubject()
+			if ref.GetType() != p.Type {
+				add(i, "object.subject.type", fmt.Sprintf("expected subject of type %q but got %q", p.Type, ref.GetType()))
+				continue
+			}
+		}
+
+		if p.Cardinality != One {
+			continue
+		}
+		key := subjectPredicate{typ: subj.GetType(), tuid: subj.GetTuid(), pred: name}
+		j, seen := single[key]
+		if retract {
+			if seen && proto.Equal(t.GetObject(), g.Triples[j].GetObject()) {
+				delete(single, key)
+			}
+			continue
+		}
+		if !seen {
+			single[key] = i
+			continue
+		}
+		if !proto.Equal(t.GetObject(), g.Triples[j].GetObject()) {
+			add(i, "predicate.name", fmt.Sprintf("predicate %q allows one value per subject but triple %d already sets a different one", name, j))
+		}
+	}
+	return vs
+}
+
+func kindOf(o *subgraph.Object) (Kind, string) {
+	switch o.GetValue().(type) {
+	case *subgraph.Object_Subject:
+		return KindSubject, "subject"
+	case *subgraph.Object_String_:
+		return KindString, "string"
+	case *subgraph.Object_Int64:
+		return KindInt64, "int64"
+	case *subgraph.Object_Float64:
+		return KindFloat64, "float64"
+	case *subgraph.Object_Bool:
+		return KindBool, "bool"
+	case *subgraph.Object_Timestamp:
+		return KindTimestamp, "timestamp"
+	case *subgraph.Object_Bytes:
+		return KindBytes, "bytes"
+	case *subgraph.Object_Geo:
+		return KindGeo, "geo"
+	case *subgraph.Object_LangString:
+		return KindLangString, "langstring"
+	default:
+		return 0, "no value"
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/validate"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func person(tuid string) *subgraph.Subject {
+	return &subgraph.Subject{Type: "Person", Tuid: tuid}
+}
+
+func triple(subj *subgraph.Subject, pred string, obj *subgraph.Object) *subgraph.Triple {
+	return &subgraph.Triple{
+		Subject:   subj,
+		Predicate: &subgraph.Predicate{Name: pred},
+		Object:    obj,
+	}
+}
+
+func str(s string) *subgraph.Object {
+	return &subgraph.Object{Value: &subgraph.Object_String_{String_: s}}
+}
+
+func ref(subj *subgraph.Subject) *subgraph.Object {
+	return &subgraph.Object{Value: &subgraph.Object_Subject{Subject: subj}}
+}
+
+func TestSchema_Check(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("should return no violations for a conforming subgraph", func(subT *testing.T) {
+		g := &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{
+				triple(person("1"), "name", str("Bob")),
+				triple(person("1"), "name", str("Bob")),
+				triple(person("1"), "age", &subgraph.Object{Value: &subgraph.Object_Int64{Int64: 42}}),
+				triple(person("1"), "height", &subgraph.Object{Value: &subgraph.Object_Float64{Float64: 1.8}}),
+				triple(person("1"), "alive", &subgraph.Object{Value: &subgraph.Object_Bool{Bool: true}}),
+				triple(person("1"), "born", &subgraph.Object{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.Now()}}),
+				triple(person("1"), "avatar", &subgraph.Object{Value: &subgraph.Object_Bytes{Bytes: []byte{0x1}}}),
+				triple(person("1"), "location", &subgraph.Object{Value: &subgraph.Object_Geo{Geo: &subgraph.GeoPoint{}}}),
+				triple(person("1"), "bio", &subgraph.Object{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Hi", Lang: "en"}}}),
+				triple(person("1"), "knows", ref(person("2"))),
+				triple(person("1"), "knows", ref(person("3"))),
+				triple(person("1"), "worksFor", ref(&subgraph.Subject{Type: "Organization", Tuid: "1"})),
+			},
+		}
+
+		vs := s.Check(g)
+		if !assert.Empty(subT, vs) {
+			return
+		}
+	})
+
+	t.Run("should allow a single valued predicate to be reassigned after it is retracted", func(subT *testing.T) {
+		retract := triple(person("1"), "name", str("Bob"))
+		retract.Operation = subgraph.Operation_RETRACT
+
+		retractAll := triple(person("1"), "name", nil)
+		retractAll.Operation = subgraph.Operation_RETRACT
+
+		g := &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{
+				triple(person("1"), "name", str("Bob")),
+				retract,
+				triple(person("1"), "name", str("Robert")),
+				retractAll,
+				triple(person("1"), "name", str("Rob")),
+				{Subject: person("1"), Operation: subgraph.Operation_DELETE_SUBJECT},
+				triple(person("1"), "name", str("Bobby")),
+			},
+		}
+
+		vs := s.Check(g)
+		if !assert.Empty(subT, vs) {
+			return
+		}
+	})
+
+	t.Run("should return every violation", func(subT *testing.T) {
+		g := &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{
+				triple(&subgraph.Subject{Type: "Persn", Tuid: "1"}, "name", str("Bob")),
+				triple(person("1"), "nmae", str("Bob")),
+				triple(person("1"), "age", str("42")),
+				triple(person("1"), "born", str("2022-01-01")),
+				triple(person("1"), "worksFor", ref(person("2"))),
+				triple(person("1"), "name", str("Bob")),
+				triple(person("1"), "name", str("Robert")),
+			},
+		}
+
+		vs := s.Check(g)
+		expected := []validate.Violation{
+			{TripleIndex: 0, Field: "triples[0].subject.type", Description: `unknown type: "Persn"`},
+			{TripleIndex: 1, Field: "triples[1].predicate.name", Description: `predicate "nmae" is not declared on type "Person"`},
+			{TripleIndex: 2, Field: "triples[2].object", Description: "expected int64 but got string"},
+			{TripleIndex: 3, Field: "triples[3].object", Description: "expected timestamp but got string"},
+			{TripleIndex: 4, Field: "triples[4].object.subject.type", Description: `expected subject of type "Organization" but got "Person"`},
+			{TripleIndex: 6, Field: "triples[6].predicate.name", Description: `predicate "name" allows one value per subject but triple 5 already sets a different one`},
+		}
+		if !assert.Equal(subT, expected, vs) {
+			return
+		}
+	})
+}
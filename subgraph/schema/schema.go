@@ -0,0 +1,239 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package schema declares which subject types, and which predicates on
+// those types, are allowed in subgraphs.
+//
+// A schema is written in YAML (or JSON) and looks like:
+//
+//	types:
+//	  Person:
+//	    predicates:
+//	      name:
+//	        kind: string
+//	        cardinality: one
+//	      knows:
+//	        kind: subject
+//	        type: Person
+//	        cardinality: many
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kind is the kind of value a predicate points to.
+type Kind int
+
+const (
+	KindSubject Kind = iota + 1
+	KindString
+	KindInt64
+	KindFloat64
+	KindBool
+	KindTimestamp
+	KindBytes
+	KindGeo
+	KindLangString
+)
+
+var kindNames = map[Kind]string{
+	KindSubject:    "subject",
+	KindString:     "string",
+	KindInt64:      "int64",
+	KindFloat64:    "float64",
+	KindBool:       "bool",
+	KindTimestamp:  "timestamp",
+	KindBytes:      "bytes",
+	KindGeo:        "geo",
+	KindLangString: "langstring",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// ParseKind parses the name of a kind, e.g. int64.
+func ParseKind(s string) (Kind, error) {
+	for k, name := range kindNames {
+		if name == s {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown kind: %q", s)
+}
+
+// Cardinality is how many values a subject may have for a predicate.
+type Cardinality int
+
+const (
+	// Many allows a subject to have any number of values for a predicate.
+	Many Cardinality = iota
+
+	// One allows a subject to have at most one value for a predicate.
+	One
+)
+
+func (c Cardinality) String() string {
+	if c == One {
+		return "one"
+	}
+	return "many"
+}
+
+func parseCardinality(s string) (Cardinality, error) {
+	switch s {
+	case "", "many":
+		return Many, nil
+	case "one":
+		return One, nil
+	default:
+		return 0, fmt.Errorf("unknown cardinality: %q", s)
+	}
+}
+
+// Predicate
+type Predicate struct {
+	Name string
+	Kind Kind
+
+	// Type is the subject type a KindSubject predicate must point to.
+	Type string
+
+	Cardinality Cardinality
+}
+
+// Type
+type Type struct {
+	Name       string
+	Predicates map[string]*Predicate
+}
+
+// Schema
+type Schema struct {
+	Types map[string]*Type
+}
+
+// Predicate returns the predicate declared on the given type, if any.
+func (s *Schema) Predicate(typ, name string) (*Predicate, bool) {
+	t, ok := s.Types[typ]
+	if !ok {
+		return nil, false
+	}
+	p, ok := t.Predicates[name]
+	return p, ok
+}
+
+type schemaFile struct {
+	Types map[string]typeFile `yaml:"types"`
+}
+
+type typeFile struct {
+	Predicates map[string]predicateFile `yaml:"predicates"`
+}
+
+type predicateFile struct {
+	Kind        string `yaml:"kind"`
+	Type        string `yaml:"type"`
+	Cardinality string `yaml:"cardinality"`
+}
+
+// Parse parses a YAML, or JSON, encoded schema.
+func Parse(b []byte) (*Schema, error) {
+	var sf schemaFile
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	err := dec.Decode(&sf)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{Types: make(map[string]*Type, len(sf.Types))}
+	for typName, tf := range sf.Types {
+		if strings.TrimSpace(typName) == "" {
+			return nil, fmt.Errorf("type name must not be empty")
+		}
+
+		t := &Type{
+			Name:       typName,
+			Predicates: make(map[string]*Predicate, len(tf.Predicates)),
+		}
+		for predName, pf := range tf.Predicates {
+			p, err := parsePredicate(predName, pf)
+			if err != nil {
+				return nil, fmt.Errorf("type %s: predicate %s: %w", typName, predName, err)
+			}
+			t.Predicates[predName] = p
+		}
+		s.Types[typName] = t
+	}
+
+	// subject predicates must reference declared types
+	for _, t := range s.Types {
+		for _, p := range t.Predicates {
+			if p.Kind != KindSubject {
+				continue
+			}
+			if _, ok := s.Types[p.Type]; !ok {
+				return nil, fmt.Errorf("type %s: predicate %s: references unknown type: %q", t.Name, p.Name, p.Type)
+			}
+		}
+	}
+	return s, nil
+}
+
+func parsePredicate(name string, pf predicateFile) (*Predicate, error) {
+	kind, err := ParseKind(pf.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if kind == KindSubject && pf.Type == "" {
+		return nil, fmt.Errorf("subject kind requires a type")
+	}
+	if kind != KindSubject && pf.Type != "" {
+		return nil, fmt.Errorf("only subject kind may declare a type")
+	}
+
+	card, err := parseCardinality(pf.Cardinality)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Predicate{
+		Name:        name,
+		Kind:        kind,
+		Type:        pf.Type,
+		Cardinality: card,
+	}
+	return p, nil
+}
+
+// Load reads and parses the named schema file.
+func Load(filename string) (*Schema, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `
+types:
+  Person:
+    predicates:
+      name:
+        kind: string
+        cardinality: one
+      age:
+        kind: int64
+        cardinality: one
+      height:
+        kind: float64
+      alive:
+        kind: bool
+      born:
+        kind: timestamp
+      avatar:
+        kind: bytes
+      location:
+        kind: geo
+      bio:
+        kind: langstring
+      knows:
+        kind: subject
+        type: Person
+      worksFor:
+        kind: subject
+        type: Organization
+  Organization:
+    predicates:
+      name:
+        kind: string
+`
+
+func TestParse(t *testing.T) {
+	t.Run("should parse every type and predicate", func(subT *testing.T) {
+		s, err := Parse([]byte(testSchema))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, s.Types, 2) {
+			return
+		}
+
+		p, ok := s.Predicate("Person", "knows")
+		if !assert.True(subT, ok) {
+			return
+		}
+		expected := &Predicate{
+			Name:        "knows",
+			Kind:        KindSubject,
+			Type:        "Person",
+			Cardinality: Many,
+		}
+		if !assert.Equal(subT, expected, p) {
+			return
+		}
+
+		p, ok = s.Predicate("Person", "name")
+		if !assert.True(subT, ok) {
+			return
+		}
+		if !assert.Equal(subT, One, p.Cardinality) {
+			return
+		}
+	})
+
+	t.Run("should parse json", func(subT *testing.T) {
+		s, err := Parse([]byte(`{"types":{"Person":{"predicates":{"name":{"kind":"string"}}}}}`))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		_, ok := s.Predicate("Person", "name")
+		if !assert.True(subT, ok) {
+			return
+		}
+	})
+
+	testCases := []struct {
+		Name   string
+		Schema string
+	}{
+		{
+			Name:   "unknown kind",
+			Schema: `{"types":{"Person":{"predicates":{"name":{"kind":"text"}}}}}`,
+		},
+		{
+			Name:   "unknown cardinality",
+			Schema: `{"types":{"Person":{"predicates":{"name":{"kind":"string","cardinality":"two"}}}}}`,
+		},
+		{
+			Name:   "subject kind without a type",
+			Schema: `{"types":{"Person":{"predicates":{"knows":{"kind":"subject"}}}}}`,
+		},
+		{
+			Name:   "subject kind referencing an unknown type",
+			Schema: `{"types":{"Person":{"predicates":{"knows":{"kind":"subject","type":"Persn"}}}}}`,
+		},
+		{
+			Name:   "non-subject kind with a type",
+			Schema: `{"types":{"Person":{"predicates":{"name":{"kind":"string","type":"Person"}}}}}`,
+		},
+		{
+			Name:   "unknown field",
+			Schema: `{"types":{"Person":{"predicates":{"name":{"knd":"string"}}}}}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run("should fail on "+tc.Name, func(subT *testing.T) {
+			_, err := Parse([]byte(tc.Schema))
+			if !assert.NotNil(subT, err) {
+				return
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("should load a schema file", func(subT *testing.T) {
+		name := filepath.Join(subT.TempDir(), "schema.yaml")
+		err := os.WriteFile(name, []byte(testSchema), 0o644)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		s, err := Load(name)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, s.Types, 2) {
+			return
+		}
+	})
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"bytes"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+// Pattern matches triples. A nil field matches any value.
+type Pattern struct {
+	Subject   *subgraph.Subject
+	Predicate *subgraph.Predicate
+	Object    *subgraph.Object
+}
+
+// Find returns every triple which matches the pattern, using
+// whichever index is most selective for the given fields.
+func (s *Store) Find(p Pattern) ([]*subgraph.Triple, error) {
+	var obj []byte
+	if p.Object != nil {
+		var err error
+		obj, err = objectKey(p.Object)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var triples []*subgraph.Triple
+	err := s.db.View(func(tx *bolt.Tx) error {
+		idx := newIndexes(tx, s.schema)
+		collect := func(spo []byte) error {
+			b := idx.spo.Get(spo)
+			if b == nil {
+				return nil
+			}
+			var t subgraph.Triple
+			err := proto.Unmarshal(b, &t)
+			if err != nil {
+				return err
+			}
+			triples = append(triples, &t)
+			return nil
+		}
+
+		switch {
+		case p.Subject != nil:
+			prefix := subjectKey(p.Subject)
+			if p.Predicate != nil {
+				prefix = appendComponent(prefix, []byte(p.Predicate.Name))
+				if obj != nil {
+					return collect(appendComponent(prefix, obj))
+				}
+			}
+			return scan(idx.spo, prefix, func(k []byte) error {
+				if obj != nil {
+					_, _, o, ok := splitSPOKey(k)
+					if !ok || !bytes.Equal(o, obj) {
+						return nil
+					}
+				}
+				return collect(k)
+			})
+		case p.Predicate != nil:
+			prefix := appendComponent(nil, []byte(p.Predicate.Name))
+			if obj != nil {
+				prefix = appendComponent(prefix, obj)
+			}
+			return scan(idx.pos, prefix, func(k []byte) error {
+				pred, rest, _ := splitComponent(k)
+				o, subj, _ := splitComponent(rest)
+				return collect(spoKey(subj, pred, o))
+			})
+		case obj != nil:
+			return scan(idx.osp, appendComponent(nil, obj), func(k []byte) error {
+				o, rest, _ := splitComponent(k)
+				_, predComponent, _ := splitComponent(rest)
+				subj := rest[:len(rest)-len(predComponent)]
+				pred, _, _ := splitComponent(predComponent)
+				return collect(spoKey(subj, pred, o))
+			})
+		default:
+			return scan(idx.spo, nil, collect)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return triples, nil
+}
+
+// Provenance returns the provenance of the subgraph the assertion of the
+// triple, identified by its timestamp, was published in. If the triple has no
+// timestamp, the stored assertion of its value is used. It returns nil if the
+// assertion is not stored or was applied without any, e.g. by Apply.
+func (s *Store) Provenance(t *subgraph.Triple) (*subgraph.Provenance, error) {
+	obj, err := objectKey(t.GetObject())
+	if err != nil {
+		return nil, err
+	}
+	key := spoKey(subjectKey(t.GetSubject()), []byte(t.GetPredicate().GetName()), obj)
+
+	var prov *subgraph.Provenance
+	err = s.db.View(func(tx *bolt.Tx) error {
+		ts := t.GetHlc()
+		if ts == nil {
+			stored, err := newIndexes(tx, s.schema).triple(tx.Bucket(spoBucket), key)
+			if err != nil || stored == nil {
+				return err
+			}
+			ts = stored.Hlc
+		}
+		tag, err := tagKey(ts)
+		if err != nil {
+			return err
+		}
+
+		b := tx.Bucket(provenanceBucket).Get(appendComponent(key, tag))
+		if b == nil {
+			return nil
+		}
+		prov = new(subgraph.Provenance)
+		return proto.Unmarshal(b, prov)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prov, nil
+}
+
+func scan(b *bolt.Bucket, prefix []byte, fn func([]byte) error) error {
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, _ = c.Next() {
+		err := fn(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
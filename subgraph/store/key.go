@@ -0,0 +1,118 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Keys are made of length prefixed components so that a key
+// for a subject is never a prefix of the key of another subject,
+// e.g. Person/1 and Person/10.
+func appendComponent(b []byte, c []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(c)))
+	return append(b, c...)
+}
+
+func splitComponent(b []byte) (c []byte, rest []byte, ok bool) {
+	n, m := binary.Uvarint(b)
+	if m <= 0 || uint64(len(b)-m) < n {
+		return nil, nil, false
+	}
+	return b[m : m+int(n)], b[m+int(n):], true
+}
+
+// subjectKey encodes the type and tuid of the subject as a single component.
+func subjectKey(s *subgraph.Subject) []byte {
+	var b []byte
+	b = appendComponent(b, []byte(s.GetType()))
+	b = appendComponent(b, []byte(s.GetTuid()))
+	return appendComponent(nil, b)
+}
+
+func objectKey(o *subgraph.Object) ([]byte, error) {
+	return proto.MarshalOptions{Deterministic: true}.Marshal(o)
+}
+
+// spoKey expects subj to already be encoded as a component
+// by subjectKey, whereas pred and obj are raw.
+func spoKey(subj, pred, obj []byte) []byte {
+	return appendComponent(appendComponent(clone(subj), pred), obj)
+}
+
+func posKey(subj, pred, obj []byte) []byte {
+	return append(appendComponent(appendComponent(nil, pred), obj), subj...)
+}
+
+func ospKey(subj, pred, obj []byte) []byte {
+	return appendComponent(append(appendComponent(nil, obj), subj...), pred)
+}
+
+// splitSPOKey returns the encoded subject along with the raw predicate and object.
+func splitSPOKey(key []byte) (subj, pred, obj []byte, ok bool) {
+	_, rest, ok := splitComponent(key)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	subj = key[:len(key)-len(rest)]
+	pred, rest, ok = splitComponent(rest)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	obj, rest, ok = splitComponent(rest)
+	if !ok || len(rest) > 0 {
+		return nil, nil, nil, false
+	}
+	return subj, pred, obj, true
+}
+
+// tagKey encodes the timestamp of an assertion, which identifies it
+// among every assertion of the same value.
+func tagKey(ts *subgraph.HybridTimestamp) ([]byte, error) {
+	return proto.MarshalOptions{Deterministic: true}.Marshal(ts)
+}
+
+// splitAddKey splits the key of an assertion, i.e. its spo key followed
+// by its tag as a component, into the spo key and the raw tag.
+func splitAddKey(key []byte) (spo, tag []byte, ok bool) {
+	rest := key
+	for i := 0; i < 3; i++ {
+		_, rest, ok = splitComponent(rest)
+		if !ok {
+			return nil, nil, false
+		}
+	}
+	spo = key[:len(key)-len(rest)]
+	tag, rest, ok = splitComponent(rest)
+	if !ok || len(rest) > 0 {
+		return nil, nil, false
+	}
+	return spo, tag, true
+}
+
+func hasPrefix(b, prefix []byte) bool {
+	return bytes.HasPrefix(b, prefix)
+}
+
+func clone(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
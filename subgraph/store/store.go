@@ -0,0 +1,637 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package store is an embedded, file-backed triple store.
+//
+// Every triple is indexed by subject, predicate and object so that
+// triples can be found by any combination of the three.
+//
+// Operations are resolved by their hybrid logical clock timestamp, with
+// the same semantics as the merge package, rather than by the order they
+// are applied in. Every value is stored with the timestamp of the assertion
+// it came from, and every retraction and subject deletion leaves a tombstone,
+// so an operation which arrives late, e.g. from a replica which was behind,
+// never overrides a later one. Triples without a timestamp are timestamped
+// in the order they are applied.
+//
+// Predicates declared with a cardinality of many hold an observed-remove set
+// of values, as they do in the merge package. Every assertion of their values
+// is kept, so a retraction which lists the assertions it observed removes
+// only those.
+//
+// Triples which are published keep the provenance of their subgraph, so the
+// source, receive time and caller of any stored value can be looked up.
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/merge"
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrMissingTriple is returned when a nil triple is applied.
+	ErrMissingTriple = errors.New("store: missing triple")
+
+	// ErrUnsupportedOperation is returned when a triple has an unknown operation.
+	ErrUnsupportedOperation = errors.New("store: unsupported operation")
+)
+
+var (
+	spoBucket = []byte("spo")
+	posBucket = []byte("pos")
+	ospBucket = []byte("osp")
+
+	// tombstoneBucket holds the timestamp of the latest removal of a
+	// subject, predicate or value, keyed by the prefix of its spo keys.
+	tombstoneBucket = []byte("tombstones")
+
+	// registerBucket holds the latest assertion of every predicate which
+	// holds a single value, keyed by the prefix of its spo keys.
+	registerBucket = []byte("registers")
+
+	// provenanceBucket holds the provenance of the subgraph every
+	// stored assertion was published in, keyed by its add key.
+	provenanceBucket = []byte("provenance")
+
+	// addBucket holds every assertion of the values of predicates which
+	// hold an observed-remove set, keyed by their add key.
+	addBucket = []byte("adds")
+
+	// observedBucket holds the timestamp of the retraction which removed
+	// an assertion it observed, keyed by the prefix of the spo keys it
+	// retracted followed by the tag of the assertion.
+	observedBucket = []byte("observed")
+)
+
+// Option
+type Option func(*options)
+
+type options struct {
+	mode    os.FileMode
+	timeout time.Duration
+	noSync  bool
+	schema  *schema.Schema
+	clock   *merge.Clock
+}
+
+// WithFileMode sets the permissions the store file is created with.
+func WithFileMode(mode os.FileMode) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// WithLockTimeout sets how long to wait for another process to release
+// the store file. By default Open waits indefinitely.
+func WithLockTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithNoSync skips fsync after each write. This is faster but
+// writes may be lost if the machine crashes.
+func WithNoSync() Option {
+	return func(o *options) {
+		o.noSync = true
+	}
+}
+
+// WithSchema uses the schema to decide how the values of each predicate are
+// resolved, as it does in the merge package. Assertions of predicates which
+// hold a single value replace each other by last-writer-wins, predicates
+// declared with a cardinality of many hold an observed-remove set and every
+// other predicate holds a set of values.
+func WithSchema(sc *schema.Schema) Option {
+	return func(o *options) {
+		o.schema = sc
+	}
+}
+
+// WithClock sets the clock used to timestamp triples which are applied
+// without a timestamp. It defaults to a clock with the node ID "store".
+func WithClock(c *merge.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// Store is an embedded triple store. A Store is safe for concurrent use.
+type Store struct {
+	db     *bolt.DB
+	schema *schema.Schema
+	clock  *merge.Clock
+}
+
+// Open opens the store file, creating it if it does not exist yet.
+func Open(path string, opts ...Option) (*Store, error) {
+	o := options{mode: 0o600}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.clock == nil {
+		o.clock = merge.NewClock("store")
+	}
+
+	db, err := bolt.Open(path, o.mode, &bolt.Options{
+		Timeout: o.timeout,
+		NoSync:  o.noSync,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{spoBucket, posBucket, ospBucket, tombstoneBucket, registerBucket, provenanceBucket, addBucket, observedBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db, schema: o.schema, clock: o.clock}, nil
+}
+
+// Close closes the store file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Publish applies every triple in the subgraph in a single transaction.
+// It allows the store to be used as a sink for ingested subgraphs. The
+// provenance of the subgraph, e.g. its source URI, when it was received and
+// by which caller, is kept with every triple it asserts, see Provenance.
+func (s *Store) Publish(ctx context.Context, g *subgraph.Subgraph) error {
+	return s.apply(g.GetProvenance(), g.GetTriples())
+}
+
+// Apply applies the triples in a single transaction. The outcome does not
+// depend on the order of the triples, except for those without a timestamp,
+// which are timestamped in order. The triples are expected to have already
+// passed validate.Triple.
+func (s *Store) Apply(triples ...*subgraph.Triple) error {
+	return s.apply(nil, triples)
+}
+
+func (s *Store) apply(prov *subgraph.Provenance, triples []*subgraph.Triple) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		idx := newIndexes(tx, s.schema)
+		idx.prov = prov
+		for _, t := range triples {
+			if t != nil && t.Hlc == nil {
+				t = proto.Clone(t).(*subgraph.Triple)
+				t.Hlc = s.clock.Now()
+			}
+			err := idx.apply(t)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type indexes struct {
+	spo, pos, osp *bolt.Bucket
+	tombstones    *bolt.Bucket
+	registers     *bolt.Bucket
+	provenance    *bolt.Bucket
+	adds          *bolt.Bucket
+	observed      *bolt.Bucket
+	schema        *schema.Schema
+
+	// prov is the provenance of the triples being applied, if any
+	prov *subgraph.Provenance
+}
+
+func newIndexes(tx *bolt.Tx, sc *schema.Schema) indexes {
+	return indexes{
+		spo:        tx.Bucket(spoBucket),
+		pos:        tx.Bucket(posBucket),
+		osp:        tx.Bucket(ospBucket),
+		tombstones: tx.Bucket(tombstoneBucket),
+		registers:  tx.Bucket(registerBucket),
+		provenance: tx.Bucket(provenanceBucket),
+		adds:       tx.Bucket(addBucket),
+		observed:   tx.Bucket(observedBucket),
+		schema:     sc,
+	}
+}
+
+func (idx indexes) apply(t *subgraph.Triple) error {
+	if t == nil {
+		return ErrMissingTriple
+	}
+
+	subj := subjectKey(t.Subject)
+	switch t.Operation {
+	case subgraph.Operation_ASSERT:
+		return idx.assert(t)
+	case subgraph.Operation_RETRACT:
+		prefix := appendComponent(subj, []byte(t.GetPredicate().GetName()))
+		if t.Object != nil {
+			obj, err := objectKey(t.Object)
+			if err != nil {
+				return err
+			}
+			prefix = appendComponent(prefix, obj)
+		}
+		if len(t.Observed) > 0 && idx.observedRemove(t) {
+			return idx.removeObserved(prefix, t)
+		}
+		return idx.remove(prefix, t.Hlc)
+	case subgraph.Operation_DELETE_SUBJECT:
+		return idx.remove(subj, t.Hlc)
+	default:
+		return ErrUnsupportedOperation
+	}
+}
+
+// assert stores the triple unless it has been removed by a later, or
+// concurrent, retraction or subject deletion, or by a retraction which
+// observed it. The stored value is the latest assertion of it which has
+// not been removed and, for single valued predicates, only the latest
+// assertion of any value is stored.
+func (idx indexes) assert(t *subgraph.Triple) error {
+	subj := subjectKey(t.Subject)
+	pred := appendComponent(clone(subj), []byte(t.GetPredicate().GetName()))
+	obj, err := objectKey(t.Object)
+	if err != nil {
+		return err
+	}
+	key := appendComponent(clone(pred), obj)
+
+	if idx.singleValued(t) {
+		// The latest assertion wins even if it has been removed since,
+		// in which case the predicate has no value.
+		winner, err := idx.triple(idx.registers, pred)
+		if err != nil {
+			return err
+		}
+		if winner != nil && !merge.After(t, winner) {
+			return nil
+		}
+		b, err := proto.Marshal(t)
+		if err != nil {
+			return err
+		}
+		err = idx.registers.Put(pred, b)
+		if err != nil {
+			return err
+		}
+		err = idx.deletePrefix(pred, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, prefix := range [][]byte{subj, pred, key} {
+		removedAt, err := idx.tombstone(prefix)
+		if err != nil {
+			return err
+		}
+		if removedAt != nil && merge.Compare(t.Hlc, removedAt) <= 0 {
+			return nil
+		}
+	}
+
+	tag, err := tagKey(t.Hlc)
+	if err != nil {
+		return err
+	}
+	add := appendComponent(clone(key), tag)
+	if idx.observedRemove(t) {
+		for _, prefix := range [][]byte{pred, key} {
+			if idx.observed.Get(appendComponent(clone(prefix), tag)) != nil {
+				return nil
+			}
+		}
+		b, err := proto.Marshal(t)
+		if err != nil {
+			return err
+		}
+		err = idx.adds.Put(add, b)
+		if err != nil {
+			return err
+		}
+		err = idx.putProvenance(add)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing, err := idx.triple(idx.spo, key)
+	if err != nil {
+		return err
+	}
+	if existing != nil && !merge.After(t, existing) {
+		return nil
+	}
+	if !idx.observedRemove(t) {
+		// Only the provenance of the stored assertion is kept.
+		err = idx.deleteWithPrefix(idx.provenance, key)
+		if err != nil {
+			return err
+		}
+		err = idx.putProvenance(add)
+		if err != nil {
+			return err
+		}
+	}
+	return idx.put(t)
+}
+
+// remove records a tombstone for the prefix and deletes every triple
+// under it which was asserted at, or before, the removal.
+func (idx indexes) remove(prefix []byte, ts *subgraph.HybridTimestamp) error {
+	removedAt, err := idx.tombstone(prefix)
+	if err != nil {
+		return err
+	}
+	if merge.Compare(ts, removedAt) > 0 {
+		b, err := proto.Marshal(ts)
+		if err != nil {
+			return err
+		}
+		err = idx.tombstones.Put(prefix, b)
+		if err != nil {
+			return err
+		}
+	}
+	err = idx.deletePrefix(prefix, ts)
+	if err != nil {
+		return err
+	}
+
+	// Earlier assertions of values which are still stored
+	// must not be restored by a later removal of observed ones.
+	return idx.deleteAdds(prefix, func(_ []byte, t *subgraph.Triple) bool {
+		return merge.Compare(t.Hlc, ts) <= 0
+	})
+}
+
+// removeObserved removes the assertions under the prefix which the retraction
+// observed, including those which are only applied after it, and stores the
+// latest remaining assertion of every value it removed assertions of.
+func (idx indexes) removeObserved(prefix []byte, retraction *subgraph.Triple) error {
+	removedAt, err := proto.Marshal(retraction.Hlc)
+	if err != nil {
+		return err
+	}
+	tags := make(map[string]bool, len(retraction.Observed))
+	for _, ts := range retraction.Observed {
+		tag, err := tagKey(ts)
+		if err != nil {
+			return err
+		}
+		tags[string(tag)] = true
+		err = idx.observed.Put(appendComponent(clone(prefix), tag), removedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	values := make(map[string]bool)
+	err = idx.deleteAdds(prefix, func(add []byte, _ *subgraph.Triple) bool {
+		key, tag, ok := splitAddKey(add)
+		if !ok || !tags[string(tag)] {
+			return false
+		}
+		values[string(key)] = true
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for key := range values {
+		err := idx.refresh([]byte(key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refresh stores the latest remaining assertion of the value at key,
+// or deletes the value if none remain.
+func (idx indexes) refresh(key []byte) error {
+	var latest *subgraph.Triple
+	c := idx.adds.Cursor()
+	for k, v := c.Seek(key); k != nil && hasPrefix(k, key); k, v = c.Next() {
+		var t subgraph.Triple
+		err := proto.Unmarshal(v, &t)
+		if err != nil {
+			return err
+		}
+		if merge.After(&t, latest) {
+			latest = &t
+		}
+	}
+	if latest == nil {
+		return idx.deleteKey(key)
+	}
+	return idx.put(latest)
+}
+
+// deleteAdds deletes every assertion, and its provenance, in the
+// adds bucket under the prefix which the match function matches.
+func (idx indexes) deleteAdds(prefix []byte, match func([]byte, *subgraph.Triple) bool) error {
+	var keys [][]byte
+	c := idx.adds.Cursor()
+	for k, v := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, v = c.Next() {
+		var t subgraph.Triple
+		err := proto.Unmarshal(v, &t)
+		if err != nil {
+			return err
+		}
+		if match(k, &t) {
+			keys = append(keys, clone(k))
+		}
+	}
+	for _, k := range keys {
+		err := idx.adds.Delete(k)
+		if err != nil {
+			return err
+		}
+		err = idx.provenance.Delete(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteWithPrefix deletes every key of the bucket which starts with the prefix.
+func (idx indexes) deleteWithPrefix(b *bolt.Bucket, prefix []byte) error {
+	var keys [][]byte
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, clone(k))
+	}
+	for _, k := range keys {
+		err := b.Delete(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx indexes) tombstone(prefix []byte) (*subgraph.HybridTimestamp, error) {
+	b := idx.tombstones.Get(prefix)
+	if b == nil {
+		return nil, nil
+	}
+	var ts subgraph.HybridTimestamp
+	err := proto.Unmarshal(b, &ts)
+	if err != nil {
+		return nil, err
+	}
+	return &ts, nil
+}
+
+func (idx indexes) triple(bucket *bolt.Bucket, key []byte) (*subgraph.Triple, error) {
+	b := bucket.Get(key)
+	if b == nil {
+		return nil, nil
+	}
+	var t subgraph.Triple
+	err := proto.Unmarshal(b, &t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (idx indexes) singleValued(t *subgraph.Triple) bool {
+	if idx.schema == nil {
+		return false
+	}
+	p, ok := idx.schema.Predicate(t.GetSubject().GetType(), t.GetPredicate().GetName())
+	return ok && p.Cardinality == schema.One
+}
+
+// observedRemove reports whether the predicate of the triple is declared
+// with a cardinality of many, so its values hold an observed-remove set.
+func (idx indexes) observedRemove(t *subgraph.Triple) bool {
+	if idx.schema == nil {
+		return false
+	}
+	p, ok := idx.schema.Predicate(t.GetSubject().GetType(), t.GetPredicate().GetName())
+	return ok && p.Cardinality != schema.One
+}
+
+func (idx indexes) put(t *subgraph.Triple) error {
+	subj := subjectKey(t.Subject)
+	pred := []byte(t.GetPredicate().GetName())
+	obj, err := objectKey(t.Object)
+	if err != nil {
+		return err
+	}
+
+	b, err := proto.Marshal(t)
+	if err != nil {
+		return err
+	}
+	err = idx.spo.Put(spoKey(subj, pred, obj), b)
+	if err != nil {
+		return err
+	}
+	err = idx.pos.Put(posKey(subj, pred, obj), nil)
+	if err != nil {
+		return err
+	}
+	return idx.osp.Put(ospKey(subj, pred, obj), nil)
+}
+
+// putProvenance records the provenance being applied for the assertion
+// at the add key, or deletes any stale provenance if there is none.
+func (idx indexes) putProvenance(key []byte) error {
+	if idx.prov == nil {
+		return idx.provenance.Delete(key)
+	}
+	b, err := proto.Marshal(idx.prov)
+	if err != nil {
+		return err
+	}
+	return idx.provenance.Put(key, b)
+}
+
+// deletePrefix deletes every triple whose spo key starts with the prefix
+// and, if before is not nil, which was asserted at or before it.
+func (idx indexes) deletePrefix(prefix []byte, before *subgraph.HybridTimestamp) error {
+	var keys [][]byte
+	c := idx.spo.Cursor()
+	for k, v := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, v = c.Next() {
+		if before != nil {
+			var t subgraph.Triple
+			err := proto.Unmarshal(v, &t)
+			if err != nil {
+				return err
+			}
+			if merge.Compare(t.Hlc, before) > 0 {
+				continue
+			}
+		}
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, k := range keys {
+		err := idx.deleteKey(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx indexes) deleteKey(key []byte) error {
+	subj, pred, obj, ok := splitSPOKey(key)
+	if !ok || idx.spo.Get(key) == nil {
+		return nil
+	}
+
+	err := idx.spo.Delete(key)
+	if err != nil {
+		return err
+	}
+	err = idx.deleteWithPrefix(idx.provenance, key)
+	if err != nil {
+		return err
+	}
+	err = idx.deleteWithPrefix(idx.adds, key)
+	if err != nil {
+		return err
+	}
+	err = idx.pos.Delete(posKey(subj, pred, obj))
+	if err != nil {
+		return err
+	}
+	return idx.osp.Delete(ospKey(subj, pred, obj))
+}
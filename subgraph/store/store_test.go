@@ -0,0 +1,514 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/merge"
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func person(tuid string) *subgraph.Subject {
+	return &subgraph.Subject{Type: "Person", Tuid: tuid}
+}
+
+func str(s string) *subgraph.Object {
+	return &subgraph.Object{Value: &subgraph.Object_String_{String_: s}}
+}
+
+func ref(subj *subgraph.Subject) *subgraph.Object {
+	return &subgraph.Object{Value: &subgraph.Object_Subject{Subject: subj}}
+}
+
+func triple(subj *subgraph.Subject, pred string, obj *subgraph.Object) *subgraph.Triple {
+	return &subgraph.Triple{
+		Subject:   subj,
+		Predicate: &subgraph.Predicate{Name: pred},
+		Object:    obj,
+	}
+}
+
+func hlc(wall int64, nodeID string) *subgraph.HybridTimestamp {
+	return &subgraph.HybridTimestamp{WallTime: wall, NodeId: nodeID}
+}
+
+func at(t *subgraph.Triple, op subgraph.Operation, ts *subgraph.HybridTimestamp) *subgraph.Triple {
+	t.Operation = op
+	t.Hlc = ts
+	return t
+}
+
+func newTestSchema(t *testing.T) *schema.Schema {
+	s, err := schema.Parse([]byte(`
+types:
+  Person:
+    predicates:
+      name:
+        kind: string
+        cardinality: one
+      nickname:
+        kind: string
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// permutations calls f with every ordering of the triples.
+func permutations(triples []*subgraph.Triple, f func([]*subgraph.Triple) bool) bool {
+	if len(triples) <= 1 {
+		return f(triples)
+	}
+	for i := range triples {
+		rest := make([]*subgraph.Triple, 0, len(triples)-1)
+		rest = append(rest, triples[:i]...)
+		rest = append(rest, triples[i+1:]...)
+		ok := permutations(rest, func(p []*subgraph.Triple) bool {
+			return f(append([]*subgraph.Triple{triples[i]}, p...))
+		})
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func openTestStore(t *testing.T, opts ...Option) (*Store, string) {
+	path := filepath.Join(t.TempDir(), "megamind.db")
+	s, err := Open(path, append([]Option{WithNoSync()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+// spo formats triples as subject tuid, predicate and string object or subject tuid.
+func spo(triples []*subgraph.Triple) []string {
+	var ss []string
+	for _, t := range triples {
+		obj := t.GetObject().GetString_()
+		if subj := t.GetObject().GetSubject(); subj != nil {
+			obj = "->" + subj.Tuid
+		}
+		ss = append(ss, t.Subject.Tuid+" "+t.Predicate.Name+" "+obj)
+	}
+	return ss
+}
+
+func newTestGraph() *subgraph.Subgraph {
+	return &subgraph.Subgraph{
+		Triples: []*subgraph.Triple{
+			triple(person("1"), "name", str("Bob")),
+			triple(person("1"), "nickname", str("Rob")),
+			triple(person("1"), "knows", ref(person("2"))),
+			triple(person("10"), "name", str("Alice")),
+			triple(person("10"), "knows", ref(person("2"))),
+			triple(person("2"), "name", str("Bob")),
+		},
+	}
+}
+
+func TestStore_Find(t *testing.T) {
+	s, _ := openTestStore(t)
+	err := s.Publish(context.Background(), newTestGraph())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	testCases := []struct {
+		Name     string
+		Pattern  Pattern
+		Expected []string
+	}{
+		{
+			Name:     "subject",
+			Pattern:  Pattern{Subject: person("1")},
+			Expected: []string{"1 knows ->2", "1 name Bob", "1 nickname Rob"},
+		},
+		{
+			Name:     "subject and predicate",
+			Pattern:  Pattern{Subject: person("1"), Predicate: &subgraph.Predicate{Name: "name"}},
+			Expected: []string{"1 name Bob"},
+		},
+		{
+			Name:     "subject, predicate and object",
+			Pattern:  Pattern{Subject: person("1"), Predicate: &subgraph.Predicate{Name: "name"}, Object: str("Bob")},
+			Expected: []string{"1 name Bob"},
+		},
+		{
+			Name:     "subject and object",
+			Pattern:  Pattern{Subject: person("1"), Object: str("Rob")},
+			Expected: []string{"1 nickname Rob"},
+		},
+		{
+			Name:     "predicate",
+			Pattern:  Pattern{Predicate: &subgraph.Predicate{Name: "knows"}},
+			Expected: []string{"1 knows ->2", "10 knows ->2"},
+		},
+		{
+			Name:     "predicate and object",
+			Pattern:  Pattern{Predicate: &subgraph.Predicate{Name: "name"}, Object: str("Bob")},
+			Expected: []string{"1 name Bob", "2 name Bob"},
+		},
+		{
+			Name:     "object",
+			Pattern:  Pattern{Object: ref(person("2"))},
+			Expected: []string{"1 knows ->2", "10 knows ->2"},
+		},
+		{
+			Name:    "nothing",
+			Pattern: Pattern{},
+			Expected: []string{
+				"1 knows ->2", "1 name Bob", "1 nickname Rob",
+				"10 knows ->2", "10 name Alice",
+				"2 name Bob",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run("should find triples by "+tc.Name, func(subT *testing.T) {
+			triples, err := s.Find(tc.Pattern)
+			if !assert.Nil(subT, err) {
+				return
+			}
+			if !assert.ElementsMatch(subT, tc.Expected, spo(triples)) {
+				return
+			}
+		})
+	}
+}
+
+func TestStore_Apply(t *testing.T) {
+	t.Run("should persist triples across restarts", func(subT *testing.T) {
+		s, path := openTestStore(subT)
+		err := s.Publish(context.Background(), newTestGraph())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = s.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		s, err = Open(path)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer s.Close()
+
+		triples, err := s.Find(Pattern{Subject: person("10")})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.ElementsMatch(subT, []string{"10 knows ->2", "10 name Alice"}, spo(triples)) {
+			return
+		}
+	})
+
+	t.Run("should keep the facets and timestamp of a triple", func(subT *testing.T) {
+		s, _ := openTestStore(subT)
+
+		t := triple(person("1"), "name", str("Bob"))
+		t.Facets = map[string]*subgraph.FacetValue{
+			"confidence": {Value: &subgraph.FacetValue_Float64{Float64: 0.5}},
+		}
+		t.Hlc = &subgraph.HybridTimestamp{WallTime: 1, NodeId: "a"}
+		err := s.Apply(t)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		triples, err := s.Find(Pattern{Subject: person("1")})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, triples, 1) {
+			return
+		}
+		if !assert.Equal(subT, 0.5, triples[0].Facets["confidence"].GetFloat64()) {
+			return
+		}
+		if !assert.Equal(subT, "a", triples[0].GetHlc().GetNodeId()) {
+			return
+		}
+	})
+
+	t.Run("should remove retracted and deleted triples from every index", func(subT *testing.T) {
+		s, _ := openTestStore(subT)
+		err := s.Publish(context.Background(), newTestGraph())
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		retract := triple(person("1"), "name", str("Bob"))
+		retract.Operation = subgraph.Operation_RETRACT
+		retractAll := triple(person("1"), "nickname", nil)
+		retractAll.Operation = subgraph.Operation_RETRACT
+		del := &subgraph.Triple{Subject: person("10"), Operation: subgraph.Operation_DELETE_SUBJECT}
+		err = s.Apply(retract, retractAll, del)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		triples, err := s.Find(Pattern{})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.ElementsMatch(subT, []string{"1 knows ->2", "2 name Bob"}, spo(triples)) {
+			return
+		}
+
+		triples, err = s.Find(Pattern{Predicate: &subgraph.Predicate{Name: "name"}})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.ElementsMatch(subT, []string{"2 name Bob"}, spo(triples)) {
+			return
+		}
+
+		triples, err = s.Find(Pattern{Object: ref(person("2"))})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.ElementsMatch(subT, []string{"1 knows ->2"}, spo(triples)) {
+			return
+		}
+	})
+
+	t.Run("should apply operations in order", func(subT *testing.T) {
+		s, _ := openTestStore(subT)
+
+		retract := triple(person("1"), "name", str("Bob"))
+		retract.Operation = subgraph.Operation_RETRACT
+		err := s.Apply(
+			triple(person("1"), "name", str("Bob")),
+			retract,
+			triple(person("1"), "name", str("Bob")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		triples, err := s.Find(Pattern{Subject: person("1")})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1 name Bob"}, spo(triples)) {
+			return
+		}
+	})
+
+	t.Run("should not apply any triples if one fails", func(subT *testing.T) {
+		s, _ := openTestStore(subT)
+
+		err := s.Apply(triple(person("1"), "name", str("Bob")), nil)
+		if !assert.Equal(subT, ErrMissingTriple, err) {
+			return
+		}
+
+		triples, err := s.Find(Pattern{})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Empty(subT, triples) {
+			return
+		}
+	})
+
+	t.Run("should converge to the same triples as the merge package in any order", func(subT *testing.T) {
+		sc := newTestSchema(subT)
+		triples := []*subgraph.Triple{
+			at(triple(person("1"), "name", str("Bob")), subgraph.Operation_ASSERT, hlc(2, "a")),
+			at(triple(person("1"), "name", str("Rob")), subgraph.Operation_ASSERT, hlc(1, "b")),
+			at(triple(person("1"), "name", str("Bob")), subgraph.Operation_RETRACT, hlc(3, "b")),
+			at(triple(person("1"), "nickname", str("Bobby")), subgraph.Operation_ASSERT, hlc(4, "a")),
+			at(triple(person("1"), "nickname", nil), subgraph.Operation_RETRACT, hlc(3, "b")),
+		}
+
+		g := merge.New(merge.WithSchema(sc))
+		for _, t := range triples {
+			err := g.Apply(t)
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+		expected := spo(g.Triples())
+		if !assert.Equal(subT, []string{"1 nickname Bobby"}, expected) {
+			return
+		}
+
+		permutations(triples, func(p []*subgraph.Triple) bool {
+			s, _ := openTestStore(subT, WithSchema(sc))
+			defer s.Close()
+
+			err := s.Apply(p...)
+			if !assert.Nil(subT, err) {
+				return false
+			}
+			found, err := s.Find(Pattern{})
+			if !assert.Nil(subT, err) {
+				return false
+			}
+			return assert.ElementsMatch(subT, expected, spo(found), "applied in order: %v", spo(p))
+		})
+	})
+
+	t.Run("should only remove the assertions a retraction observed in any order", func(subT *testing.T) {
+		sc := newTestSchema(subT)
+		retract := at(triple(person("1"), "nickname", str("Bob")), subgraph.Operation_RETRACT, hlc(3, "b"))
+		retract.Observed = []*subgraph.HybridTimestamp{hlc(1, "a")}
+		retractAll := at(triple(person("1"), "nickname", nil), subgraph.Operation_RETRACT, hlc(4, "b"))
+		retractAll.Observed = []*subgraph.HybridTimestamp{hlc(1, "c")}
+		triples := []*subgraph.Triple{
+			at(triple(person("1"), "nickname", str("Bob")), subgraph.Operation_ASSERT, hlc(1, "a")),
+			at(triple(person("1"), "nickname", str("Bob")), subgraph.Operation_ASSERT, hlc(2, "a")),
+			retract,
+			at(triple(person("1"), "nickname", str("Rob")), subgraph.Operation_ASSERT, hlc(1, "c")),
+			retractAll,
+		}
+
+		g := merge.New(merge.WithSchema(sc))
+		for _, t := range triples {
+			err := g.Apply(t)
+			if !assert.Nil(subT, err) {
+				return
+			}
+		}
+		expected := g.Triples()
+		if !assert.Equal(subT, []string{"1 nickname Bob"}, spo(expected)) {
+			return
+		}
+
+		permutations(triples, func(p []*subgraph.Triple) bool {
+			s, _ := openTestStore(subT, WithSchema(sc))
+			defer s.Close()
+
+			err := s.Apply(p...)
+			if !assert.Nil(subT, err) {
+				return false
+			}
+			found, err := s.Find(Pattern{})
+			if !assert.Nil(subT, err) {
+				return false
+			}
+			if !assert.Len(subT, found, 1, "applied in order: %v", spo(p)) {
+				return false
+			}
+			return assert.True(subT, proto.Equal(expected[0], found[0]), "applied in order: %v", spo(p))
+		})
+	})
+
+	t.Run("should not let an earlier assertion undo a later retraction", func(subT *testing.T) {
+		s, _ := openTestStore(subT)
+
+		err := s.Apply(
+			at(triple(person("1"), "name", str("Bob")), subgraph.Operation_RETRACT, hlc(2, "a")),
+			at(&subgraph.Triple{Subject: person("2")}, subgraph.Operation_DELETE_SUBJECT, hlc(2, "a")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = s.Apply(
+			at(triple(person("1"), "name", str("Bob")), subgraph.Operation_ASSERT, hlc(1, "b")),
+			at(triple(person("2"), "name", str("Alice")), subgraph.Operation_ASSERT, hlc(2, "a")),
+			at(triple(person("2"), "name", str("Carol")), subgraph.Operation_ASSERT, hlc(3, "b")),
+		)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		triples, err := s.Find(Pattern{})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, []string{"2 name Carol"}, spo(triples)) {
+			return
+		}
+	})
+}
+
+func TestStore_Publish(t *testing.T) {
+	t.Run("should keep the provenance of the subgraph with its triples", func(subT *testing.T) {
+		s, _ := openTestStore(subT)
+
+		g := newTestGraph()
+		g.Provenance = &subgraph.Provenance{
+			SourceUri:  "s3://docs/bob.pdf",
+			ReceivedAt: timestamppb.New(time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)),
+			Caller:     "alice",
+		}
+		err := s.Publish(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		prov, err := s.Provenance(triple(person("1"), "name", str("Bob")))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.True(subT, proto.Equal(g.Provenance, prov)) {
+			return
+		}
+
+		prov, err = s.Provenance(triple(person("1"), "name", str("Alice")))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Nil(subT, prov) {
+			return
+		}
+	})
+
+	t.Run("should drop the provenance of removed triples", func(subT *testing.T) {
+		s, _ := openTestStore(subT)
+
+		g := newTestGraph()
+		g.Provenance = &subgraph.Provenance{SourceUri: "s3://docs/bob.pdf"}
+		err := s.Publish(context.Background(), g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		retract := triple(person("1"), "name", str("Bob"))
+		retract.Operation = subgraph.Operation_RETRACT
+		err = s.Apply(retract)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		prov, err := s.Provenance(triple(person("1"), "name", str("Bob")))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Nil(subT, prov) {
+			return
+		}
+	})
+}
@@ -24,6 +24,7 @@ package subgraph
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -35,12 +36,69 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Operation is what a triple does to the graph. The triples of
+// a subgraph are applied in the order they appear in it.
+type Operation int32
+
+const (
+	// Asserts the triple is true.
+	Operation_ASSERT Operation = 0
+	// Retracts a previously asserted triple. If the object is not set,
+	// every value of the predicate is retracted from the subject.
+	Operation_RETRACT Operation = 1
+	// Deletes the subject and every triple it is the subject of.
+	// The predicate, object and facets must not be set.
+	Operation_DELETE_SUBJECT Operation = 2
+)
+
+// Enum value maps for Operation.
+var (
+	Operation_name = map[int32]string{
+		0: "ASSERT",
+		1: "RETRACT",
+		2: "DELETE_SUBJECT",
+	}
+	Operation_value = map[string]int32{
+		"ASSERT":         0,
+		"RETRACT":        1,
+		"DELETE_SUBJECT": 2,
+	}
+)
+
+func (x Operation) Enum() *Operation {
+	p := new(Operation)
+	*p = x
+	return p
+}
+
+func (x Operation) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Operation) Descriptor() protoreflect.EnumDescriptor {
+	return file_subgraph_subgraph_proto_enumTypes[0].Descriptor()
+}
+
+func (Operation) Type() protoreflect.EnumType {
+	return &file_subgraph_subgraph_proto_enumTypes[0]
+}
+
+func (x Operation) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Operation.Descriptor instead.
+func (Operation) EnumDescriptor() ([]byte, []int) {
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{0}
+}
+
 type Subgraph struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Triples []*Triple `protobuf:"bytes,1,rep,name=triples,proto3" json:"triples,omitempty"`
+	Triples    []*Triple   `protobuf:"bytes,1,rep,name=triples,proto3" json:"triples,omitempty"`
+	Provenance *Provenance `protobuf:"bytes,2,opt,name=provenance,proto3" json:"provenance,omitempty"`
 }
 
 func (x *Subgraph) Reset() {
@@ -82,6 +140,114 @@ func (x *Subgraph) GetTriples() []*Triple {
 	return nil
 }
 
+func (x *Subgraph) GetProvenance() *Provenance {
+	if x != nil {
+		return x.Provenance
+	}
+	return nil
+}
+
+// Provenance describes where a subgraph came from.
+type Provenance struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Identifies the producer which extracted the subgraph.
+	ProducerId string `protobuf:"bytes,1,opt,name=producer_id,json=producerId,proto3" json:"producer_id,omitempty"`
+	// Identifies the pipeline run which extracted the subgraph.
+	RunId string `protobuf:"bytes,2,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	// Document, or other resource, the subgraph was extracted from.
+	SourceUri   string                 `protobuf:"bytes,3,opt,name=source_uri,json=sourceUri,proto3" json:"source_uri,omitempty"`
+	ExtractedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=extracted_at,json=extractedAt,proto3" json:"extracted_at,omitempty"`
+	Labels      map[string]string      `protobuf:"bytes,5,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Set by the ingest service when the subgraph is received.
+	ReceivedAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"`
+	// Set by the ingest service to the authenticated caller which sent the subgraph.
+	Caller string `protobuf:"bytes,7,opt,name=caller,proto3" json:"caller,omitempty"`
+}
+
+func (x *Provenance) Reset() {
+	*x = Provenance{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_subgraph_subgraph_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Provenance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Provenance) ProtoMessage() {}
+
+func (x *Provenance) ProtoReflect() protoreflect.Message {
+	mi := &file_subgraph_subgraph_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Provenance.ProtoReflect.Descriptor instead.
+func (*Provenance) Descriptor() ([]byte, []int) {
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Provenance) GetProducerId() string {
+	if x != nil {
+		return x.ProducerId
+	}
+	return ""
+}
+
+func (x *Provenance) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *Provenance) GetSourceUri() string {
+	if x != nil {
+		return x.SourceUri
+	}
+	return ""
+}
+
+func (x *Provenance) GetExtractedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExtractedAt
+	}
+	return nil
+}
+
+func (x *Provenance) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Provenance) GetReceivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return nil
+}
+
+func (x *Provenance) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
 type Triple struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -90,12 +256,25 @@ type Triple struct {
 	Subject   *Subject   `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
 	Predicate *Predicate `protobuf:"bytes,2,opt,name=predicate,proto3" json:"predicate,omitempty"`
 	Object    *Object    `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`
+	// Facets are properties of the edge itself, e.g. confidence, source or valid_from.
+	Facets    map[string]*FacetValue `protobuf:"bytes,4,rep,name=facets,proto3" json:"facets,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Operation Operation              `protobuf:"varint,5,opt,name=operation,proto3,enum=subgraph.Operation" json:"operation,omitempty"`
+	// Orders the triple against every other operation on the same subject
+	// and predicate when merging. Assigned by the ingest service if the
+	// producer did not set one.
+	Hlc *HybridTimestamp `protobuf:"bytes,6,opt,name=hlc,proto3" json:"hlc,omitempty"`
+	// Timestamps of the assertions a retraction observed, e.g. those read from
+	// the graph before retracting. For predicates declared with a cardinality
+	// of many, a retraction which lists them removes only those assertions, so
+	// an assertion it did not observe survives it however it is timestamped.
+	// Must only be set on retractions.
+	Observed []*HybridTimestamp `protobuf:"bytes,7,rep,name=observed,proto3" json:"observed,omitempty"`
 }
 
 func (x *Triple) Reset() {
 	*x = Triple{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_subgraph_subgraph_proto_msgTypes[1]
+		mi := &file_subgraph_subgraph_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -108,7 +287,7 @@ func (x *Triple) String() string {
 func (*Triple) ProtoMessage() {}
 
 func (x *Triple) ProtoReflect() protoreflect.Message {
-	mi := &file_subgraph_subgraph_proto_msgTypes[1]
+	mi := &file_subgraph_subgraph_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -121,7 +300,7 @@ func (x *Triple) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Triple.ProtoReflect.Descriptor instead.
 func (*Triple) Descriptor() ([]byte, []int) {
-	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{1}
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *Triple) GetSubject() *Subject {
@@ -145,6 +324,101 @@ func (x *Triple) GetObject() *Object {
 	return nil
 }
 
+func (x *Triple) GetFacets() map[string]*FacetValue {
+	if x != nil {
+		return x.Facets
+	}
+	return nil
+}
+
+func (x *Triple) GetOperation() Operation {
+	if x != nil {
+		return x.Operation
+	}
+	return Operation_ASSERT
+}
+
+func (x *Triple) GetHlc() *HybridTimestamp {
+	if x != nil {
+		return x.Hlc
+	}
+	return nil
+}
+
+func (x *Triple) GetObserved() []*HybridTimestamp {
+	if x != nil {
+		return x.Observed
+	}
+	return nil
+}
+
+// HybridTimestamp is a hybrid logical clock timestamp. Timestamps are
+// ordered by wall time, then by logical counter and finally by node ID.
+type HybridTimestamp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Nanoseconds since the Unix epoch.
+	WallTime int64  `protobuf:"varint,1,opt,name=wall_time,json=wallTime,proto3" json:"wall_time,omitempty"`
+	Logical  uint32 `protobuf:"varint,2,opt,name=logical,proto3" json:"logical,omitempty"`
+	// Identifies the node which issued the timestamp.
+	NodeId string `protobuf:"bytes,3,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+}
+
+func (x *HybridTimestamp) Reset() {
+	*x = HybridTimestamp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_subgraph_subgraph_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HybridTimestamp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HybridTimestamp) ProtoMessage() {}
+
+func (x *HybridTimestamp) ProtoReflect() protoreflect.Message {
+	mi := &file_subgraph_subgraph_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HybridTimestamp.ProtoReflect.Descriptor instead.
+func (*HybridTimestamp) Descriptor() ([]byte, []int) {
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HybridTimestamp) GetWallTime() int64 {
+	if x != nil {
+		return x.WallTime
+	}
+	return 0
+}
+
+func (x *HybridTimestamp) GetLogical() uint32 {
+	if x != nil {
+		return x.Logical
+	}
+	return 0
+}
+
+func (x *HybridTimestamp) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
 type Subject struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -157,7 +431,7 @@ type Subject struct {
 func (x *Subject) Reset() {
 	*x = Subject{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_subgraph_subgraph_proto_msgTypes[2]
+		mi := &file_subgraph_subgraph_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -170,7 +444,7 @@ func (x *Subject) String() string {
 func (*Subject) ProtoMessage() {}
 
 func (x *Subject) ProtoReflect() protoreflect.Message {
-	mi := &file_subgraph_subgraph_proto_msgTypes[2]
+	mi := &file_subgraph_subgraph_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -183,7 +457,7 @@ func (x *Subject) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Subject.ProtoReflect.Descriptor instead.
 func (*Subject) Descriptor() ([]byte, []int) {
-	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{2}
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Subject) GetType() string {
@@ -211,7 +485,7 @@ type Predicate struct {
 func (x *Predicate) Reset() {
 	*x = Predicate{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_subgraph_subgraph_proto_msgTypes[3]
+		mi := &file_subgraph_subgraph_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -224,7 +498,7 @@ func (x *Predicate) String() string {
 func (*Predicate) ProtoMessage() {}
 
 func (x *Predicate) ProtoReflect() protoreflect.Message {
-	mi := &file_subgraph_subgraph_proto_msgTypes[3]
+	mi := &file_subgraph_subgraph_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -237,7 +511,7 @@ func (x *Predicate) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Predicate.ProtoReflect.Descriptor instead.
 func (*Predicate) Descriptor() ([]byte, []int) {
-	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{3}
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Predicate) GetName() string {
@@ -257,13 +531,18 @@ type Object struct {
 	//	*Object_String_
 	//	*Object_Int64
 	//	*Object_Float64
+	//	*Object_Bool
+	//	*Object_Timestamp
+	//	*Object_Bytes
+	//	*Object_Geo
+	//	*Object_LangString
 	Value isObject_Value `protobuf_oneof:"value"`
 }
 
 func (x *Object) Reset() {
 	*x = Object{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_subgraph_subgraph_proto_msgTypes[4]
+		mi := &file_subgraph_subgraph_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -276,7 +555,7 @@ func (x *Object) String() string {
 func (*Object) ProtoMessage() {}
 
 func (x *Object) ProtoReflect() protoreflect.Message {
-	mi := &file_subgraph_subgraph_proto_msgTypes[4]
+	mi := &file_subgraph_subgraph_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -289,7 +568,7 @@ func (x *Object) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Object.ProtoReflect.Descriptor instead.
 func (*Object) Descriptor() ([]byte, []int) {
-	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{4}
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{6}
 }
 
 func (m *Object) GetValue() isObject_Value {
@@ -327,6 +606,41 @@ func (x *Object) GetFloat64() float64 {
 	return 0
 }
 
+func (x *Object) GetBool() bool {
+	if x, ok := x.GetValue().(*Object_Bool); ok {
+		return x.Bool
+	}
+	return false
+}
+
+func (x *Object) GetTimestamp() *timestamppb.Timestamp {
+	if x, ok := x.GetValue().(*Object_Timestamp); ok {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Object) GetBytes() []byte {
+	if x, ok := x.GetValue().(*Object_Bytes); ok {
+		return x.Bytes
+	}
+	return nil
+}
+
+func (x *Object) GetGeo() *GeoPoint {
+	if x, ok := x.GetValue().(*Object_Geo); ok {
+		return x.Geo
+	}
+	return nil
+}
+
+func (x *Object) GetLangString() *LangString {
+	if x, ok := x.GetValue().(*Object_LangString); ok {
+		return x.LangString
+	}
+	return nil
+}
+
 type isObject_Value interface {
 	isObject_Value()
 }
@@ -347,6 +661,26 @@ type Object_Float64 struct {
 	Float64 float64 `protobuf:"fixed64,4,opt,name=float64,proto3,oneof"`
 }
 
+type Object_Bool struct {
+	Bool bool `protobuf:"varint,5,opt,name=bool,proto3,oneof"`
+}
+
+type Object_Timestamp struct {
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=timestamp,proto3,oneof"`
+}
+
+type Object_Bytes struct {
+	Bytes []byte `protobuf:"bytes,7,opt,name=bytes,proto3,oneof"`
+}
+
+type Object_Geo struct {
+	Geo *GeoPoint `protobuf:"bytes,8,opt,name=geo,proto3,oneof"`
+}
+
+type Object_LangString struct {
+	LangString *LangString `protobuf:"bytes,9,opt,name=lang_string,json=langString,proto3,oneof"`
+}
+
 func (*Object_Subject) isObject_Value() {}
 
 func (*Object_String_) isObject_Value() {}
@@ -355,42 +689,375 @@ func (*Object_Int64) isObject_Value() {}
 
 func (*Object_Float64) isObject_Value() {}
 
+func (*Object_Bool) isObject_Value() {}
+
+func (*Object_Timestamp) isObject_Value() {}
+
+func (*Object_Bytes) isObject_Value() {}
+
+func (*Object_Geo) isObject_Value() {}
+
+func (*Object_LangString) isObject_Value() {}
+
+// GeoPoint is a WGS84 coordinate in degrees.
+type GeoPoint struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Latitude  float64 `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude float64 `protobuf:"fixed64,2,opt,name=longitude,proto3" json:"longitude,omitempty"`
+}
+
+func (x *GeoPoint) Reset() {
+	*x = GeoPoint{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_subgraph_subgraph_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GeoPoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeoPoint) ProtoMessage() {}
+
+func (x *GeoPoint) ProtoReflect() protoreflect.Message {
+	mi := &file_subgraph_subgraph_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeoPoint.ProtoReflect.Descriptor instead.
+func (*GeoPoint) Descriptor() ([]byte, []int) {
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GeoPoint) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *GeoPoint) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+// LangString is a string with the language it is written in.
+type LangString struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Value string `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// BCP 47 language tag, e.g. en or en-US.
+	Lang string `protobuf:"bytes,2,opt,name=lang,proto3" json:"lang,omitempty"`
+}
+
+func (x *LangString) Reset() {
+	*x = LangString{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_subgraph_subgraph_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LangString) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LangString) ProtoMessage() {}
+
+func (x *LangString) ProtoReflect() protoreflect.Message {
+	mi := &file_subgraph_subgraph_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LangString.ProtoReflect.Descriptor instead.
+func (*LangString) Descriptor() ([]byte, []int) {
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *LangString) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *LangString) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+type FacetValue struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Value:
+	//	*FacetValue_String_
+	//	*FacetValue_Int64
+	//	*FacetValue_Float64
+	//	*FacetValue_Bool
+	//	*FacetValue_Timestamp
+	Value isFacetValue_Value `protobuf_oneof:"value"`
+}
+
+func (x *FacetValue) Reset() {
+	*x = FacetValue{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_subgraph_subgraph_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FacetValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FacetValue) ProtoMessage() {}
+
+func (x *FacetValue) ProtoReflect() protoreflect.Message {
+	mi := &file_subgraph_subgraph_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FacetValue.ProtoReflect.Descriptor instead.
+func (*FacetValue) Descriptor() ([]byte, []int) {
+	return file_subgraph_subgraph_proto_rawDescGZIP(), []int{9}
+}
+
+func (m *FacetValue) GetValue() isFacetValue_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *FacetValue) GetString_() string {
+	if x, ok := x.GetValue().(*FacetValue_String_); ok {
+		return x.String_
+	}
+	return ""
+}
+
+func (x *FacetValue) GetInt64() int64 {
+	if x, ok := x.GetValue().(*FacetValue_Int64); ok {
+		return x.Int64
+	}
+	return 0
+}
+
+func (x *FacetValue) GetFloat64() float64 {
+	if x, ok := x.GetValue().(*FacetValue_Float64); ok {
+		return x.Float64
+	}
+	return 0
+}
+
+func (x *FacetValue) GetBool() bool {
+	if x, ok := x.GetValue().(*FacetValue_Bool); ok {
+		return x.Bool
+	}
+	return false
+}
+
+func (x *FacetValue) GetTimestamp() *timestamppb.Timestamp {
+	if x, ok := x.GetValue().(*FacetValue_Timestamp); ok {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type isFacetValue_Value interface {
+	isFacetValue_Value()
+}
+
+type FacetValue_String_ struct {
+	String_ string `protobuf:"bytes,1,opt,name=string,proto3,oneof"`
+}
+
+type FacetValue_Int64 struct {
+	Int64 int64 `protobuf:"varint,2,opt,name=int64,proto3,oneof"`
+}
+
+type FacetValue_Float64 struct {
+	Float64 float64 `protobuf:"fixed64,3,opt,name=float64,proto3,oneof"`
+}
+
+type FacetValue_Bool struct {
+	Bool bool `protobuf:"varint,4,opt,name=bool,proto3,oneof"`
+}
+
+type FacetValue_Timestamp struct {
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3,oneof"`
+}
+
+func (*FacetValue_String_) isFacetValue_Value() {}
+
+func (*FacetValue_Int64) isFacetValue_Value() {}
+
+func (*FacetValue_Float64) isFacetValue_Value() {}
+
+func (*FacetValue_Bool) isFacetValue_Value() {}
+
+func (*FacetValue_Timestamp) isFacetValue_Value() {}
+
 var File_subgraph_subgraph_proto protoreflect.FileDescriptor
 
 var file_subgraph_subgraph_proto_rawDesc = []byte{
 	0x0a, 0x17, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2f, 0x73, 0x75, 0x62, 0x67, 0x72,
 	0x61, 0x70, 0x68, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x73, 0x75, 0x62, 0x67, 0x72,
-	0x61, 0x70, 0x68, 0x22, 0x36, 0x0a, 0x08, 0x53, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x12,
-	0x2a, 0x0a, 0x07, 0x74, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
-	0x32, 0x10, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x54, 0x72, 0x69, 0x70,
-	0x6c, 0x65, 0x52, 0x07, 0x74, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x22, 0x92, 0x01, 0x0a, 0x06,
-	0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x12, 0x2b, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63,
+	0x61, 0x70, 0x68, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x6c, 0x0a, 0x08, 0x53, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68,
+	0x12, 0x2a, 0x0a, 0x07, 0x74, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x54, 0x72, 0x69,
+	0x70, 0x6c, 0x65, 0x52, 0x07, 0x74, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x73, 0x12, 0x34, 0x0a, 0x0a,
+	0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x14, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x50, 0x72, 0x6f, 0x76,
+	0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x0a, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e,
+	0x63, 0x65, 0x22, 0xec, 0x02, 0x0a, 0x0a, 0x50, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63,
+	0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x65, 0x72, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x65, 0x72,
+	0x49, 0x64, 0x12, 0x15, 0x0a, 0x06, 0x72, 0x75, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x72, 0x75, 0x6e, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x5f, 0x75, 0x72, 0x69, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x55, 0x72, 0x69, 0x12, 0x3d, 0x0a, 0x0c, 0x65, 0x78, 0x74, 0x72,
+	0x61, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0b, 0x65, 0x78, 0x74, 0x72,
+	0x61, 0x63, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x38, 0x0a, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c,
+	0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61,
+	0x70, 0x68, 0x2e, 0x50, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x2e, 0x4c, 0x61,
+	0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c,
+	0x73, 0x12, 0x3b, 0x0a, 0x0b, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x5f, 0x61, 0x74,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x0a, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x41, 0x74, 0x12, 0x16,
+	0x0a, 0x06, 0x63, 0x61, 0x6c, 0x6c, 0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x63, 0x61, 0x6c, 0x6c, 0x65, 0x72, 0x1a, 0x39, 0x0a, 0x0b, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x22, 0xb0, 0x03, 0x0a, 0x06, 0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x12, 0x2b, 0x0a, 0x07,
+	0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e,
+	0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74,
+	0x52, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x31, 0x0a, 0x09, 0x70, 0x72, 0x65,
+	0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x73,
+	0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74,
+	0x65, 0x52, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x28, 0x0a, 0x06,
+	0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73,
+	0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x06,
+	0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x34, 0x0a, 0x06, 0x66, 0x61, 0x63, 0x65, 0x74, 0x73,
+	0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70,
+	0x68, 0x2e, 0x54, 0x72, 0x69, 0x70, 0x6c, 0x65, 0x2e, 0x46, 0x61, 0x63, 0x65, 0x74, 0x73, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x66, 0x61, 0x63, 0x65, 0x74, 0x73, 0x12, 0x31, 0x0a, 0x09,
+	0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x13, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x4f, 0x70, 0x65, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x2b, 0x0a, 0x03, 0x68, 0x6c, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x73,
+	0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x48, 0x79, 0x62, 0x72, 0x69, 0x64, 0x54, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x03, 0x68, 0x6c, 0x63, 0x12, 0x35, 0x0a, 0x08,
+	0x6f, 0x62, 0x73, 0x65, 0x72, 0x76, 0x65, 0x64, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19,
+	0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x48, 0x79, 0x62, 0x72, 0x69, 0x64,
+	0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x08, 0x6f, 0x62, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x64, 0x1a, 0x4f, 0x0a, 0x0b, 0x46, 0x61, 0x63, 0x65, 0x74, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x6b, 0x65, 0x79, 0x12, 0x2a, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x46,
+	0x61, 0x63, 0x65, 0x74, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x22, 0x61, 0x0a, 0x0f, 0x48, 0x79, 0x62, 0x72, 0x69, 0x64, 0x54, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1b, 0x0a, 0x09, 0x77, 0x61, 0x6c, 0x6c, 0x5f,
+	0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x77, 0x61, 0x6c, 0x6c,
+	0x54, 0x69, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6c, 0x6f, 0x67, 0x69, 0x63, 0x61, 0x6c, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x6c, 0x6f, 0x67, 0x69, 0x63, 0x61, 0x6c, 0x12, 0x17,
+	0x0a, 0x07, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x22, 0x31, 0x0a, 0x07, 0x53, 0x75, 0x62, 0x6a, 0x65,
+	0x63, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x75, 0x69, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x75, 0x69, 0x64, 0x22, 0x1f, 0x0a, 0x09, 0x50, 0x72,
+	0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0xd9, 0x02, 0x0a, 0x06,
+	0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x2d, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63,
 	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61,
-	0x70, 0x68, 0x2e, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x07, 0x73, 0x75, 0x62, 0x6a,
-	0x65, 0x63, 0x74, 0x12, 0x31, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70,
-	0x68, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x52, 0x09, 0x70, 0x72, 0x65,
-	0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x28, 0x0a, 0x06, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70,
-	0x68, 0x2e, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x06, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74,
-	0x22, 0x31, 0x0a, 0x07, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74,
-	0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12,
-	0x12, 0x0a, 0x04, 0x74, 0x75, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74,
-	0x75, 0x69, 0x64, 0x22, 0x1f, 0x0a, 0x09, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65,
-	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
-	0x6e, 0x61, 0x6d, 0x65, 0x22, 0x8e, 0x01, 0x0a, 0x06, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12,
-	0x2d, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x11, 0x2e, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x53, 0x75, 0x62, 0x6a,
-	0x65, 0x63, 0x74, 0x48, 0x00, 0x52, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x18,
-	0x0a, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00,
-	0x52, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12, 0x16, 0x0a, 0x05, 0x69, 0x6e, 0x74, 0x36,
-	0x34, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x05, 0x69, 0x6e, 0x74, 0x36, 0x34,
-	0x12, 0x1a, 0x0a, 0x07, 0x66, 0x6c, 0x6f, 0x61, 0x74, 0x36, 0x34, 0x18, 0x04, 0x20, 0x01, 0x28,
-	0x01, 0x48, 0x00, 0x52, 0x07, 0x66, 0x6c, 0x6f, 0x61, 0x74, 0x36, 0x34, 0x42, 0x07, 0x0a, 0x05,
-	0x76, 0x61, 0x6c, 0x75, 0x65, 0x42, 0x25, 0x5a, 0x23, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
-	0x63, 0x6f, 0x6d, 0x2f, 0x7a, 0x35, 0x6c, 0x61, 0x62, 0x73, 0x2f, 0x6d, 0x65, 0x67, 0x61, 0x6d,
-	0x69, 0x6e, 0x64, 0x2f, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x70, 0x68, 0x2e, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x48, 0x00, 0x52, 0x07, 0x73, 0x75,
+	0x62, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x18, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12,
+	0x16, 0x0a, 0x05, 0x69, 0x6e, 0x74, 0x36, 0x34, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00,
+	0x52, 0x05, 0x69, 0x6e, 0x74, 0x36, 0x34, 0x12, 0x1a, 0x0a, 0x07, 0x66, 0x6c, 0x6f, 0x61, 0x74,
+	0x36, 0x34, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x07, 0x66, 0x6c, 0x6f, 0x61,
+	0x74, 0x36, 0x34, 0x12, 0x14, 0x0a, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x08, 0x48, 0x00, 0x52, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x12, 0x3a, 0x0a, 0x09, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54,
+	0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x48, 0x00, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x16, 0x0a, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x12, 0x26, 0x0a,
+	0x03, 0x67, 0x65, 0x6f, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x75, 0x62,
+	0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x47, 0x65, 0x6f, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x48, 0x00,
+	0x52, 0x03, 0x67, 0x65, 0x6f, 0x12, 0x37, 0x0a, 0x0b, 0x6c, 0x61, 0x6e, 0x67, 0x5f, 0x73, 0x74,
+	0x72, 0x69, 0x6e, 0x67, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x73, 0x75, 0x62,
+	0x67, 0x72, 0x61, 0x70, 0x68, 0x2e, 0x4c, 0x61, 0x6e, 0x67, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67,
+	0x48, 0x00, 0x52, 0x0a, 0x6c, 0x61, 0x6e, 0x67, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x42, 0x07,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x44, 0x0a, 0x08, 0x47, 0x65, 0x6f, 0x50, 0x6f,
+	0x69, 0x6e, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x6c, 0x61, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12,
+	0x1c, 0x0a, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x69, 0x74, 0x75, 0x64, 0x65, 0x22, 0x36, 0x0a,
+	0x0a, 0x4c, 0x61, 0x6e, 0x67, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x61, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6c, 0x61, 0x6e, 0x67, 0x22, 0xb5, 0x01, 0x0a, 0x0a, 0x46, 0x61, 0x63, 0x65, 0x74, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x12, 0x18, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12, 0x16,
+	0x0a, 0x05, 0x69, 0x6e, 0x74, 0x36, 0x34, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52,
+	0x05, 0x69, 0x6e, 0x74, 0x36, 0x34, 0x12, 0x1a, 0x0a, 0x07, 0x66, 0x6c, 0x6f, 0x61, 0x74, 0x36,
+	0x34, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x07, 0x66, 0x6c, 0x6f, 0x61, 0x74,
+	0x36, 0x34, 0x12, 0x14, 0x0a, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08,
+	0x48, 0x00, 0x52, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x12, 0x3a, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x48, 0x00, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x42, 0x07, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x2a, 0x38, 0x0a,
+	0x09, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0a, 0x0a, 0x06, 0x41, 0x53,
+	0x53, 0x45, 0x52, 0x54, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x52, 0x45, 0x54, 0x52, 0x41, 0x43,
+	0x54, 0x10, 0x01, 0x12, 0x12, 0x0a, 0x0e, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x5f, 0x53, 0x55,
+	0x42, 0x4a, 0x45, 0x43, 0x54, 0x10, 0x02, 0x42, 0x25, 0x5a, 0x23, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x7a, 0x35, 0x6c, 0x61, 0x62, 0x73, 0x2f, 0x6d, 0x65, 0x67,
+	0x61, 0x6d, 0x69, 0x6e, 0x64, 0x2f, 0x73, 0x75, 0x62, 0x67, 0x72, 0x61, 0x70, 0x68, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -405,25 +1072,48 @@ func file_subgraph_subgraph_proto_rawDescGZIP() []byte {
 	return file_subgraph_subgraph_proto_rawDescData
 }
 
-var file_subgraph_subgraph_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_subgraph_subgraph_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_subgraph_subgraph_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_subgraph_subgraph_proto_goTypes = []interface{}{
-	(*Subgraph)(nil),  // 0: subgraph.Subgraph
-	(*Triple)(nil),    // 1: subgraph.Triple
-	(*Subject)(nil),   // 2: subgraph.Subject
-	(*Predicate)(nil), // 3: subgraph.Predicate
-	(*Object)(nil),    // 4: subgraph.Object
+	(Operation)(0),                // 0: subgraph.Operation
+	(*Subgraph)(nil),              // 1: subgraph.Subgraph
+	(*Provenance)(nil),            // 2: subgraph.Provenance
+	(*Triple)(nil),                // 3: subgraph.Triple
+	(*HybridTimestamp)(nil),       // 4: subgraph.HybridTimestamp
+	(*Subject)(nil),               // 5: subgraph.Subject
+	(*Predicate)(nil),             // 6: subgraph.Predicate
+	(*Object)(nil),                // 7: subgraph.Object
+	(*GeoPoint)(nil),              // 8: subgraph.GeoPoint
+	(*LangString)(nil),            // 9: subgraph.LangString
+	(*FacetValue)(nil),            // 10: subgraph.FacetValue
+	nil,                           // 11: subgraph.Provenance.LabelsEntry
+	nil,                           // 12: subgraph.Triple.FacetsEntry
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
 }
 var file_subgraph_subgraph_proto_depIdxs = []int32{
-	1, // 0: subgraph.Subgraph.triples:type_name -> subgraph.Triple
-	2, // 1: subgraph.Triple.subject:type_name -> subgraph.Subject
-	3, // 2: subgraph.Triple.predicate:type_name -> subgraph.Predicate
-	4, // 3: subgraph.Triple.object:type_name -> subgraph.Object
-	2, // 4: subgraph.Object.subject:type_name -> subgraph.Subject
-	5, // [5:5] is the sub-list for method output_type
-	5, // [5:5] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	3,  // 0: subgraph.Subgraph.triples:type_name -> subgraph.Triple
+	2,  // 1: subgraph.Subgraph.provenance:type_name -> subgraph.Provenance
+	13, // 2: subgraph.Provenance.extracted_at:type_name -> google.protobuf.Timestamp
+	11, // 3: subgraph.Provenance.labels:type_name -> subgraph.Provenance.LabelsEntry
+	13, // 4: subgraph.Provenance.received_at:type_name -> google.protobuf.Timestamp
+	5,  // 5: subgraph.Triple.subject:type_name -> subgraph.Subject
+	6,  // 6: subgraph.Triple.predicate:type_name -> subgraph.Predicate
+	7,  // 7: subgraph.Triple.object:type_name -> subgraph.Object
+	12, // 8: subgraph.Triple.facets:type_name -> subgraph.Triple.FacetsEntry
+	0,  // 9: subgraph.Triple.operation:type_name -> subgraph.Operation
+	4,  // 10: subgraph.Triple.hlc:type_name -> subgraph.HybridTimestamp
+	4,  // 11: subgraph.Triple.observed:type_name -> subgraph.HybridTimestamp
+	5,  // 12: subgraph.Object.subject:type_name -> subgraph.Subject
+	13, // 13: subgraph.Object.timestamp:type_name -> google.protobuf.Timestamp
+	8,  // 14: subgraph.Object.geo:type_name -> subgraph.GeoPoint
+	9,  // 15: subgraph.Object.lang_string:type_name -> subgraph.LangString
+	13, // 16: subgraph.FacetValue.timestamp:type_name -> google.protobuf.Timestamp
+	10, // 17: subgraph.Triple.FacetsEntry.value:type_name -> subgraph.FacetValue
+	18, // [18:18] is the sub-list for method output_type
+	18, // [18:18] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
 }
 
 func init() { file_subgraph_subgraph_proto_init() }
@@ -445,7 +1135,7 @@ func file_subgraph_subgraph_proto_init() {
 			}
 		}
 		file_subgraph_subgraph_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Triple); i {
+			switch v := v.(*Provenance); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -457,7 +1147,7 @@ func file_subgraph_subgraph_proto_init() {
 			}
 		}
 		file_subgraph_subgraph_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Subject); i {
+			switch v := v.(*Triple); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -469,7 +1159,7 @@ func file_subgraph_subgraph_proto_init() {
 			}
 		}
 		file_subgraph_subgraph_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Predicate); i {
+			switch v := v.(*HybridTimestamp); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -481,6 +1171,30 @@ func file_subgraph_subgraph_proto_init() {
 			}
 		}
 		file_subgraph_subgraph_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Subject); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_subgraph_subgraph_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Predicate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_subgraph_subgraph_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Object); i {
 			case 0:
 				return &v.state
@@ -492,25 +1206,74 @@ func file_subgraph_subgraph_proto_init() {
 				return nil
 			}
 		}
+		file_subgraph_subgraph_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GeoPoint); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_subgraph_subgraph_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LangString); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_subgraph_subgraph_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FacetValue); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
-	file_subgraph_subgraph_proto_msgTypes[4].OneofWrappers = []interface{}{
+	file_subgraph_subgraph_proto_msgTypes[6].OneofWrappers = []interface{}{
 		(*Object_Subject)(nil),
 		(*Object_String_)(nil),
 		(*Object_Int64)(nil),
 		(*Object_Float64)(nil),
+		(*Object_Bool)(nil),
+		(*Object_Timestamp)(nil),
+		(*Object_Bytes)(nil),
+		(*Object_Geo)(nil),
+		(*Object_LangString)(nil),
+	}
+	file_subgraph_subgraph_proto_msgTypes[9].OneofWrappers = []interface{}{
+		(*FacetValue_String_)(nil),
+		(*FacetValue_Int64)(nil),
+		(*FacetValue_Float64)(nil),
+		(*FacetValue_Bool)(nil),
+		(*FacetValue_Timestamp)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_subgraph_subgraph_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   5,
+			NumEnums:      1,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_subgraph_subgraph_proto_goTypes,
 		DependencyIndexes: file_subgraph_subgraph_proto_depIdxs,
+		EnumInfos:         file_subgraph_subgraph_proto_enumTypes,
 		MessageInfos:      file_subgraph_subgraph_proto_msgTypes,
 	}.Build()
 	File_subgraph_subgraph_proto = out.File
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tabular converts the rows of CSV, and other delimited, files into subgraphs.
+//
+// Each row becomes a subgraph about a single subject. A mapping declares
+// which column holds the TUID of the subject and how the other columns
+// become predicates. It is written in YAML (or JSON) and looks like:
+//
+//	type: Person
+//	tuid: id
+//	columns:
+//	  name:
+//	    kind: string
+//	  age:
+//	    kind: int64
+//	  greeting:
+//	    kind: langstring
+//	    lang: fr
+//	  joined:
+//	    kind: timestamp
+//	    layout: "2006-01-02"
+//	  employer_id:
+//	    predicate: worksAt
+//	    kind: subject
+//	    type: Company
+//
+// Columns which are not mapped are ignored, as are empty cells. A subject
+// column is a foreign key whose cells are the TUIDs of subjects of the
+// given type. Geo cells are written as latitude,longitude and bytes cells
+// are base64 encoded.
+package tabular
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mapping declares how the rows of a file become subgraphs.
+type Mapping struct {
+	// Type of every subject.
+	Type string
+
+	// Tuid is the column holding the TUID of each subject.
+	Tuid string
+
+	// Delimiter separates the cells of a row. Defaults to a comma.
+	Delimiter rune
+
+	// Columns maps column names to predicates.
+	Columns map[string]*Column
+}
+
+// Column declares the predicate a column becomes.
+type Column struct {
+	// Predicate name. Defaults to the column name.
+	Predicate string
+
+	Kind schema.Kind
+
+	// Type of the subjects a KindSubject column references.
+	Type string
+
+	// Lang is the language tag of a KindLangString column.
+	Lang string
+
+	// Layout of a KindTimestamp column, as in time.Parse.
+	// Defaults to RFC 3339.
+	Layout string
+}
+
+type mappingFile struct {
+	Type      string                `yaml:"type"`
+	Tuid      string                `yaml:"tuid"`
+	Delimiter string                `yaml:"delimiter"`
+	Columns   map[string]columnFile `yaml:"columns"`
+}
+
+type columnFile struct {
+	Predicate string `yaml:"predicate"`
+	Kind      string `yaml:"kind"`
+	Type      string `yaml:"type"`
+	Lang      string `yaml:"lang"`
+	Layout    string `yaml:"layout"`
+}
+
+// ParseMapping parses a YAML, or JSON, encoded mapping.
+func ParseMapping(b []byte) (*Mapping, error) {
+	var mf mappingFile
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	err := dec.Decode(&mf)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(mf.Type) == "" {
+		return nil, fmt.Errorf("type must not be empty")
+	}
+	if strings.TrimSpace(mf.Tuid) == "" {
+		return nil, fmt.Errorf("tuid must not be empty")
+	}
+
+	m := &Mapping{
+		Type:      mf.Type,
+		Tuid:      mf.Tuid,
+		Delimiter: ',',
+		Columns:   make(map[string]*Column, len(mf.Columns)),
+	}
+	if mf.Delimiter != "" {
+		r, n := utf8.DecodeRuneInString(mf.Delimiter)
+		if n != len(mf.Delimiter) || r == '"' || r == '\r' || r == '\n' {
+			return nil, fmt.Errorf("delimiter must be a single character other than a quote or newline")
+		}
+		m.Delimiter = r
+	}
+
+	for name, cf := range mf.Columns {
+		c, err := parseColumn(name, cf)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", name, err)
+		}
+		m.Columns[name] = c
+	}
+	return m, nil
+}
+
+func parseColumn(name string, cf columnFile) (*Column, error) {
+	kind, err := schema.ParseKind(cf.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if kind == schema.KindSubject && cf.Type == "" {
+		return nil, fmt.Errorf("subject kind requires a type")
+	}
+	if kind != schema.KindSubject && cf.Type != "" {
+		return nil, fmt.Errorf("only subject kind may declare a type")
+	}
+	if kind == schema.KindLangString && cf.Lang == "" {
+		return nil, fmt.Errorf("langstring kind requires a lang")
+	}
+	if kind != schema.KindLangString && cf.Lang != "" {
+		return nil, fmt.Errorf("only langstring kind may declare a lang")
+	}
+	if kind != schema.KindTimestamp && cf.Layout != "" {
+		return nil, fmt.Errorf("only timestamp kind may declare a layout")
+	}
+
+	c := &Column{
+		Predicate: cf.Predicate,
+		Kind:      kind,
+		Type:      cf.Type,
+		Lang:      cf.Lang,
+		Layout:    cf.Layout,
+	}
+	if c.Predicate == "" {
+		c.Predicate = name
+	}
+	return c, nil
+}
+
+// LoadMapping reads and parses the named mapping file.
+func LoadMapping(filename string) (*Mapping, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMapping(b)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tabular
+
+import (
+	"testing"
+
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMapping(t *testing.T) {
+	t.Run("should parse a mapping", func(subT *testing.T) {
+		m, err := ParseMapping([]byte(`
+type: Person
+tuid: id
+delimiter: ";"
+columns:
+  name:
+    kind: string
+  employer_id:
+    predicate: worksAt
+    kind: subject
+    type: Company
+`))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, ';', m.Delimiter) {
+			return
+		}
+		if !assert.Equal(subT, &Column{Predicate: "name", Kind: schema.KindString}, m.Columns["name"]) {
+			return
+		}
+		if !assert.Equal(subT, &Column{Predicate: "worksAt", Kind: schema.KindSubject, Type: "Company"}, m.Columns["employer_id"]) {
+			return
+		}
+	})
+
+	testCases := map[string]string{
+		"missing type":             "tuid: id",
+		"missing tuid":             "type: Person",
+		"unknown field":            "type: Person\ntuid: id\ncolumn: {}",
+		"unknown kind":             "type: Person\ntuid: id\ncolumns:\n  age:\n    kind: integer",
+		"subject without type":     "type: Person\ntuid: id\ncolumns:\n  boss:\n    kind: subject",
+		"langstring without lang":  "type: Person\ntuid: id\ncolumns:\n  greeting:\n    kind: langstring",
+		"layout on a string":       "type: Person\ntuid: id\ncolumns:\n  name:\n    kind: string\n    layout: '2006'",
+		"multi character delimter": "type: Person\ntuid: id\ndelimiter: '||'",
+	}
+	for name, src := range testCases {
+		t.Run("should fail on "+name, func(subT *testing.T) {
+			_, err := ParseMapping([]byte(src))
+			if !assert.NotNil(subT, err) {
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tabular
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrMissingColumn is returned when a mapped column is not in the header.
+var ErrMissingColumn = errors.New("tabular: mapped column is missing from the header")
+
+// CellError describes a cell which could not be converted to its kind.
+type CellError struct {
+	Line   int
+	Column string
+	Err    error
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("tabular: line %d: column %s: %s", e.Line, e.Column, e.Err)
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+type mappedColumn struct {
+	index int
+	name  string
+	*Column
+}
+
+// Reader converts each row into a subgraph. The first
+// row is expected to be a header naming every column.
+type Reader struct {
+	r       *csv.Reader
+	typ     string
+	tuid    int
+	columns []mappedColumn
+}
+
+// NewReader reads the header from r and checks
+// that every mapped column is present.
+func NewReader(r io.Reader, m *Mapping) (*Reader, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = m.Delimiter
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	indexes := make(map[string]int, len(header))
+	for i, name := range header {
+		indexes[strings.TrimSpace(name)] = i
+	}
+
+	tuid, ok := indexes[m.Tuid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMissingColumn, m.Tuid)
+	}
+
+	// columns are kept in header order so that triples are too
+	var columns []mappedColumn
+	for i, name := range header {
+		c, ok := m.Columns[strings.TrimSpace(name)]
+		if !ok {
+			continue
+		}
+		columns = append(columns, mappedColumn{index: i, name: strings.TrimSpace(name), Column: c})
+	}
+	for name := range m.Columns {
+		if _, ok := indexes[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, name)
+		}
+	}
+
+	return &Reader{
+		r:       cr,
+		typ:     m.Type,
+		tuid:    tuid,
+		columns: columns,
+	}, nil
+}
+
+// Read returns the subgraph of the next row. Rows without any mapped values
+// are skipped. It returns io.EOF once every row has been read.
+func (r *Reader) Read() (*subgraph.Subgraph, error) {
+	for {
+		row, err := r.r.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		g, err := r.subgraph(row)
+		if err != nil {
+			return nil, err
+		}
+		if len(g.Triples) > 0 {
+			return g, nil
+		}
+	}
+}
+
+func (r *Reader) subgraph(row []string) (*subgraph.Subgraph, error) {
+	subj := &subgraph.Subject{Type: r.typ, Tuid: strings.TrimSpace(row[r.tuid])}
+	if subj.Tuid == "" {
+		line, _ := r.r.FieldPos(r.tuid)
+		return nil, &CellError{Line: line, Column: "tuid", Err: errors.New("must not be empty")}
+	}
+
+	g := new(subgraph.Subgraph)
+	for _, c := range r.columns {
+		cell := row[c.index]
+		if strings.TrimSpace(cell) == "" {
+			continue
+		}
+
+		obj, err := c.object(cell)
+		if err != nil {
+			line, _ := r.r.FieldPos(c.index)
+			return nil, &CellError{Line: line, Column: c.name, Err: err}
+		}
+		g.Triples = append(g.Triples, &subgraph.Triple{
+			Subject:   subj,
+			Predicate: &subgraph.Predicate{Name: c.Predicate},
+			Object:    obj,
+		})
+	}
+	return g, nil
+}
+
+func (c *Column) object(cell string) (*subgraph.Object, error) {
+	trimmed := strings.TrimSpace(cell)
+
+	switch c.Kind {
+	case schema.KindSubject:
+		return &subgraph.Object{Value: &subgraph.Object_Subject{Subject: &subgraph.Subject{Type: c.Type, Tuid: trimmed}}}, nil
+	case schema.KindString:
+		return &subgraph.Object{Value: &subgraph.Object_String_{String_: cell}}, nil
+	case schema.KindLangString:
+		return &subgraph.Object{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: cell, Lang: c.Lang}}}, nil
+	case schema.KindInt64:
+		i, err := strconv.ParseInt(trimmed, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &subgraph.Object{Value: &subgraph.Object_Int64{Int64: i}}, nil
+	case schema.KindFloat64:
+		f, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &subgraph.Object{Value: &subgraph.Object_Float64{Float64: f}}, nil
+	case schema.KindBool:
+		b, err := strconv.ParseBool(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return &subgraph.Object{Value: &subgraph.Object_Bool{Bool: b}}, nil
+	case schema.KindTimestamp:
+		layout := c.Layout
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		t, err := time.Parse(layout, trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return &subgraph.Object{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.New(t)}}, nil
+	case schema.KindBytes:
+		b, err := base64.StdEncoding.DecodeString(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return &subgraph.Object{Value: &subgraph.Object_Bytes{Bytes: b}}, nil
+	case schema.KindGeo:
+		lat, lon, ok := strings.Cut(trimmed, ",")
+		if !ok {
+			return nil, errors.New("geo must be written as latitude,longitude")
+		}
+		p := new(subgraph.GeoPoint)
+		var err error
+		p.Latitude, err = strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		if err != nil {
+			return nil, err
+		}
+		p.Longitude, err = strconv.ParseFloat(strings.TrimSpace(lon), 64)
+		if err != nil {
+			return nil, err
+		}
+		return &subgraph.Object{Value: &subgraph.Object_Geo{Geo: p}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kind: %s", c.Kind)
+	}
+}
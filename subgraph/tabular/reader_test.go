@@ -0,0 +1,176 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tabular
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/validate"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const testMapping = `
+type: Person
+tuid: id
+columns:
+  name:
+    kind: string
+  age:
+    kind: int64
+  height:
+    kind: float64
+  admin:
+    kind: bool
+  joined:
+    kind: timestamp
+    layout: "2006-01-02"
+  avatar:
+    kind: bytes
+  home:
+    kind: geo
+  greeting:
+    kind: langstring
+    lang: fr
+  employer_id:
+    predicate: worksAt
+    kind: subject
+    type: Company
+`
+
+func newTestReader(subT *testing.T, src string) *Reader {
+	m, err := ParseMapping([]byte(testMapping))
+	if err != nil {
+		subT.Fatal(err)
+	}
+	r, err := NewReader(strings.NewReader(src), m)
+	if err != nil {
+		subT.Fatal(err)
+	}
+	return r
+}
+
+func TestReader_Read(t *testing.T) {
+	t.Run("should convert every mapped column", func(subT *testing.T) {
+		r := newTestReader(subT, strings.Join([]string{
+			`id,name,age,height,admin,joined,avatar,home,greeting,employer_id,ignored`,
+			`1,"Smith, Bob",42,1.8,true,2020-01-02,bWVnYW1pbmQ=,"51.5,-0.12",Bonjour,7,x`,
+		}, "\n"))
+
+		g, err := r.Read()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Nil(subT, validate.Subgraph(g)) {
+			return
+		}
+
+		objects := []*subgraph.Object{
+			{Value: &subgraph.Object_String_{String_: "Smith, Bob"}},
+			{Value: &subgraph.Object_Int64{Int64: 42}},
+			{Value: &subgraph.Object_Float64{Float64: 1.8}},
+			{Value: &subgraph.Object_Bool{Bool: true}},
+			{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.New(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))}},
+			{Value: &subgraph.Object_Bytes{Bytes: []byte("megamind")}},
+			{Value: &subgraph.Object_Geo{Geo: &subgraph.GeoPoint{Latitude: 51.5, Longitude: -0.12}}},
+			{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Bonjour", Lang: "fr"}}},
+			{Value: &subgraph.Object_Subject{Subject: &subgraph.Subject{Type: "Company", Tuid: "7"}}},
+		}
+		if !assert.Len(subT, g.Triples, len(objects)) {
+			return
+		}
+		for i, obj := range objects {
+			if !assert.Equal(subT, "1", g.Triples[i].Subject.Tuid) {
+				return
+			}
+			if !assert.True(subT, proto.Equal(obj, g.Triples[i].Object), "expected %v but got %v", obj, g.Triples[i].Object) {
+				return
+			}
+		}
+		if !assert.Equal(subT, "worksAt", g.Triples[len(objects)-1].Predicate.Name) {
+			return
+		}
+
+		_, err = r.Read()
+		if !assert.Equal(subT, io.EOF, err) {
+			return
+		}
+	})
+
+	t.Run("should skip empty cells and rows", func(subT *testing.T) {
+		r := newTestReader(subT, strings.Join([]string{
+			`id,name,age,height,admin,joined,avatar,home,greeting,employer_id`,
+			`1,,,,,,,,,`,
+			`2,Bob,,,,,,,,`,
+		}, "\n"))
+
+		g, err := r.Read()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, g.Triples, 1) {
+			return
+		}
+		if !assert.Equal(subT, "2", g.Triples[0].Subject.Tuid) {
+			return
+		}
+	})
+
+	t.Run("should report the line and column of an invalid cell", func(subT *testing.T) {
+		r := newTestReader(subT, strings.Join([]string{
+			`id,name,age,height,admin,joined,avatar,home,greeting,employer_id`,
+			`1,Bob,42,,,,,,,`,
+			`2,Alice,forty,,,,,,,`,
+		}, "\n"))
+
+		_, err := r.Read()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		_, err = r.Read()
+
+		var cerr *CellError
+		if !assert.True(subT, errors.As(err, &cerr), "unexpected error: %v", err) {
+			return
+		}
+		if !assert.Equal(subT, 3, cerr.Line) {
+			return
+		}
+		if !assert.Equal(subT, "age", cerr.Column) {
+			return
+		}
+	})
+
+	t.Run("should fail if a mapped column is missing from the header", func(subT *testing.T) {
+		m, err := ParseMapping([]byte(testMapping))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		_, err = NewReader(strings.NewReader("id,name\n1,Bob\n"), m)
+		if !assert.ErrorIs(subT, err, ErrMissingColumn) {
+			return
+		}
+	})
+}
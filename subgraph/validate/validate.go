@@ -0,0 +1,281 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package validate checks subgraphs for triples which cannot be ingested.
+package validate
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/z5labs/megamind/subgraph"
+)
+
+// langTag loosely matches BCP 47 language tags, e.g. en, en-US or zh-Hant-TW.
+var langTag = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`)
+
+var facetKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Violation describes a single problem with a triple.
+type Violation struct {
+	// TripleIndex is the index of the offending triple within its subgraph,
+	// or -1 if the violation is not for a triple.
+	TripleIndex int
+
+	// Field is the path to the offending field, e.g. triples[0].subject.tuid
+	Field string
+
+	Description string
+}
+
+func (v Violation) Error() string {
+	return v.Field + ": " + v.Description
+}
+
+// Error is returned when a subgraph has one or more violations.
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Error()
+	}
+	return "invalid subgraph: " + strings.Join(msgs, "; ")
+}
+
+// Subgraph validates every triple in the subgraph. If any violations
+// are found they are all returned together as an *Error.
+func Subgraph(g *subgraph.Subgraph) error {
+	vs := Provenance(g.GetProvenance())
+	for i, t := range g.GetTriples() {
+		vs = append(vs, Triple(i, t)...)
+	}
+	if len(vs) == 0 {
+		return nil
+	}
+	return &Error{Violations: vs}
+}
+
+// Provenance returns every violation for the provenance of a subgraph.
+// A subgraph is not required to have provenance.
+func Provenance(p *subgraph.Provenance) []Violation {
+	v := &validator{index: -1}
+	if p == nil {
+		return nil
+	}
+
+	if p.SourceUri != "" {
+		u, err := url.Parse(p.SourceUri)
+		if err != nil || !u.IsAbs() {
+			v.add("provenance.source_uri", "must be an absolute URI")
+		}
+	}
+	if p.ExtractedAt != nil && p.ExtractedAt.CheckValid() != nil {
+		v.add("provenance.extracted_at", "must be a valid timestamp")
+	}
+	if _, ok := p.Labels[""]; ok {
+		v.add("provenance.labels", "keys must not be empty")
+	}
+	return v.violations
+}
+
+// Triple returns every violation for the i-th triple of a subgraph.
+func Triple(i int, t *subgraph.Triple) []Violation {
+	v := &validator{index: i}
+	path := fmt.Sprintf("triples[%d]", i)
+	if t == nil {
+		v.add(path, "must be set")
+		return v.violations
+	}
+
+	v.subject(path+".subject", t.Subject)
+	if t.Hlc != nil {
+		v.hlc(path+".hlc", t.Hlc)
+	}
+	switch t.Operation {
+	case subgraph.Operation_ASSERT:
+		v.predicate(path+".predicate", t.Predicate)
+		v.object(path+".object", t.Object)
+		v.facets(path+".facets", t.Facets)
+		if len(t.Observed) > 0 {
+			v.add(path+".observed", "must only be set when retracting")
+		}
+	case subgraph.Operation_RETRACT:
+		v.predicate(path+".predicate", t.Predicate)
+		if t.Object != nil {
+			v.object(path+".object", t.Object)
+		}
+		if len(t.Facets) > 0 {
+			v.add(path+".facets", "must not be set when retracting")
+		}
+		for j, ts := range t.Observed {
+			p := fmt.Sprintf("%s.observed[%d]", path, j)
+			if ts == nil {
+				v.add(p, "must be set")
+				continue
+			}
+			v.hlc(p, ts)
+		}
+	case subgraph.Operation_DELETE_SUBJECT:
+		if t.Predicate != nil {
+			v.add(path+".predicate", "must not be set when deleting a subject")
+		}
+		if t.Object != nil {
+			v.add(path+".object", "must not be set when deleting a subject")
+		}
+		if len(t.Facets) > 0 {
+			v.add(path+".facets", "must not be set when deleting a subject")
+		}
+		if len(t.Observed) > 0 {
+			v.add(path+".observed", "must only be set when retracting")
+		}
+	default:
+		v.add(path+".operation", "must be one of ASSERT, RETRACT or DELETE_SUBJECT")
+	}
+	return v.violations
+}
+
+type validator struct {
+	index      int
+	violations []Violation
+}
+
+func (v *validator) add(field, desc string) {
+	v.violations = append(v.violations, Violation{
+		TripleIndex: v.index,
+		Field:       field,
+		Description: desc,
+	})
+}
+
+func (v *validator) subject(path string, s *subgraph.Subject) {
+	if s == nil {
+		v.add(path, "must be set")
+		return
+	}
+	if strings.TrimSpace(s.Type) == "" {
+		v.add(path+".type", "must not be empty")
+	}
+	if strings.TrimSpace(s.Tuid) == "" {
+		v.add(path+".tuid", "must not be empty")
+	}
+}
+
+func (v *validator) predicate(path string, p *subgraph.Predicate) {
+	if p == nil {
+		v.add(path, "must be set")
+		return
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		v.add(path+".name", "must not be empty")
+	}
+}
+
+func (v *validator) object(path string, o *subgraph.Object) {
+	if o == nil {
+		v.add(path, "must be set")
+		return
+	}
+
+	switch x := o.Value.(type) {
+	case nil:
+		v.add(path+".value", "must be set")
+	case *subgraph.Object_Subject:
+		v.subject(path+".subject", x.Subject)
+	case *subgraph.Object_Float64:
+		v.finite(path+".float64", x.Float64)
+	case *subgraph.Object_Timestamp:
+		if x.Timestamp == nil {
+			v.add(path+".timestamp", "must be set")
+			return
+		}
+		if x.Timestamp.CheckValid() != nil {
+			v.add(path+".timestamp", "must be a valid timestamp")
+		}
+	case *subgraph.Object_Geo:
+		v.geo(path+".geo", x.Geo)
+	case *subgraph.Object_LangString:
+		if x.LangString == nil {
+			v.add(path+".lang_string", "must be set")
+			return
+		}
+		if !langTag.MatchString(x.LangString.Lang) {
+			v.add(path+".lang_string.lang", "must be a BCP 47 language tag")
+		}
+	}
+}
+
+func (v *validator) facets(path string, facets map[string]*subgraph.FacetValue) {
+	keys := make([]string, 0, len(facets))
+	for key := range facets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fpath := fmt.Sprintf("%s[%q]", path, key)
+		if !facetKey.MatchString(key) {
+			v.add(fpath, "key must only contain letters, digits and underscores")
+		}
+
+		switch x := facets[key].GetValue().(type) {
+		case nil:
+			v.add(fpath, "must be set")
+		case *subgraph.FacetValue_Float64:
+			v.finite(fpath+".float64", x.Float64)
+		case *subgraph.FacetValue_Timestamp:
+			if x.Timestamp.CheckValid() != nil {
+				v.add(fpath+".timestamp", "must be a valid timestamp")
+			}
+		}
+	}
+}
+
+func (v *validator) hlc(path string, ts *subgraph.HybridTimestamp) {
+	if ts.WallTime <= 0 {
+		v.add(path+".wall_time", "must be after the Unix epoch")
+	}
+	if strings.TrimSpace(ts.NodeId) == "" {
+		v.add(path+".node_id", "must not be empty")
+	}
+}
+
+func (v *validator) finite(path string, f float64) bool {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		v.add(path, "must be a finite number")
+		return false
+	}
+	return true
+}
+
+func (v *validator) geo(path string, p *subgraph.GeoPoint) {
+	if p == nil {
+		v.add(path, "must be set")
+		return
+	}
+	if v.finite(path+".latitude", p.Latitude) && (p.Latitude < -90 || p.Latitude > 90) {
+		v.add(path+".latitude", "must be between -90 and 90")
+	}
+	if v.finite(path+".longitude", p.Longitude) && (p.Longitude < -180 || p.Longitude > 180) {
+		v.add(path+".longitude", "must be between -180 and 180")
+	}
+}
@@ -0,0 +1,305 @@
+/*
+ * Copyright 2022 Z5Labs and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package validate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestTriple() *subgraph.Triple {
+	return &subgraph.Triple{
+		Subject: &subgraph.Subject{
+			Type: "Person",
+			Tuid: "1",
+		},
+		Predicate: &subgraph.Predicate{
+			Name: "name",
+		},
+		Object: &subgraph.Object{
+			Value: &subgraph.Object_String_{
+				String_: "Bob",
+			},
+		},
+	}
+}
+
+func TestSubgraph(t *testing.T) {
+	t.Run("should return nil for a valid subgraph", func(subT *testing.T) {
+		g := &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{newTestTriple()},
+		}
+
+		err := Subgraph(g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+	})
+
+	t.Run("should return nil for every kind of valid object", func(subT *testing.T) {
+		objects := []*subgraph.Object{
+			{Value: &subgraph.Object_Bool{Bool: true}},
+			{Value: &subgraph.Object_Bytes{Bytes: []byte("hello")}},
+			{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.Now()}},
+			{Value: &subgraph.Object_Geo{Geo: &subgraph.GeoPoint{Latitude: 51.5, Longitude: -0.12}}},
+			{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Bonjour", Lang: "fr-CA"}}},
+		}
+
+		g := new(subgraph.Subgraph)
+		for _, object := range objects {
+			t := newTestTriple()
+			t.Object = object
+			g.Triples = append(g.Triples, t)
+		}
+
+		err := Subgraph(g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+	})
+
+	t.Run("should return nil for valid facets", func(subT *testing.T) {
+		triple := newTestTriple()
+		triple.Facets = map[string]*subgraph.FacetValue{
+			"source":     {Value: &subgraph.FacetValue_String_{String_: "census"}},
+			"rank":       {Value: &subgraph.FacetValue_Int64{Int64: 1}},
+			"confidence": {Value: &subgraph.FacetValue_Float64{Float64: 0.9}},
+			"verified":   {Value: &subgraph.FacetValue_Bool{Bool: false}},
+			"valid_from": {Value: &subgraph.FacetValue_Timestamp{Timestamp: timestamppb.Now()}},
+		}
+
+		err := Subgraph(&subgraph.Subgraph{Triples: []*subgraph.Triple{triple}})
+		if !assert.Nil(subT, err) {
+			return
+		}
+	})
+
+	t.Run("should return a violation for every invalid facet", func(subT *testing.T) {
+		triple := newTestTriple()
+		triple.Facets = map[string]*subgraph.FacetValue{
+			"valid-to":   {Value: &subgraph.FacetValue_String_{String_: "2022"}},
+			"confidence": {Value: &subgraph.FacetValue_Float64{Float64: math.Inf(-1)}},
+			"source":     {},
+		}
+
+		err := Subgraph(&subgraph.Subgraph{Triples: []*subgraph.Triple{triple}})
+
+		verr, ok := err.(*Error)
+		if !assert.True(subT, ok) {
+			return
+		}
+		expected := []Violation{
+			{TripleIndex: 0, Field: `triples[0].facets["confidence"].float64`, Description: "must be a finite number"},
+			{TripleIndex: 0, Field: `triples[0].facets["source"]`, Description: "must be set"},
+			{TripleIndex: 0, Field: `triples[0].facets["valid-to"]`, Description: "key must only contain letters, digits and underscores"},
+		}
+		if !assert.Equal(subT, expected, verr.Violations) {
+			return
+		}
+	})
+
+	t.Run("should return nil for valid provenance", func(subT *testing.T) {
+		g := &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{newTestTriple()},
+			Provenance: &subgraph.Provenance{
+				ProducerId:  "crawler",
+				RunId:       "2022-09-01",
+				SourceUri:   "https://example.com/people/1",
+				ExtractedAt: timestamppb.Now(),
+				Labels:      map[string]string{"team": "search"},
+			},
+		}
+
+		err := Subgraph(g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+	})
+
+	t.Run("should return a violation for invalid provenance", func(subT *testing.T) {
+		g := &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{newTestTriple()},
+			Provenance: &subgraph.Provenance{
+				SourceUri:   "people/1",
+				ExtractedAt: &timestamppb.Timestamp{Nanos: -1},
+				Labels:      map[string]string{"": "search"},
+			},
+		}
+
+		err := Subgraph(g)
+
+		verr, ok := err.(*Error)
+		if !assert.True(subT, ok) {
+			return
+		}
+		expected := []Violation{
+			{TripleIndex: -1, Field: "provenance.source_uri", Description: "must be an absolute URI"},
+			{TripleIndex: -1, Field: "provenance.extracted_at", Description: "must be a valid timestamp"},
+			{TripleIndex: -1, Field: "provenance.labels", Description: "keys must not be empty"},
+		}
+		if !assert.Equal(subT, expected, verr.Violations) {
+			return
+		}
+	})
+
+	t.Run("should return nil for valid retractions and deletions", func(subT *testing.T) {
+		retract := newTestTriple()
+		retract.Operation = subgraph.Operation_RETRACT
+
+		retractAll := newTestTriple()
+		retractAll.Operation = subgraph.Operation_RETRACT
+		retractAll.Object = nil
+		retractAll.Observed = []*subgraph.HybridTimestamp{{WallTime: 1, NodeId: "a"}}
+
+		deleteSubject := &subgraph.Triple{
+			Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+			Operation: subgraph.Operation_DELETE_SUBJECT,
+		}
+
+		err := Subgraph(&subgraph.Subgraph{
+			Triples: []*subgraph.Triple{newTestTriple(), retract, retractAll, deleteSubject},
+		})
+		if !assert.Nil(subT, err) {
+			return
+		}
+	})
+
+	t.Run("should return a violation for invalid retractions and deletions", func(subT *testing.T) {
+		retract := newTestTriple()
+		retract.Operation = subgraph.Operation_RETRACT
+		retract.Predicate = nil
+		retract.Facets = map[string]*subgraph.FacetValue{
+			"confidence": {Value: &subgraph.FacetValue_Float64{Float64: 0.5}},
+		}
+		retract.Observed = []*subgraph.HybridTimestamp{nil, {}}
+
+		deleteSubject := newTestTriple()
+		deleteSubject.Operation = subgraph.Operation_DELETE_SUBJECT
+		deleteSubject.Observed = []*subgraph.HybridTimestamp{{WallTime: 1, NodeId: "a"}}
+
+		unknown := newTestTriple()
+		unknown.Operation = subgraph.Operation(42)
+
+		err := Subgraph(&subgraph.Subgraph{
+			Triples: []*subgraph.Triple{retract, deleteSubject, unknown},
+		})
+
+		verr, ok := err.(*Error)
+		if !assert.True(subT, ok) {
+			return
+		}
+		expected := []Violation{
+			{TripleIndex: 0, Field: "triples[0].predicate", Description: "must be set"},
+			{TripleIndex: 0, Field: "triples[0].facets", Description: "must not be set when retracting"},
+			{TripleIndex: 0, Field: "triples[0].observed[0]", Description: "must be set"},
+			{TripleIndex: 0, Field: "triples[0].observed[1].wall_time", Description: "must be after the Unix epoch"},
+			{TripleIndex: 0, Field: "triples[0].observed[1].node_id", Description: "must not be empty"},
+			{TripleIndex: 1, Field: "triples[1].predicate", Description: "must not be set when deleting a subject"},
+			{TripleIndex: 1, Field: "triples[1].object", Description: "must not be set when deleting a subject"},
+			{TripleIndex: 1, Field: "triples[1].observed", Description: "must only be set when retracting"},
+			{TripleIndex: 2, Field: "triples[2].operation", Description: "must be one of ASSERT, RETRACT or DELETE_SUBJECT"},
+		}
+		if !assert.Equal(subT, expected, verr.Violations) {
+			return
+		}
+	})
+
+	t.Run("should return nil for a subgraph without triples", func(subT *testing.T) {
+		err := Subgraph(&subgraph.Subgraph{})
+		if !assert.Nil(subT, err) {
+			return
+		}
+	})
+
+	t.Run("should return every violation", func(subT *testing.T) {
+		missingSubject := newTestTriple()
+		missingSubject.Subject = nil
+
+		emptyPredicate := newTestTriple()
+		emptyPredicate.Predicate.Name = " "
+
+		unsetObject := newTestTriple()
+		unsetObject.Object.Value = nil
+
+		badObjectSubject := newTestTriple()
+		badObjectSubject.Subject.Tuid = ""
+		badObjectSubject.Object.Value = &subgraph.Object_Subject{
+			Subject: &subgraph.Subject{Tuid: "2"},
+		}
+
+		nan := newTestTriple()
+		nan.Object.Value = &subgraph.Object_Float64{Float64: math.NaN()}
+
+		badTimestamp := newTestTriple()
+		badTimestamp.Object.Value = &subgraph.Object_Timestamp{Timestamp: &timestamppb.Timestamp{Nanos: -1}}
+
+		badGeo := newTestTriple()
+		badGeo.Object.Value = &subgraph.Object_Geo{Geo: &subgraph.GeoPoint{Latitude: 91, Longitude: math.Inf(1)}}
+
+		badLang := newTestTriple()
+		badLang.Object.Value = &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Bonjour"}}
+
+		badHlc := newTestTriple()
+		badHlc.Hlc = &subgraph.HybridTimestamp{}
+
+		g := &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{
+				newTestTriple(),
+				missingSubject,
+				emptyPredicate,
+				unsetObject,
+				badObjectSubject,
+				nil,
+				nan,
+				badTimestamp,
+				badGeo,
+				badLang,
+				badHlc,
+			},
+		}
+
+		err := Subgraph(g)
+
+		verr, ok := err.(*Error)
+		if !assert.True(subT, ok) {
+			return
+		}
+		expected := []Violation{
+			{TripleIndex: 1, Field: "triples[1].subject", Description: "must be set"},
+			{TripleIndex: 2, Field: "triples[2].predicate.name", Description: "must not be empty"},
+			{TripleIndex: 3, Field: "triples[3].object.value", Description: "must be set"},
+			{TripleIndex: 4, Field: "triples[4].subject.tuid", Description: "must not be empty"},
+			{TripleIndex: 4, Field: "triples[4].object.subject.type", Description: "must not be empty"},
+			{TripleIndex: 5, Field: "triples[5]", Description: "must be set"},
+			{TripleIndex: 6, Field: "triples[6].object.float64", Description: "must be a finite number"},
+			{TripleIndex: 7, Field: "triples[7].object.timestamp", Description: "must be a valid timestamp"},
+			{TripleIndex: 8, Field: "triples[8].object.geo.latitude", Description: "must be between -90 and 90"},
+			{TripleIndex: 8, Field: "triples[8].object.geo.longitude", Description: "must be a finite number"},
+			{TripleIndex: 9, Field: "triples[9].object.lang_string.lang", Description: "must be a BCP 47 language tag"},
+			{TripleIndex: 10, Field: "triples[10].hlc.wall_time", Description: "must be after the Unix epoch"},
+			{TripleIndex: 10, Field: "triples[10].hlc.node_id", Description: "must not be empty"},
+		}
+		if !assert.Equal(subT, expected, verr.Violations) {
+			return
+		}
+	})
+}
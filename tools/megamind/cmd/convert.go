@@ -0,0 +1,135 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/rdf"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert -|FILE",
+	Short: "Convert subgraphs to RDF N-Quads or N-Triples",
+	Long: `Convert subgraphs to RDF N-Quads or N-Triples.
+
+Subjects become IRIs built from their type and TUID under the base IRI,
+e.g. urn:megamind:Person/1, and predicates become IRIs built from their
+name under the base IRI. When writing N-Quads, the source URI of each
+subgraph is used as the graph label.
+
+RDF cannot express retractions or deleted subjects so they are skipped.
+The facets of a triple are written on a reification of it, i.e. a blank
+node of type rdf:Statement, with a predicate per facet.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		encoding := strings.ToLower(strings.TrimSpace(viper.GetString("convert.encoding")))
+		dec, err := getDecoder(encoding)
+		if err != nil {
+			zap.L().Fatal("unsupported encoding", zap.String("encoding", encoding))
+		}
+
+		format, err := rdf.ParseFormat(viper.GetString("convert.format"))
+		if err != nil {
+			zap.L().Fatal("unsupported format", zap.Error(err))
+		}
+
+		out, err := createOutput(viper.GetString("convert.output"))
+		if err != nil {
+			zap.L().Fatal("failed to create output", zap.Error(err))
+		}
+		defer out.Close()
+
+		enc, err := rdf.NewEncoder(
+			out,
+			rdf.WithFormat(format),
+			rdf.WithBaseIRI(viper.GetString("convert.base-iri")),
+		)
+		if err != nil {
+			zap.L().Fatal("invalid base iri", zap.Error(err))
+		}
+
+		err = convertSubgraphs(cmd.Context(), args[0], dec, enc)
+		if err != nil {
+			zap.L().Fatal("failed to convert subgraphs", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().String("encoding", "json", encodingUsage)
+	convertCmd.Flags().String("format", "nquads", "RDF format to write. Either nquads or ntriples.")
+	convertCmd.Flags().String("base-iri", rdf.DefaultBaseIRI, "IRI which subject and predicate IRIs are built under.")
+	convertCmd.Flags().StringP("output", "o", "-", "File to write RDF to.")
+
+	viper.BindPFlag("convert.encoding", convertCmd.Flags().Lookup("encoding"))
+	viper.BindPFlag("convert.format", convertCmd.Flags().Lookup("format"))
+	viper.BindPFlag("convert.base-iri", convertCmd.Flags().Lookup("base-iri"))
+	viper.BindPFlag("convert.output", convertCmd.Flags().Lookup("output"))
+}
+
+// convertSubgraphs encodes every subgraph from the source as soon as it is read.
+func convertSubgraphs(ctx context.Context, filename string, dec decoder, enc *rdf.Encoder) error {
+	zap.L().Info("converting subgraphs")
+
+	var converted, skipped, reified int
+	err := decodeSubgraphs(ctx, filename, dec, func(g *subgraph.Subgraph) error {
+		for _, t := range g.Triples {
+			if t.Operation != subgraph.Operation_ASSERT {
+				skipped += 1
+				continue
+			}
+			converted += 1
+			if len(t.Facets) > 0 {
+				reified += 1
+			}
+		}
+		return enc.Encode(g)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = enc.Flush()
+	if err != nil {
+		return err
+	}
+	zap.L().Info(
+		"converted subgraphs",
+		zap.Int("num_of_triples", converted),
+		zap.Int("num_of_skipped_triples", skipped),
+		zap.Int("num_of_reified_triples", reified),
+	)
+	return nil
+}
+
+func createOutput(filename string) (io.WriteCloser, error) {
+	filename = strings.TrimSpace(filename)
+	if filename == "-" {
+		return os.Stdout, nil
+	}
+
+	return os.Create(filename)
+}
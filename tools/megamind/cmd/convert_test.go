@@ -0,0 +1,72 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph/rdf"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertSubgraphs(t *testing.T) {
+	t.Run("should convert every assertion from the source", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dec, err := getDecoder("proto-delimited")
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		g := newObjectsSubgraph()
+		name := filepath.Join(subT.TempDir(), "subgraphs")
+		err = writeDelimitedProto(name, g, g)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		var buf bytes.Buffer
+		enc, err := rdf.NewEncoder(&buf, rdf.WithFormat(rdf.NQuads))
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		err = convertSubgraphs(ctx, name, dec, enc)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		// every triple has facets so is followed by a reification of it
+		var assertions []string
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if !strings.HasPrefix(line, "_:") {
+				assertions = append(assertions, line)
+			}
+		}
+		if !assert.Len(subT, assertions, 2*len(g.Triples)) {
+			return
+		}
+		if !assert.Equal(subT, "<urn:megamind:Person/1> <urn:megamind:value> <urn:megamind:Person/2> .", assertions[0]) {
+			return
+		}
+	})
+}
@@ -14,7 +14,14 @@
 
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/dgraph-io/dgo/v210"
+	"github.com/dgraph-io/dgo/v210/protos/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
 
 var dgraphCmd = &cobra.Command{
 	Use:    "dgraph",
@@ -24,4 +31,30 @@ var dgraphCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(dgraphCmd)
+
+	dgraphCmd.PersistentFlags().StringSlice("alpha", []string{"localhost:9080"}, "Dgraph alpha gRPC addresses.")
+
+	viper.BindPFlag("dgraph.alpha", dgraphCmd.PersistentFlags().Lookup("alpha"))
+}
+
+// dialDgraph returns a client which spreads requests across every alpha.
+func dialDgraph() (*dgo.Dgraph, func(), error) {
+	var conns []*grpc.ClientConn
+	closeAll := func() {
+		for _, cc := range conns {
+			cc.Close()
+		}
+	}
+
+	var clients []api.DgraphClient
+	for _, addr := range viper.GetStringSlice("dgraph.alpha") {
+		cc, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		conns = append(conns, cc)
+		clients = append(clients, api.NewDgraphClient(cc))
+	}
+	return dgo.NewDgraphClient(clients...), closeAll, nil
 }
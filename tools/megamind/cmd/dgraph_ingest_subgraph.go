@@ -16,14 +16,20 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/binary"
+	"fmt"
 	"io"
 	"os"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/dgraph"
+	"github.com/z5labs/megamind/subgraph/merge"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
@@ -37,24 +43,44 @@ var dgraphIngestSubgraphCmd = &cobra.Command{
 	Use:     "subgraphs -|FILE",
 	Aliases: []string{"subgraph"},
 	Short:   "Ingest subgraphs directly to Dgraph",
-	Args:    cobra.ExactArgs(1),
+	Long: `Ingest subgraphs directly to Dgraph.
+
+Triples are written in transactions of up to the batch size while the rest
+of the source is still being read. Assertions set their value, retractions
+delete it and deleted subjects have every one of their predicates deleted.
+
+Operations are resolved by their hybrid logical clock timestamp, with the
+same semantics as the merge package, rather than by the order they were
+read in. Triples without a timestamp are timestamped in the order they were
+read. Every batch is merged before it is written, so only the assertions
+which are not removed by a later operation in it, or by a later retraction
+or subject deletion in an earlier batch, are written. A larger batch size
+orders more operations against each other.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		var unmarshal unmarshaler
 		encoding := getEncoding()
-		switch encoding {
-		case "json":
-			unmarshal = unmarshalJSON
-		case "proto":
-			unmarshal = unmarshalProto
-		default:
+		dec, err := getDecoder(encoding)
+		if err != nil {
 			zap.L().Fatal("unsupported encoding", zap.String("encoding", encoding))
 		}
 
+		dg, closeDgraph, err := dialDgraph()
+		if err != nil {
+			zap.L().Fatal("failed to dial dgraph", zap.Error(err))
+		}
+		defer closeDgraph()
+		batchSize := viper.GetInt("dgraph.batch-size")
+		w := dgraph.NewWriter(
+			dg,
+			dgraph.WithBatchSize(batchSize),
+			dgraph.WithRetries(viper.GetInt("dgraph.retries"), 100*time.Millisecond),
+		)
+
 		// Ingest subgraphs
 		tripleCh := make(chan *subgraph.Triple)
 		g1, g1ctx := errgroup.WithContext(cmd.Context())
-		g1.Go(readSubgraphs(g1ctx, args[0], unmarshal, tripleCh))
-		g1.Go(mergeSubgraphs(g1ctx, tripleCh))
+		g1.Go(readSubgraphs(g1ctx, args[0], dec, tripleCh))
+		g1.Go(writeTriples(g1ctx, w, batchSize, tripleCh))
 
 		// Wait and log runtime stats
 		g2, g2ctx := errgroup.WithContext(g1ctx)
@@ -82,7 +108,7 @@ var dgraphIngestSubgraphCmd = &cobra.Command{
 		})
 
 		// Wait for everything to complete
-		err := g2.Wait()
+		err = g2.Wait()
 		if err != nil {
 			zap.L().Fatal("unexpected error", zap.Error(err))
 		}
@@ -92,11 +118,19 @@ var dgraphIngestSubgraphCmd = &cobra.Command{
 func init() {
 	dgraphIngestCmd.AddCommand(dgraphIngestSubgraphCmd)
 
-	dgraphIngestSubgraphCmd.Flags().String("encoding", "json", "Subgraph encoding")
+	dgraphIngestSubgraphCmd.Flags().String("encoding", "json", encodingUsage)
+
+	dgraphIngestSubgraphCmd.Flags().Int("batch-size", 1000, "Maximum number of triples written to Dgraph in a single transaction.")
+	dgraphIngestSubgraphCmd.Flags().Int("retries", 5, "Number of times an aborted Dgraph transaction is retried.")
 
 	viper.BindPFlag("encoding", dgraphIngestSubgraphCmd.Flags().Lookup("encoding"))
+	viper.BindPFlag("dgraph.batch-size", dgraphIngestSubgraphCmd.Flags().Lookup("batch-size"))
+	viper.BindPFlag("dgraph.retries", dgraphIngestSubgraphCmd.Flags().Lookup("retries"))
 }
 
+// encodingUsage describes the subgraph encodings supported by getDecoder and getEncoder.
+const encodingUsage = "Subgraph encoding. Either newline delimited json (json) or proto (proto), or varint length delimited proto (proto-delimited). Prefer proto-delimited over proto since binary proto messages may contain newlines."
+
 func getEncoding() string {
 	return strings.ToLower(
 		strings.TrimSpace(
@@ -115,80 +149,296 @@ func unmarshalProto(b []byte, v any) error {
 	return proto.Unmarshal(b, v.(proto.Message))
 }
 
-func readSubgraphs(ctx context.Context, filename string, unmarshal unmarshaler, tripleCh chan<- *subgraph.Triple) func() error {
-	g, gctx := errgroup.WithContext(ctx)
-	return func() (err error) {
+// recordReader reads the next encoded subgraph from a source.
+type recordReader func(*bufio.Reader) ([]byte, error)
+
+// readLine reads a newline delimited record. It can not be used for binary
+// proto records which contain a newline, which is why readDelimited exists.
+func readLine(br *bufio.Reader) ([]byte, error) {
+	line, err := br.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// readDelimited reads a varint length delimited record, since binary
+// protobuf messages may themselves contain newlines.
+func readDelimited(br *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, n)
+	_, err = io.ReadFull(br, b)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+type decoder struct {
+	read      recordReader
+	unmarshal unmarshaler
+}
+
+func getDecoder(encoding string) (decoder, error) {
+	switch encoding {
+	case "json":
+		return decoder{read: readLine, unmarshal: unmarshalJSON}, nil
+	case "proto":
+		return decoder{read: readLine, unmarshal: unmarshalProto}, nil
+	case "proto-delimited":
+		return decoder{read: readDelimited, unmarshal: unmarshalProto}, nil
+	default:
+		return decoder{}, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+}
+
+// readSubgraphs sends every triple from the source in the order it was read,
+// since a retraction must be applied after the assertion it retracts.
+func readSubgraphs(ctx context.Context, filename string, dec decoder, tripleCh chan<- *subgraph.Triple) func() error {
+	return func() error {
 		defer close(tripleCh)
-		defer g.Wait()
 
-		zap.L().Info("opening source", zap.String("filename", filename))
-		f, err := openSource(filename)
+		err := decodeSubgraphs(ctx, filename, dec, func(sg *subgraph.Subgraph) error {
+			for _, t := range sg.Triples {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case tripleCh <- t:
+				}
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+}
+
+// decodeSubgraphs calls f with every subgraph from the source in the order it was read.
+func decodeSubgraphs(ctx context.Context, filename string, dec decoder, f func(*subgraph.Subgraph) error) error {
+	zap.L().Info("opening source", zap.String("filename", filename))
+	src, err := openSource(filename)
+	if err != nil {
+		zap.L().Error("failed to open source", zap.Error(err))
+		return err
+	}
+	defer src.Close()
+	zap.L().Info("opened source", zap.String("filename", filename))
+
+	zap.L().Info("reading subgraphs from source", zap.String("filename", filename))
+	i := 0
+	br := bufio.NewReader(src)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		record, err := dec.read(br)
+		if err == io.EOF {
+			zap.L().Info("read subgraphs from source", zap.String("filename", filename), zap.Int("num_of_subgraphs", i))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(record) == 0 {
+			continue
+		}
+
+		var sg subgraph.Subgraph
+		err = dec.unmarshal(record, &sg)
 		if err != nil {
-			zap.L().Error("failed to open source", zap.Error(err))
 			return err
 		}
-		defer f.Close()
-		zap.L().Info("opened source", zap.String("filename", filename))
+		i += 1
 
-		zap.L().Info("reading subgraphs from source", zap.String("filename", filename))
-		i := 0
-		br := bufio.NewReader(f)
-		for {
-			select {
-			case <-gctx.Done():
-				return nil
-			default:
-			}
+		err = f(&sg)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// tripleWriter writes triples, in order, e.g. a *dgraph.Writer.
+type tripleWriter interface {
+	Write(ctx context.Context, triples ...*subgraph.Triple) error
+}
+
+// writeTriples resolves the operations it receives by their timestamp, in
+// batches of up to batchSize triples, and writes every batch once it has been
+// resolved, so that only a single batch is ever held in memory.
+func writeTriples(ctx context.Context, w tripleWriter, batchSize int, tripleCh <-chan *subgraph.Triple) func() error {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return func() error {
+		zap.L().Info("writing triples to dgraph")
 
-			line, _, err := br.ReadLine()
-			if err == io.EOF {
-				zap.L().Info("read subgraphs from source", zap.String("filename", filename), zap.Int("num_of_subgraphs", i))
+		r := newResolver(merge.NewClock("megamind"))
+		ops := make(map[subgraph.Operation]int)
+		var stale int
+		batch := make([]*subgraph.Triple, 0, batchSize)
+		flush := func() error {
+			if len(batch) == 0 {
 				return nil
 			}
+			triples, err := r.resolve(batch)
 			if err != nil {
 				return err
 			}
-
-			var sg subgraph.Subgraph
-			err = unmarshal(line, &sg)
+			err = w.Write(ctx, triples...)
 			if err != nil {
 				return err
 			}
-			i += 1
-
-			g.Go(func() error {
-				for _, t := range sg.Triples {
-					select {
-					case <-gctx.Done():
-					case tripleCh <- t:
-					}
-				}
-				return nil
-			})
+			for _, t := range triples {
+				ops[t.Operation] += 1
+			}
+			stale += len(batch) - len(triples)
+			batch = batch[:0]
+			return nil
 		}
-	}
-}
-
-func mergeSubgraphs(ctx context.Context, tripleCh <-chan *subgraph.Triple) func() error {
-	return func() error {
-		zap.L().Info("merging subgraphs")
 
-		i := 0
 		for {
 			select {
 			case <-ctx.Done():
-				return nil
-			case t := <-tripleCh:
-				if t == nil {
-					zap.L().Info("merged subgraphs", zap.Int("num_of_triples", i))
+				return ctx.Err()
+			case t, ok := <-tripleCh:
+				if !ok {
+					err := flush()
+					if err != nil {
+						return err
+					}
+					zap.L().Info(
+						"wrote triples to dgraph",
+						zap.Int("num_of_assertions", ops[subgraph.Operation_ASSERT]),
+						zap.Int("num_of_retractions", ops[subgraph.Operation_RETRACT]),
+						zap.Int("num_of_deleted_subjects", ops[subgraph.Operation_DELETE_SUBJECT]),
+						zap.Int("num_of_superseded_triples", stale),
+					)
 					return nil
 				}
-				i += 1
+
+				batch = append(batch, t)
+				if len(batch) < batchSize {
+					continue
+				}
+				err := flush()
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
 }
 
+// resolver resolves batches of operations by their hybrid logical clock
+// timestamp. It remembers the latest removal of every subject, predicate
+// and value it has seen so that a stale assertion in a later batch does
+// not restore a value removed in an earlier one.
+type resolver struct {
+	clock   *merge.Clock
+	removed map[string]*subgraph.HybridTimestamp
+}
+
+func newResolver(clock *merge.Clock) *resolver {
+	return &resolver{
+		clock:   clock,
+		removed: make(map[string]*subgraph.HybridTimestamp),
+	}
+}
+
+// resolve merges the batch and returns the operations to write for it:
+// every retraction and subject deletion, so that values written by earlier
+// batches are removed, followed by every assertion which is still in the
+// merged graph, ordered by timestamp.
+func (r *resolver) resolve(batch []*subgraph.Triple) ([]*subgraph.Triple, error) {
+	g := merge.New()
+	var removals []*subgraph.Triple
+	for _, t := range batch {
+		if t != nil && t.Hlc == nil {
+			t = proto.Clone(t).(*subgraph.Triple)
+			t.Hlc = r.clock.Now()
+		}
+		err := g.Apply(t)
+		if err != nil {
+			return nil, err
+		}
+		if t.Operation == subgraph.Operation_ASSERT {
+			continue
+		}
+		keys, err := tripleKeys(t)
+		if err != nil {
+			return nil, err
+		}
+		key := keys[len(keys)-1]
+		if merge.Compare(t.Hlc, r.removed[key]) > 0 {
+			r.removed[key] = t.Hlc
+		}
+		removals = append(removals, t)
+	}
+
+	var assertions []*subgraph.Triple
+	for _, t := range g.Triples() {
+		removed, err := r.removedAfter(t)
+		if err != nil {
+			return nil, err
+		}
+		if !removed {
+			assertions = append(assertions, t)
+		}
+	}
+	sort.SliceStable(assertions, func(i, j int) bool {
+		return merge.Compare(assertions[i].Hlc, assertions[j].Hlc) < 0
+	})
+	return append(removals, assertions...), nil
+}
+
+// removedAfter reports whether the subject, predicate or value of the
+// assertion has been removed at, or after, it was asserted.
+func (r *resolver) removedAfter(t *subgraph.Triple) (bool, error) {
+	keys, err := tripleKeys(t)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		removedAt, ok := r.removed[key]
+		if ok && merge.Compare(t.Hlc, removedAt) <= 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tripleKeys returns the keys of the subject of the triple and, if they are
+// set, of its predicate and of its value, so that the last key identifies
+// what a retraction or subject deletion removes.
+func tripleKeys(t *subgraph.Triple) ([]string, error) {
+	keys := []string{fmt.Sprintf("%q %q", t.GetSubject().GetType(), t.GetSubject().GetTuid())}
+	if t.Predicate == nil {
+		return keys, nil
+	}
+	keys = append(keys, fmt.Sprintf("%s %q", keys[0], t.Predicate.GetName()))
+	if t.Object == nil {
+		return keys, nil
+	}
+	obj, err := proto.MarshalOptions{Deterministic: true}.Marshal(t.Object)
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, fmt.Sprintf("%s %q", keys[1], obj)), nil
+}
+
 func openSource(filename string) (*os.File, error) {
 	filename = strings.TrimSpace(filename)
 	if filename == "-" {
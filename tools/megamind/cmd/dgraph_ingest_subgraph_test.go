@@ -0,0 +1,361 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newObjectsSubgraph() *subgraph.Subgraph {
+	objects := []*subgraph.Object{
+		{Value: &subgraph.Object_Subject{Subject: &subgraph.Subject{Type: "Person", Tuid: "2"}}},
+		{Value: &subgraph.Object_String_{String_: "Bob\nSmith"}},
+		{Value: &subgraph.Object_Int64{Int64: 10}},
+		{Value: &subgraph.Object_Float64{Float64: 1.5}},
+		{Value: &subgraph.Object_Bool{Bool: true}},
+		{Value: &subgraph.Object_Timestamp{Timestamp: timestamppb.New(time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC))}},
+		{Value: &subgraph.Object_Bytes{Bytes: []byte{'\n', 0x0, 0xff}}},
+		{Value: &subgraph.Object_Geo{Geo: &subgraph.GeoPoint{Latitude: 51.5, Longitude: -0.12}}},
+		{Value: &subgraph.Object_LangString{LangString: &subgraph.LangString{Value: "Bonjour", Lang: "fr"}}},
+	}
+
+	g := new(subgraph.Subgraph)
+	for _, object := range objects {
+		g.Triples = append(g.Triples, &subgraph.Triple{
+			Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+			Predicate: &subgraph.Predicate{Name: "value"},
+			Object:    object,
+			Facets: map[string]*subgraph.FacetValue{
+				"confidence": {Value: &subgraph.FacetValue_Float64{Float64: 0.5}},
+				"verified":   {Value: &subgraph.FacetValue_Bool{Bool: true}},
+				"since":      {Value: &subgraph.FacetValue_Timestamp{Timestamp: timestamppb.New(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))}},
+			},
+		})
+	}
+	return g
+}
+
+func writeJSON(name string, gs ...*subgraph.Subgraph) error {
+	var b []byte
+	for _, g := range gs {
+		line, err := protojson.Marshal(g)
+		if err != nil {
+			return err
+		}
+		b = append(append(b, line...), '\n')
+	}
+	return os.WriteFile(name, b, 0o644)
+}
+
+func writeDelimitedProto(name string, gs ...*subgraph.Subgraph) error {
+	var b []byte
+	for _, g := range gs {
+		record, err := proto.Marshal(g)
+		if err != nil {
+			return err
+		}
+		b = binary.AppendUvarint(b, uint64(len(record)))
+		b = append(b, record...)
+	}
+	return os.WriteFile(name, b, 0o644)
+}
+
+func collectTriples(ctx context.Context, filename string, dec decoder) ([]*subgraph.Triple, error) {
+	tripleCh := make(chan *subgraph.Triple)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- readSubgraphs(ctx, filename, dec, tripleCh)()
+	}()
+
+	var triples []*subgraph.Triple
+	for t := range tripleCh {
+		triples = append(triples, t)
+	}
+	return triples, <-errCh
+}
+
+func TestReadSubgraphs(t *testing.T) {
+	testCases := []struct {
+		Encoding string
+		Write    func(string, ...*subgraph.Subgraph) error
+	}{
+		{
+			Encoding: "json",
+			Write:    writeJSON,
+		},
+		{
+			Encoding: "proto-delimited",
+			Write:    writeDelimitedProto,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run("should round-trip every kind of object with "+tc.Encoding+" encoding", func(subT *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			dec, err := getDecoder(tc.Encoding)
+			if !assert.Nil(subT, err) {
+				return
+			}
+
+			g := newObjectsSubgraph()
+			name := filepath.Join(subT.TempDir(), "subgraphs")
+			err = tc.Write(name, g, g)
+			if !assert.Nil(subT, err) {
+				return
+			}
+
+			triples, err := collectTriples(ctx, name, dec)
+			if !assert.Nil(subT, err) {
+				return
+			}
+			if !assert.Len(subT, triples, 2*len(g.Triples)) {
+				return
+			}
+			for _, triple := range triples {
+				found := false
+				for _, expected := range g.Triples {
+					found = found || proto.Equal(expected, triple)
+				}
+				if !assert.True(subT, found, "unexpected triple: %v", triple) {
+					return
+				}
+			}
+		})
+	}
+
+	t.Run("should keep retractions in order with the assertions they retract", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dec, err := getDecoder("proto-delimited")
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		subj := &subgraph.Subject{Type: "Person", Tuid: "1"}
+		assertName := func(name string) *subgraph.Subgraph {
+			return &subgraph.Subgraph{
+				Triples: []*subgraph.Triple{
+					{
+						Subject:   subj,
+						Predicate: &subgraph.Predicate{Name: "name"},
+						Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: name}},
+					},
+				},
+			}
+		}
+		retract := assertName("Bob")
+		retract.Triples[0].Operation = subgraph.Operation_RETRACT
+		del := &subgraph.Subgraph{
+			Triples: []*subgraph.Triple{
+				{Subject: subj, Operation: subgraph.Operation_DELETE_SUBJECT},
+			},
+		}
+
+		var gs []*subgraph.Subgraph
+		for i := 0; i < 50; i++ {
+			gs = append(gs, assertName("Bob"), retract, del)
+		}
+		name := filepath.Join(subT.TempDir(), "subgraphs")
+		err = writeDelimitedProto(name, gs...)
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		triples, err := collectTriples(ctx, name, dec)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Len(subT, triples, len(gs)) {
+			return
+		}
+		for i, triple := range triples {
+			if !assert.True(subT, proto.Equal(gs[i].Triples[0], triple), "unexpected triple at %d: %v", i, triple) {
+				return
+			}
+		}
+	})
+
+	t.Run("should read every subgraph in testdata", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dec, err := getDecoder("json")
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		triples, err := collectTriples(ctx, "testdata/subgraphs.json", dec)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.NotEmpty(subT, triples) {
+			return
+		}
+	})
+
+	t.Run("should fail on an unsupported encoding", func(subT *testing.T) {
+		_, err := getDecoder("xml")
+		if !assert.NotNil(subT, err) {
+			return
+		}
+	})
+}
+
+type tripleWriterFunc func(context.Context, ...*subgraph.Triple) error
+
+func (f tripleWriterFunc) Write(ctx context.Context, triples ...*subgraph.Triple) error {
+	return f(ctx, triples...)
+}
+
+func TestWriteTriples(t *testing.T) {
+	t.Run("should write every operation in the order they were read", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dec, err := getDecoder("json")
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		tripleCh := make(chan *subgraph.Triple)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- readSubgraphs(ctx, "testdata/subgraphs.json", dec, tripleCh)()
+		}()
+
+		var batches [][]*subgraph.Triple
+		w := tripleWriterFunc(func(_ context.Context, triples ...*subgraph.Triple) error {
+			batches = append(batches, append([]*subgraph.Triple(nil), triples...))
+			return nil
+		})
+		err = writeTriples(ctx, w, 4, tripleCh)()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Nil(subT, <-errCh) {
+			return
+		}
+
+		if !assert.Len(subT, batches, 2) {
+			return
+		}
+		if !assert.Len(subT, batches[0], 4) {
+			return
+		}
+		// The last subgraph retracts the name of Person 1 and deletes Person 2.
+		var ops []subgraph.Operation
+		var tuids []string
+		for _, triple := range batches[1] {
+			ops = append(ops, triple.Operation)
+			tuids = append(tuids, triple.Subject.Tuid)
+		}
+		if !assert.Equal(subT, []subgraph.Operation{subgraph.Operation_RETRACT, subgraph.Operation_DELETE_SUBJECT}, ops) {
+			return
+		}
+		if !assert.Equal(subT, []string{"1", "2"}, tuids) {
+			return
+		}
+	})
+
+	t.Run("should not let a stale assertion restore a value removed by a later retraction", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		at := func(t *subgraph.Triple, op subgraph.Operation, wall int64) *subgraph.Triple {
+			t.Operation = op
+			t.Hlc = &subgraph.HybridTimestamp{WallTime: wall, NodeId: "a"}
+			return t
+		}
+		name := func(v string) *subgraph.Triple {
+			return &subgraph.Triple{
+				Subject:   &subgraph.Subject{Type: "Person", Tuid: "1"},
+				Predicate: &subgraph.Predicate{Name: "name"},
+				Object:    &subgraph.Object{Value: &subgraph.Object_String_{String_: v}},
+			}
+		}
+
+		tripleCh := make(chan *subgraph.Triple, 4)
+		tripleCh <- at(name("Bob"), subgraph.Operation_RETRACT, 3)
+		tripleCh <- at(name("Bob"), subgraph.Operation_ASSERT, 2)
+		tripleCh <- at(name("Bob"), subgraph.Operation_ASSERT, 1)
+		tripleCh <- at(name("Rob"), subgraph.Operation_ASSERT, 4)
+		close(tripleCh)
+
+		var batches [][]string
+		w := tripleWriterFunc(func(_ context.Context, triples ...*subgraph.Triple) error {
+			var batch []string
+			for _, t := range triples {
+				batch = append(batch, t.Operation.String()+" "+t.Object.GetString_())
+			}
+			batches = append(batches, batch)
+			return nil
+		})
+		err := writeTriples(ctx, w, 2, tripleCh)()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Equal(subT, [][]string{{"RETRACT Bob"}, {"ASSERT Rob"}}, batches) {
+			return
+		}
+	})
+
+	t.Run("should return the context error if cancelled before every triple is written", func(subT *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		w := tripleWriterFunc(func(context.Context, ...*subgraph.Triple) error {
+			return nil
+		})
+		err := writeTriples(ctx, w, 10, make(chan *subgraph.Triple))()
+		if !assert.ErrorIs(subT, err, context.Canceled) {
+			return
+		}
+	})
+
+	t.Run("should return the error from the writer", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		tripleCh := make(chan *subgraph.Triple, 1)
+		tripleCh <- newObjectsSubgraph().Triples[0]
+		close(tripleCh)
+
+		writeErr := errors.New("failed to write")
+		w := tripleWriterFunc(func(context.Context, ...*subgraph.Triple) error {
+			return writeErr
+		})
+		err := writeTriples(ctx, w, 10, tripleCh)()
+		if !assert.Equal(subT, writeErr, err) {
+			return
+		}
+	})
+}
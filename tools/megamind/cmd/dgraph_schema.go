@@ -0,0 +1,149 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/dgraph"
+	"github.com/z5labs/megamind/subgraph/schema"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+var dgraphSchemaCmd = &cobra.Command{
+	Use:   "schema -|FILE",
+	Short: "Generate a Dgraph schema from subgraphs",
+	Long: `Generate a Dgraph schema from subgraphs.
+
+The type of each predicate is inferred from the values it is asserted
+with and every subject type becomes a Dgraph type with the predicates
+asserted on it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		encoding := strings.ToLower(strings.TrimSpace(viper.GetString("dgraph.schema.encoding")))
+		dec, err := getDecoder(encoding)
+		if err != nil {
+			zap.L().Fatal("unsupported encoding", zap.String("encoding", encoding))
+		}
+
+		var opts []dgraph.SchemaOption
+		if schemaFile := viper.GetString("dgraph.schema.schema-file"); schemaFile != "" {
+			sc, err := schema.Load(schemaFile)
+			if err != nil {
+				zap.L().Fatal("failed to load schema", zap.String("filename", schemaFile), zap.Error(err))
+			}
+			opts = append(opts, dgraph.WithCardinalities(sc))
+		}
+		if viper.GetBool("dgraph.schema.index") {
+			opts = append(opts, dgraph.WithIndexes())
+		}
+		if viper.GetBool("dgraph.schema.reverse") {
+			opts = append(opts, dgraph.WithReverseEdges())
+		}
+
+		tripleCh := make(chan *subgraph.Triple)
+		s := dgraph.NewSchema(opts...)
+		g, gctx := errgroup.WithContext(cmd.Context())
+		g.Go(readSubgraphs(gctx, args[0], dec, tripleCh))
+		g.Go(inferSchema(gctx, s, tripleCh))
+
+		err = g.Wait()
+		if err != nil {
+			zap.L().Fatal("unexpected error", zap.Error(err))
+		}
+		for _, name := range s.Conflicts() {
+			zap.L().Warn("predicate has values of conflicting types so it will use the default type", zap.String("predicate", name))
+		}
+
+		if viper.GetBool("dgraph.schema.apply") {
+			dg, closeDgraph, err := dialDgraph()
+			if err != nil {
+				zap.L().Fatal("failed to dial dgraph", zap.Error(err))
+			}
+			defer closeDgraph()
+
+			err = s.Alter(cmd.Context(), dg)
+			if err != nil {
+				zap.L().Fatal("failed to alter dgraph schema", zap.Error(err))
+			}
+			zap.L().Info("altered dgraph schema")
+			return
+		}
+
+		err = writeSchema(viper.GetString("dgraph.schema.output"), s)
+		if err != nil {
+			zap.L().Fatal("failed to write schema", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	dgraphCmd.AddCommand(dgraphSchemaCmd)
+
+	dgraphSchemaCmd.Flags().String("encoding", "json", encodingUsage)
+	dgraphSchemaCmd.Flags().String("schema-file", "", "Schema file declaring which predicates hold a single value. Every other predicate is a list.")
+	dgraphSchemaCmd.Flags().StringP("output", "o", "-", "File to write the Dgraph schema to.")
+	dgraphSchemaCmd.Flags().Bool("index", false, "Index every scalar predicate with its default tokenizer.")
+	dgraphSchemaCmd.Flags().Bool("reverse", false, "Add reverse edges to every predicate which points to subjects.")
+	dgraphSchemaCmd.Flags().Bool("apply", false, "Alter the schema of the Dgraph cluster instead of writing it out.")
+
+	viper.BindPFlag("dgraph.schema.encoding", dgraphSchemaCmd.Flags().Lookup("encoding"))
+	viper.BindPFlag("dgraph.schema.schema-file", dgraphSchemaCmd.Flags().Lookup("schema-file"))
+	viper.BindPFlag("dgraph.schema.output", dgraphSchemaCmd.Flags().Lookup("output"))
+	viper.BindPFlag("dgraph.schema.index", dgraphSchemaCmd.Flags().Lookup("index"))
+	viper.BindPFlag("dgraph.schema.reverse", dgraphSchemaCmd.Flags().Lookup("reverse"))
+	viper.BindPFlag("dgraph.schema.apply", dgraphSchemaCmd.Flags().Lookup("apply"))
+}
+
+// inferSchema adds every triple to the schema.
+func inferSchema(ctx context.Context, s *dgraph.Schema, tripleCh <-chan *subgraph.Triple) func() error {
+	return func() error {
+		zap.L().Info("inferring schema")
+
+		n := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case t := <-tripleCh:
+				if t == nil {
+					zap.L().Info("inferred schema", zap.Int("num_of_triples", n))
+					return nil
+				}
+
+				err := s.Add(t)
+				if err != nil {
+					return err
+				}
+				n += 1
+			}
+		}
+	}
+}
+
+func writeSchema(filename string, s *dgraph.Schema) error {
+	if strings.TrimSpace(filename) == "-" {
+		_, err := os.Stdout.WriteString(s.String())
+		return err
+	}
+	return os.WriteFile(filename, []byte(s.String()), 0o644)
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/dgraph"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferSchema(t *testing.T) {
+	t.Run("should infer a schema from every subgraph in testdata", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dec, err := getDecoder("json")
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		tripleCh := make(chan *subgraph.Triple)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- readSubgraphs(ctx, "testdata/subgraphs.json", dec, tripleCh)()
+		}()
+
+		s := dgraph.NewSchema()
+		err = inferSchema(ctx, s, tripleCh)()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Nil(subT, <-errCh) {
+			return
+		}
+
+		expected := "<name>: [string] .\n<tuid>: string @index(exact) @upsert .\n\ntype Person {\n\tname\n\ttuid\n}\n"
+		if !assert.Equal(subT, expected, s.String()) {
+			return
+		}
+	})
+}
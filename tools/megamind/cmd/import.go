@@ -0,0 +1,238 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	pb "github.com/z5labs/megamind/services/ingest/proto"
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/validate"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import data from other formats as subgraphs",
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.PersistentFlags().String("encoding", "json", encodingUsage)
+	importCmd.PersistentFlags().StringP("output", "o", "-", "File to write subgraphs to.")
+	importCmd.PersistentFlags().String("ingest-addr", "", "Address of an ingest service to stream subgraphs to instead of writing them out.")
+
+	viper.BindPFlag("import.encoding", importCmd.PersistentFlags().Lookup("encoding"))
+	viper.BindPFlag("import.output", importCmd.PersistentFlags().Lookup("output"))
+	viper.BindPFlag("import.ingest-addr", importCmd.PersistentFlags().Lookup("ingest-addr"))
+}
+
+// subgraphSink receives imported subgraphs.
+type subgraphSink interface {
+	Send(*subgraph.Subgraph) error
+	Close() error
+}
+
+// openSink returns a sink which either streams to the ingest service
+// or writes to the output, depending on how import was configured.
+func openSink(ctx context.Context) (subgraphSink, error) {
+	if addr := strings.TrimSpace(viper.GetString("import.ingest-addr")); addr != "" {
+		return dialIngest(ctx, addr)
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(viper.GetString("import.encoding")))
+	enc, err := getEncoder(encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := createOutput(viper.GetString("import.output"))
+	if err != nil {
+		return nil, err
+	}
+	return &writerSink{w: bufio.NewWriter(out), c: out, enc: enc}, nil
+}
+
+// importSubgraphs validates every subgraph returned by next and sends
+// it to the sink. It stops once next returns io.EOF, or with the error
+// of ctx once it's cancelled.
+func importSubgraphs(ctx context.Context, next func() (*subgraph.Subgraph, error), sink subgraphSink) error {
+	zap.L().Info("importing subgraphs")
+
+	var subgraphs, triples int
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		g, err := next()
+		if err == io.EOF {
+			zap.L().Info(
+				"imported subgraphs",
+				zap.Int("num_of_subgraphs", subgraphs),
+				zap.Int("num_of_triples", triples),
+			)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = validate.Subgraph(g)
+		if err != nil {
+			return fmt.Errorf("subgraph %d: %w", subgraphs, err)
+		}
+		err = sink.Send(g)
+		if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+			// the ingest stream was cancelled along with ctx
+			return ctxErr
+		}
+		if err != nil {
+			return err
+		}
+		subgraphs += 1
+		triples += len(g.Triples)
+	}
+}
+
+type encoder func(io.Writer, *subgraph.Subgraph) error
+
+func encodeJSON(w io.Writer, g *subgraph.Subgraph) error {
+	b, err := protojson.Marshal(g)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func encodeProto(w io.Writer, g *subgraph.Subgraph) error {
+	b, err := proto.Marshal(g)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func encodeDelimitedProto(w io.Writer, g *subgraph.Subgraph) error {
+	record, err := proto.Marshal(g)
+	if err != nil {
+		return err
+	}
+	b := binary.AppendUvarint(nil, uint64(len(record)))
+	_, err = w.Write(append(b, record...))
+	return err
+}
+
+// getEncoder returns the inverse of the decoder for the same encoding.
+func getEncoder(encoding string) (encoder, error) {
+	switch encoding {
+	case "json":
+		return encodeJSON, nil
+	case "proto":
+		return encodeProto, nil
+	case "proto-delimited":
+		return encodeDelimitedProto, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+}
+
+type writerSink struct {
+	w   *bufio.Writer
+	c   io.Closer
+	enc encoder
+}
+
+func (s *writerSink) Send(g *subgraph.Subgraph) error {
+	return s.enc(s.w, g)
+}
+
+func (s *writerSink) Close() error {
+	err := s.w.Flush()
+	if err != nil {
+		s.c.Close()
+		return err
+	}
+	return s.c.Close()
+}
+
+type ingestSink struct {
+	cc     *grpc.ClientConn
+	stream pb.SubgraphIngest_IngestClient
+}
+
+func dialIngest(ctx context.Context, addr string) (*ingestSink, error) {
+	cc, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := pb.NewSubgraphIngestClient(cc).Ingest(ctx)
+	if err != nil {
+		cc.Close()
+		return nil, err
+	}
+	return &ingestSink{cc: cc, stream: stream}, nil
+}
+
+func (s *ingestSink) Send(g *subgraph.Subgraph) error {
+	err := s.stream.Send(g)
+	if err == io.EOF {
+		// the stream was aborted, its status says why
+		_, err = s.stream.CloseAndRecv()
+	}
+	return err
+}
+
+// Close waits for the ingest service to accept every subgraph sent on the stream.
+func (s *ingestSink) Close() error {
+	defer s.cc.Close()
+
+	resp, err := s.stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	zap.L().Info(
+		"ingested subgraphs",
+		zap.String("ingest_id", resp.IngestId),
+		zap.Int64("num_of_triples", resp.NumOfTriples),
+		zap.Int("num_of_warnings", len(resp.Warnings)),
+	)
+	for _, w := range resp.Warnings {
+		zap.L().Warn(
+			w.Message,
+			zap.Int32("subgraph_index", w.SubgraphIndex),
+			zap.Int32("triple_index", w.TripleIndex),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/z5labs/megamind/subgraph/tabular"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var importCSVCmd = &cobra.Command{
+	Use:   "csv -|FILE",
+	Short: "Import the rows of a CSV file as subgraphs",
+	Long: `Import the rows of a CSV file as subgraphs.
+
+Each row becomes a subgraph about a single subject. The mapping file
+declares which column holds the TUID of the subject, the type of the
+subject and which columns become predicates of which kind.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mappingFile := viper.GetString("import.csv.mapping")
+		m, err := tabular.LoadMapping(mappingFile)
+		if err != nil {
+			zap.L().Fatal("failed to load mapping", zap.String("filename", mappingFile), zap.Error(err))
+		}
+
+		src, err := openSource(args[0])
+		if err != nil {
+			zap.L().Fatal("failed to open source", zap.String("filename", args[0]), zap.Error(err))
+		}
+		defer src.Close()
+
+		r, err := tabular.NewReader(src, m)
+		if err != nil {
+			zap.L().Fatal("failed to read header", zap.Error(err))
+		}
+
+		sink, err := openSink(cmd.Context())
+		if err != nil {
+			zap.L().Fatal("failed to open output", zap.Error(err))
+		}
+
+		err = importSubgraphs(cmd.Context(), r.Read, sink)
+		if err != nil {
+			sink.Close()
+			zap.L().Fatal("failed to import csv", zap.Error(err))
+		}
+		err = sink.Close()
+		if err != nil {
+			zap.L().Fatal("failed to close output", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	importCmd.AddCommand(importCSVCmd)
+
+	importCSVCmd.Flags().String("mapping", "", "Mapping file declaring how columns become predicates.")
+	importCSVCmd.MarkFlagRequired("mapping")
+
+	viper.BindPFlag("import.csv.mapping", importCSVCmd.Flags().Lookup("mapping"))
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/z5labs/megamind/subgraph/rdf"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var importRDFCmd = &cobra.Command{
+	Use:   "rdf -|FILE",
+	Short: "Import RDF N-Triples, N-Quads or Turtle as subgraphs",
+	Long: `Import RDF N-Triples, N-Quads or Turtle as subgraphs.
+
+Consecutive statements about the same subject are grouped into a single
+subgraph. IRIs are mapped back to subject types, TUIDs and predicate names
+by the rules file, falling back to the base IRI the same way megamind
+convert builds them. The graph of an N-Quad becomes the source URI of
+its subgraph.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := getRDFFormat(args[0])
+		if err != nil {
+			zap.L().Fatal("unsupported format", zap.Error(err))
+		}
+
+		opts := []rdf.Option{
+			rdf.WithFormat(format),
+			rdf.WithBaseIRI(viper.GetString("import.rdf.base-iri")),
+		}
+		if rulesFile := viper.GetString("import.rdf.rules"); rulesFile != "" {
+			rules, err := rdf.LoadRules(rulesFile)
+			if err != nil {
+				zap.L().Fatal("failed to load rules", zap.String("filename", rulesFile), zap.Error(err))
+			}
+			opts = append(opts, rdf.WithRules(rules))
+		}
+
+		src, err := openSource(args[0])
+		if err != nil {
+			zap.L().Fatal("failed to open source", zap.String("filename", args[0]), zap.Error(err))
+		}
+		defer src.Close()
+
+		r, err := rdf.NewSubgraphReader(src, opts...)
+		if err != nil {
+			zap.L().Fatal("invalid base iri", zap.Error(err))
+		}
+
+		sink, err := openSink(cmd.Context())
+		if err != nil {
+			zap.L().Fatal("failed to open output", zap.Error(err))
+		}
+
+		err = importSubgraphs(cmd.Context(), r.Read, sink)
+		if err != nil {
+			sink.Close()
+			zap.L().Fatal("failed to import rdf", zap.Error(err))
+		}
+		err = sink.Close()
+		if err != nil {
+			zap.L().Fatal("failed to close output", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	importCmd.AddCommand(importRDFCmd)
+
+	importRDFCmd.Flags().String("format", "", "RDF format. Either ntriples, nquads or turtle. Defaults to the format of the file extension.")
+	importRDFCmd.Flags().String("base-iri", rdf.DefaultBaseIRI, "IRI which subject and predicate IRIs are mapped back from.")
+	importRDFCmd.Flags().String("rules", "", "Rules file mapping IRIs to subject types, TUIDs and predicate names.")
+
+	viper.BindPFlag("import.rdf.format", importRDFCmd.Flags().Lookup("format"))
+	viper.BindPFlag("import.rdf.base-iri", importRDFCmd.Flags().Lookup("base-iri"))
+	viper.BindPFlag("import.rdf.rules", importRDFCmd.Flags().Lookup("rules"))
+}
+
+var rdfExtensions = map[string]rdf.Format{
+	".nt":  rdf.NTriples,
+	".nq":  rdf.NQuads,
+	".ttl": rdf.Turtle,
+}
+
+func getRDFFormat(filename string) (rdf.Format, error) {
+	if format := viper.GetString("import.rdf.format"); format != "" {
+		return rdf.ParseFormat(format)
+	}
+	if format, ok := rdfExtensions[strings.ToLower(filepath.Ext(filename))]; ok {
+		return format, nil
+	}
+	return rdf.NTriples, nil
+}
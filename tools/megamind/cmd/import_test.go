@@ -0,0 +1,200 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ingestgrpc "github.com/z5labs/megamind/services/ingest/grpc"
+	"github.com/z5labs/megamind/services/ingest/ingest"
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/rdf"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+type publisherFunc func(context.Context, *subgraph.Subgraph) error
+
+func (f publisherFunc) Publish(ctx context.Context, g *subgraph.Subgraph) error {
+	return f(ctx, g)
+}
+
+const people = `@prefix m: <urn:megamind:> .
+m:Person\/1 m:name "Alice" ; m:knows m:Person\/2 .
+m:Person\/2 m:name "Bob" .
+`
+
+func newRDFReader(subT *testing.T, src string) *rdf.SubgraphReader {
+	r, err := rdf.NewSubgraphReader(strings.NewReader(src), rdf.WithFormat(rdf.Turtle))
+	if err != nil {
+		subT.Fatal(err)
+	}
+	return r
+}
+
+func TestImportSubgraphs(t *testing.T) {
+	t.Run("should write every subgraph to the output", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var buf bytes.Buffer
+		sink := &writerSink{w: bufio.NewWriter(&buf), c: nopCloser{}, enc: encodeDelimitedProto}
+		err := importSubgraphs(ctx, newRDFReader(subT, people).Read, sink)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = sink.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		dec, err := getDecoder("proto-delimited")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		var gs []*subgraph.Subgraph
+		br := bufio.NewReader(&buf)
+		for {
+			record, err := dec.read(br)
+			if err == io.EOF {
+				break
+			}
+			if !assert.Nil(subT, err) {
+				return
+			}
+
+			var g subgraph.Subgraph
+			err = dec.unmarshal(record, &g)
+			if !assert.Nil(subT, err) {
+				return
+			}
+			gs = append(gs, &g)
+		}
+		if !assert.Len(subT, gs, 2) {
+			return
+		}
+		if !assert.Len(subT, gs[0].Triples, 2) {
+			return
+		}
+	})
+
+	t.Run("should stream every subgraph to the ingest service", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		ls, err := net.Listen("tcp", ":0")
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		var mu sync.Mutex
+		var published []*subgraph.Subgraph
+		p := publisherFunc(func(_ context.Context, g *subgraph.Subgraph) error {
+			mu.Lock()
+			defer mu.Unlock()
+			published = append(published, g)
+			return nil
+		})
+		go ingestgrpc.Serve(ctx, ls, ingest.NewSubgraphIngester(zap.NewNop(), p))
+
+		sink, err := dialIngest(ctx, ls.Addr().String())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = importSubgraphs(ctx, newRDFReader(subT, people).Read, sink)
+		if !assert.Nil(subT, err) {
+			return
+		}
+		err = sink.Close()
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !assert.Len(subT, published, 2) {
+			return
+		}
+	})
+
+	t.Run("should return the context error if it is cancelled", func(subT *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		sink := &writerSink{w: bufio.NewWriter(io.Discard), c: nopCloser{}, enc: encodeJSON}
+		err := importSubgraphs(ctx, newRDFReader(subT, people).Read, sink)
+		if !assert.ErrorIs(subT, err, context.Canceled) {
+			return
+		}
+	})
+
+	t.Run("should return the context error if the ingest stream is cancelled", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		ls, err := net.Listen("tcp", ":0")
+		if !assert.Nil(subT, err) {
+			return
+		}
+		p := publisherFunc(func(context.Context, *subgraph.Subgraph) error { return nil })
+		go ingestgrpc.Serve(ctx, ls, ingest.NewSubgraphIngester(zap.NewNop(), p))
+
+		importCtx, cancelImport := context.WithCancel(ctx)
+		defer cancelImport()
+
+		sink, err := dialIngest(importCtx, ls.Addr().String())
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer sink.Close()
+
+		r := newRDFReader(subT, people)
+		next := func() (*subgraph.Subgraph, error) {
+			cancelImport()
+			return r.Read()
+		}
+		err = importSubgraphs(importCtx, next, sink)
+		if !assert.ErrorIs(subT, err, context.Canceled) {
+			return
+		}
+	})
+
+	t.Run("should fail on an invalid subgraph", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		next := func() (*subgraph.Subgraph, error) {
+			return &subgraph.Subgraph{Triples: []*subgraph.Triple{{}}}, nil
+		}
+		sink := &writerSink{w: bufio.NewWriter(io.Discard), c: nopCloser{}, enc: encodeJSON}
+		err := importSubgraphs(ctx, next, sink)
+		if !assert.NotNil(subT, err) {
+			return
+		}
+	})
+}
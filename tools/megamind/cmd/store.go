@@ -0,0 +1,52 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/z5labs/megamind/subgraph/schema"
+	"github.com/z5labs/megamind/subgraph/store"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Work with an embedded triple store",
+}
+
+func init() {
+	rootCmd.AddCommand(storeCmd)
+
+	storeCmd.PersistentFlags().String("db", "megamind.db", "Embedded triple store file.")
+	storeCmd.PersistentFlags().String("schema-file", "", "Schema file declaring which predicates hold a single value.")
+
+	viper.BindPFlag("store.db", storeCmd.PersistentFlags().Lookup("db"))
+	viper.BindPFlag("store.schema-file", storeCmd.PersistentFlags().Lookup("schema-file"))
+}
+
+func openStore() (*store.Store, error) {
+	opts := []store.Option{store.WithLockTimeout(10 * time.Second)}
+	if schemaFile := viper.GetString("store.schema-file"); schemaFile != "" {
+		sc, err := schema.Load(schemaFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, store.WithSchema(sc))
+	}
+	return store.Open(viper.GetString("store.db"), opts...)
+}
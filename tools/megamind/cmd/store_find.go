@@ -0,0 +1,103 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/store"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var storeFindCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Print every triple in an embedded triple store which matches the given pattern",
+	Long: `Print every triple in an embedded triple store which matches the given pattern
+as newline delimited json. Any part of the pattern which is not given matches everything.`,
+	Example: `  megamind store find --subject Person/1
+  megamind store find --predicate knows --object '{"subject":{"type":"Person","tuid":"2"}}'`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := getPattern()
+		if err != nil {
+			zap.L().Fatal("invalid pattern", zap.Error(err))
+		}
+
+		s, err := openStore()
+		if err != nil {
+			zap.L().Fatal("failed to open store", zap.String("filename", viper.GetString("store.db")), zap.Error(err))
+		}
+		defer s.Close()
+
+		triples, err := s.Find(p)
+		if err != nil {
+			zap.L().Fatal("failed to find triples", zap.Error(err))
+		}
+
+		w := bufio.NewWriter(os.Stdout)
+		defer w.Flush()
+		for _, t := range triples {
+			b, err := protojson.Marshal(t)
+			if err != nil {
+				zap.L().Fatal("unexpected error when marshalling triple", zap.Error(err))
+			}
+			w.Write(append(b, '\n'))
+		}
+	},
+}
+
+func init() {
+	storeCmd.AddCommand(storeFindCmd)
+
+	storeFindCmd.Flags().String("subject", "", "Subject to match given as TYPE/TUID.")
+	storeFindCmd.Flags().String("predicate", "", "Predicate name to match.")
+	storeFindCmd.Flags().String("object", "", "Object to match given as json, e.g. {\"string\":\"Bob\"}")
+
+	viper.BindPFlag("store.subject", storeFindCmd.Flags().Lookup("subject"))
+	viper.BindPFlag("store.predicate", storeFindCmd.Flags().Lookup("predicate"))
+	viper.BindPFlag("store.object", storeFindCmd.Flags().Lookup("object"))
+}
+
+var errInvalidSubject = errors.New("subject must be given as TYPE/TUID")
+
+func getPattern() (store.Pattern, error) {
+	var p store.Pattern
+	if subj := strings.TrimSpace(viper.GetString("store.subject")); subj != "" {
+		typ, tuid, ok := strings.Cut(subj, "/")
+		if !ok {
+			return p, errInvalidSubject
+		}
+		p.Subject = &subgraph.Subject{Type: typ, Tuid: tuid}
+	}
+	if pred := strings.TrimSpace(viper.GetString("store.predicate")); pred != "" {
+		p.Predicate = &subgraph.Predicate{Name: pred}
+	}
+	if obj := strings.TrimSpace(viper.GetString("store.object")); obj != "" {
+		p.Object = new(subgraph.Object)
+		err := protojson.Unmarshal([]byte(obj), p.Object)
+		if err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/store"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+var storeIngestCmd = &cobra.Command{
+	Use:   "ingest",
+	Short: "Ingest data into an embedded triple store",
+}
+
+var storeIngestSubgraphCmd = &cobra.Command{
+	Use:     "subgraphs -|FILE",
+	Aliases: []string{"subgraph"},
+	Short:   "Ingest subgraphs into an embedded triple store",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		encoding := strings.ToLower(strings.TrimSpace(viper.GetString("store.encoding")))
+		dec, err := getDecoder(encoding)
+		if err != nil {
+			zap.L().Fatal("unsupported encoding", zap.String("encoding", encoding))
+		}
+
+		s, err := openStore()
+		if err != nil {
+			zap.L().Fatal("failed to open store", zap.String("filename", viper.GetString("store.db")), zap.Error(err))
+		}
+		defer func() {
+			err := s.Close()
+			if err != nil {
+				zap.L().Error("unexpected error when closing store", zap.Error(err))
+			}
+		}()
+
+		tripleCh := make(chan *subgraph.Triple)
+		g, gctx := errgroup.WithContext(cmd.Context())
+		g.Go(readSubgraphs(gctx, args[0], dec, tripleCh))
+		g.Go(storeTriples(gctx, s, viper.GetInt("store.batch-size"), tripleCh))
+
+		err = g.Wait()
+		if err != nil {
+			zap.L().Fatal("unexpected error", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	storeCmd.AddCommand(storeIngestCmd)
+	storeIngestCmd.AddCommand(storeIngestSubgraphCmd)
+
+	storeIngestSubgraphCmd.Flags().String("encoding", "json", encodingUsage)
+	storeIngestSubgraphCmd.Flags().Int("batch-size", 1000, "Number of triples to apply per transaction.")
+
+	viper.BindPFlag("store.encoding", storeIngestSubgraphCmd.Flags().Lookup("encoding"))
+	viper.BindPFlag("store.batch-size", storeIngestSubgraphCmd.Flags().Lookup("batch-size"))
+}
+
+// storeTriples applies triples to the store, in the order they are
+// received, in transactions of up to batchSize triples.
+func storeTriples(ctx context.Context, s *store.Store, batchSize int, tripleCh <-chan *subgraph.Triple) func() error {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return func() error {
+		zap.L().Info("storing triples")
+
+		n := 0
+		batch := make([]*subgraph.Triple, 0, batchSize)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			err := s.Apply(batch...)
+			if err != nil {
+				return err
+			}
+			n += len(batch)
+			batch = batch[:0]
+			return nil
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case t := <-tripleCh:
+				if t == nil {
+					err := flush()
+					if err != nil {
+						return err
+					}
+					zap.L().Info("stored triples", zap.Int("num_of_triples", n))
+					return nil
+				}
+
+				batch = append(batch, t)
+				if len(batch) < batchSize {
+					continue
+				}
+				err := flush()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
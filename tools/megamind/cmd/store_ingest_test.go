@@ -0,0 +1,72 @@
+// Copyright 2022 Z5Labs and Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/z5labs/megamind/subgraph"
+	"github.com/z5labs/megamind/subgraph/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreTriples(t *testing.T) {
+	t.Run("should store every triple in the order they were read", func(subT *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		s, err := store.Open(filepath.Join(subT.TempDir(), "megamind.db"))
+		if !assert.Nil(subT, err) {
+			return
+		}
+		defer s.Close()
+
+		dec, err := getDecoder("json")
+		if !assert.Nil(subT, err) {
+			return
+		}
+
+		tripleCh := make(chan *subgraph.Triple)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- readSubgraphs(ctx, "testdata/subgraphs.json", dec, tripleCh)()
+		}()
+
+		err = storeTriples(ctx, s, 2, tripleCh)()
+		if !assert.Nil(subT, err) {
+			return
+		}
+		if !assert.Nil(subT, <-errCh) {
+			return
+		}
+
+		// The last subgraph retracts the name of Person 1 and deletes Person 2.
+		triples, err := s.Find(store.Pattern{})
+		if !assert.Nil(subT, err) {
+			return
+		}
+		var tuids []string
+		for _, triple := range triples {
+			tuids = append(tuids, triple.Subject.Tuid)
+		}
+		if !assert.Equal(subT, []string{"3", "4"}, tuids) {
+			return
+		}
+	})
+}